@@ -0,0 +1,543 @@
+package main
+
+import (
+	"RestApi/internal/lib/audit"
+	"RestApi/internal/lib/random"
+	"RestApi/internal/lib/transfer"
+	"RestApi/internal/storage"
+	"RestApi/internal/storage/copier"
+	"RestApi/storage/scripts"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const aliasLength = 6
+
+func (c *command) create(args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	urlToSave := fs.String("url", "", "target URL")
+	alias := fs.String("alias", "", "alias, generated when empty")
+	domain := fs.String("domain", "", "custom domain, the default domain when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *urlToSave == "" {
+		return errors.New("create: -url is required")
+	}
+	if *alias == "" {
+		*alias = random.NewRandomString(aliasLength)
+	}
+
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	check, err := c.linkCheck(s)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	link, err := check.Link(context.Background(), storage.Link{
+		Domain:    *domain,
+		Alias:     *alias,
+		URL:       *urlToSave,
+		CreatedBy: c.actor,
+	})
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	link.ID, err = s.SaveLink(link)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	rec := c.auditLog(s)
+	rec.RecordAs(c.actor, audit.ActionCreate, link.Domain, link.Alias, nil, rec.Snapshot(link.Domain, link.Alias))
+
+	return printLinks(c.out, c.format, []storage.Link{link})
+}
+
+func (c *command) get(args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	domain := fs.String("domain", "", "custom domain, the default domain when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	alias, err := singleArg("get", fs.Args())
+	if err != nil {
+		return err
+	}
+
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	check, err := c.linkCheck(s)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if *domain, err = check.Domain(*domain); err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if alias, err = check.Alias(alias); err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+
+	urlFound, err := s.GetURL(*domain, alias)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+
+	return printLinks(c.out, c.format, []storage.Link{{Domain: *domain, Alias: alias, URL: urlFound}})
+}
+
+func (c *command) update(args []string) error {
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	alias := fs.String("alias", "", "alias to update")
+	urlToSave := fs.String("url", "", "new target URL")
+	domain := fs.String("domain", "", "custom domain, the default domain when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *alias == "" || *urlToSave == "" {
+		return errors.New("update: -alias and -url are required")
+	}
+
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	check, err := c.linkCheck(s)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if *domain, err = check.Domain(*domain); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if *alias, err = check.Alias(*alias); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	target, original, err := check.Target(context.Background(), *urlToSave)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	rec := c.auditLog(s)
+	old := rec.Snapshot(*domain, *alias)
+
+	if err := s.UpdateURL(*domain, *alias, target, original); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	rec.RecordAs(c.actor, audit.ActionUpdate, *domain, *alias, old, rec.Snapshot(*domain, *alias))
+
+	return printLinks(c.out, c.format, []storage.Link{{Domain: *domain, Alias: *alias, URL: target}})
+}
+
+func (c *command) delete(args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	domain := fs.String("domain", "", "custom domain, the default domain when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	alias, err := singleArg("delete", fs.Args())
+	if err != nil {
+		return err
+	}
+
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	check, err := c.linkCheck(s)
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if *domain, err = check.Domain(*domain); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if alias, err = check.Alias(alias); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	rec := c.auditLog(s)
+	old := rec.Snapshot(*domain, alias)
+
+	if err := s.DeleteURL(*domain, alias); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	rec.RecordAs(c.actor, audit.ActionDelete, *domain, alias, old, nil)
+
+	return printValues(c.out, c.format, []field{{"deleted", alias}})
+}
+
+func (c *command) list(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	limit := fs.Int("limit", 50, "maximum number of links")
+	offset := fs.Int("offset", 0, "number of links to skip")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	links, err := s.ListURLs(*limit, *offset)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+
+	return printLinks(c.out, c.format, links)
+}
+
+func (c *command) export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", transfer.FormatJSONL, "jsonl or csv")
+	file := fs.String("file", "", "write to file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	out := c.out
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	enc, err := transfer.NewEncoder(out, *format)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	count, err := transfer.Export(enc, s)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d links\n", count)
+
+	return nil
+}
+
+func (c *command) importLinks(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", transfer.FormatJSONL, "jsonl or csv")
+	file := fs.String("file", "", "read from file instead of stdin")
+	conflict := fs.String("conflict", string(transfer.ConflictFail), "skip, overwrite, fail or rename")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	policy, err := transfer.ParseConflictPolicy(*conflict)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	in := c.in
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	dec, err := transfer.NewDecoder(in, *format)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	check, err := c.linkCheck(s)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	res, err := transfer.Import(context.Background(), dec, s, policy, check)
+	// Records stored before a failure are kept, so failed imports are
+	// recorded too.
+	c.auditLog(s).RecordAs(c.actor, audit.ActionImport, "", "", nil, res)
+	if printErr := printValues(c.out, c.format, []field{
+		{"created", res.Created},
+		{"overwritten", res.Overwritten},
+		{"skipped", res.Skipped},
+		{"renamed", res.Renamed},
+		{"failed", res.Failed},
+	}); printErr != nil {
+		return printErr
+	}
+	for _, e := range res.Errors {
+		fmt.Fprintf(os.Stderr, "record %d (%s): %s\n", e.Record, e.Alias, e.Error)
+	}
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	return nil
+}
+
+func (c *command) copy(args []string) error {
+	fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+	from := fs.String("from", backendSQLite, "source backend")
+	to := fs.String("to", backendPostgres, "destination backend")
+	batch := fs.Int("batch", copier.DefaultBatchSize, "links per batch")
+	checkpoint := fs.String("checkpoint", "", "file to resume from and record progress in")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == *to {
+		return errors.New("copy: -from and -to must differ")
+	}
+
+	afterID, err := readCheckpoint(*checkpoint)
+	if err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+
+	src, err := c.openBackend(*from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := c.openBackend(*to)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	cp := &copier.Copier{
+		Src:       src,
+		Dst:       dst,
+		BatchSize: *batch,
+		OnBatch: func(lastID int64) error {
+			fmt.Fprintf(os.Stderr, "copied up to id %d\n", lastID)
+			return writeCheckpoint(*checkpoint, lastID)
+		},
+	}
+
+	res, err := cp.Copy(afterID)
+	if err != nil {
+		return fmt.Errorf("copy: %w (resume after id %d)", err, res.LastID)
+	}
+
+	v, err := copier.Verify(src, dst)
+	if err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+
+	if err := printValues(c.out, c.format, []field{
+		{"copied", res.Copied},
+		{"existing", res.Existing},
+		{"last_id", res.LastID},
+		{"source_count", v.SourceCount},
+		{"destination_count", v.DestinationCount},
+		{"source_checksum", v.SourceChecksum},
+		{"destination_checksum", v.DestinationChecksum},
+	}); err != nil {
+		return err
+	}
+
+	if !v.OK() {
+		return errors.New("copy: verification failed, source and destination differ")
+	}
+
+	return nil
+}
+
+func readCheckpoint(path string) (int64, error) {
+	if path == "" {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func writeCheckpoint(path string, lastID int64) error {
+	if path == "" {
+		return nil
+	}
+
+	return os.WriteFile(path, []byte(strconv.FormatInt(lastID, 10)+"\n"), 0o644)
+}
+
+func (c *command) stats(args []string) error {
+	if len(args) != 0 {
+		return errors.New("stats: unexpected arguments")
+	}
+
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	total, err := s.CountURLs()
+	if err != nil {
+		return fmt.Errorf("stats: %w", err)
+	}
+
+	return printValues(c.out, c.format, []field{{"total", total}})
+}
+
+func (c *command) migrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate: expected up, down, goto, force or status")
+	}
+
+	// Arguments are checked, and a full rollback confirmed, before the
+	// database is touched.
+	var apply func(m *scripts.Migrator) error
+	sub, rest := args[0], args[1:]
+	switch sub {
+	case "up":
+		n := 0
+		if len(rest) > 0 {
+			var err error
+			if n, err = stepCount(sub, rest[0]); err != nil {
+				return err
+			}
+		}
+		apply = func(m *scripts.Migrator) error { return m.Up(n) }
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		all := fs.Bool("all", false, "roll back every migration, after confirmation")
+		if err := fs.Parse(rest); err != nil {
+			return err
+		}
+		switch {
+		case *all && fs.NArg() > 0:
+			return errors.New("migrate down: -all takes no step count")
+		case *all:
+			if !c.confirm("Roll back every migration, dropping all links? Type yes to continue: ") {
+				return errors.New("migrate down: not confirmed")
+			}
+			apply = func(m *scripts.Migrator) error { return m.Down(0) }
+		case fs.NArg() == 1:
+			n, err := stepCount(sub, fs.Arg(0))
+			if err != nil {
+				return err
+			}
+			apply = func(m *scripts.Migrator) error { return m.Down(n) }
+		default:
+			return errors.New("migrate down: expected a step count, or -all to roll back everything")
+		}
+	case "goto":
+		if len(rest) != 1 {
+			return errors.New("migrate goto: expected a version")
+		}
+		version, err := strconv.ParseUint(rest[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("migrate goto: invalid version %q", rest[0])
+		}
+		apply = func(m *scripts.Migrator) error { return m.Goto(uint(version)) }
+	case "force":
+		if len(rest) != 1 {
+			return errors.New("migrate force: expected a version")
+		}
+		version, err := strconv.Atoi(rest[0])
+		if err != nil {
+			return fmt.Errorf("migrate force: invalid version %q", rest[0])
+		}
+		apply = func(m *scripts.Migrator) error { return m.Force(version) }
+	case "status":
+	default:
+		return fmt.Errorf("migrate: unknown subcommand %q", sub)
+	}
+
+	dsn := c.cfg.GetDBURL()
+	if c.backend == backendSQLite {
+		dsn = scripts.SQLiteDSN(c.cfg.StoragePath)
+	}
+
+	m, err := scripts.NewMigrator(dsn, c.cfg.MigrationsPath)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer m.Close()
+
+	if apply != nil {
+		if err := apply(m); err != nil {
+			return fmt.Errorf("migrate %s: %w", sub, err)
+		}
+	}
+
+	st, err := m.Status()
+	if err != nil {
+		return fmt.Errorf("migrate %s: %w", sub, err)
+	}
+
+	return printValues(c.out, c.format, []field{
+		{"version", st.Version},
+		{"dirty", st.Dirty},
+		{"pending", strings.Join(st.Pending, " ")},
+	})
+}
+
+func stepCount(sub, arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("migrate %s: invalid step count %q", sub, arg)
+	}
+
+	return n, nil
+}
+
+// confirm asks prompt on stderr and reports whether the answer read from
+// the command's input is "yes".
+func (c *command) confirm(prompt string) bool {
+	fmt.Fprint(os.Stderr, prompt)
+	answer, _ := bufio.NewReader(c.in).ReadString('\n')
+
+	return strings.TrimSpace(answer) == "yes"
+}
+
+func singleArg(name string, args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("%s: expected exactly one alias", name)
+	}
+
+	return args[0], nil
+}
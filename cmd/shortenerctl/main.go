@@ -0,0 +1,207 @@
+// Command shortenerctl is an administration tool that works with the
+// url-shortener storage directly.
+package main
+
+import (
+	"RestApi/internal/config"
+	"RestApi/internal/lib/alias"
+	"RestApi/internal/lib/audit"
+	"RestApi/internal/lib/linkcheck"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/lib/urlnorm"
+	"RestApi/internal/lib/urlpolicy"
+	"RestApi/internal/storage"
+	"RestApi/internal/storage/postgres"
+	"RestApi/internal/storage/sqllite"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+const (
+	backendPostgres = "postgres"
+	backendSQLite   = "sqlite"
+)
+
+const usage = `Usage: shortenerctl [flags] <command> [args]
+
+Commands:
+  create   -url <url> [-alias <alias>]   create a link
+           [-domain <domain>]
+  get      [-domain <domain>] <alias>    print the target of a link
+  update   -alias <alias> -url <url>     change the target of a link
+           [-domain <domain>]
+  delete   [-domain <domain>] <alias>    delete a link
+  list     [-limit n] [-offset n]        list links
+  export   [-format f] [-file path]      dump every link as jsonl or csv
+  import   [-format f] [-file path]      load links, see -conflict
+           [-conflict policy]
+  copy     -from <backend> -to <backend>  copy every link between backends
+           [-batch n] [-checkpoint path]  and verify counts and checksums
+  stats                                  print link statistics
+  migrate  up [n] | down <n>             apply or roll back migrations
+  migrate  down -all                     roll back every migration, asks first
+  migrate  goto <version>                migrate to a version
+  migrate  force <version>               set the version, clearing dirty
+  migrate  status                        print version and pending files
+
+Links are checked against the alias and URL policies and normalized as
+the server does, and changes are written to the audit log as -actor.
+
+Flags:
+`
+
+type Storage interface {
+	SaveURL(urlToSave string, alias string) (int64, error)
+	SaveLink(link storage.Link) (int64, error)
+	GetURL(domain, alias string) (string, error)
+	UpdateURL(domain, alias string, urlToSave, originalURL string) error
+	DeleteURL(domain, alias string) error
+	GetLink(domain, alias string) (storage.Link, error)
+	ListURLs(limit, offset int) ([]storage.Link, error)
+	ListURLsAfter(afterID int64, limit int) ([]storage.Link, error)
+	ListLinksAfter(afterID int64, limit int) ([]storage.Link, error)
+	CountURLs() (int64, error)
+	GetReservation(domain, alias string) (storage.Reservation, error)
+	AppendAudit(entry storage.AuditEntry) error
+	Close() error
+}
+
+type command struct {
+	cfg     *config.Config
+	backend string
+	format  string
+	actor   string
+	log     *slog.Logger
+	in      io.Reader
+	out     io.Writer
+}
+
+func main() {
+	backend := flag.String("storage", backendPostgres, "storage backend: postgres or sqlite")
+	format := flag.String("o", formatTable, "output format: table, json or csv")
+	actor := flag.String("actor", os.Getenv("USER"), "name changes are audited under")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cmd := &command{
+		cfg:     config.MustLoad(),
+		backend: *backend,
+		format:  *format,
+		actor:   *actor,
+		log:     slog.New(slog.NewTextHandler(os.Stderr, nil)),
+		in:      os.Stdin,
+		out:     os.Stdout,
+	}
+
+	if err := cmd.run(flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "shortenerctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (c *command) run(name string, args []string) error {
+	switch name {
+	case "create":
+		return c.create(args)
+	case "get":
+		return c.get(args)
+	case "update":
+		return c.update(args)
+	case "delete":
+		return c.delete(args)
+	case "list":
+		return c.list(args)
+	case "export":
+		return c.export(args)
+	case "import":
+		return c.importLinks(args)
+	case "copy":
+		return c.copy(args)
+	case "stats":
+		return c.stats(args)
+	case "migrate":
+		return c.migrate(args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+func (c *command) openStorage() (Storage, error) {
+	return c.openBackend(c.backend)
+}
+
+func (c *command) openBackend(backend string) (Storage, error) {
+	switch backend {
+	case backendPostgres:
+		return postgres.New(c.cfg.GetDBURL(), c.cfg.HTTPServer.Timeout)
+	case backendSQLite:
+		return sqllite.New(c.cfg.StoragePath)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// linkCheck builds the checks the server applies to new links from the
+// configuration. Reservations are looked up in holds.
+func (c *command) linkCheck(holds linkcheck.ReservationStore) (*linkcheck.Checker, error) {
+	aliasPolicy, err := alias.New(alias.Config{
+		Charset:       c.cfg.Alias.Charset,
+		MinLength:     c.cfg.Alias.MinLength,
+		MaxLength:     c.cfg.Alias.MaxLength,
+		CaseMode:      c.cfg.Alias.CaseMode,
+		Reserved:      c.cfg.Alias.Reserved,
+		BlocklistFile: c.cfg.Alias.BlocklistFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shortURLs, err := shorturl.New(c.cfg.BaseURL, c.cfg.Domains)
+	if err != nil {
+		return nil, err
+	}
+
+	urlPolicy, err := urlpolicy.New(urlpolicy.Config{
+		AllowedSchemes: c.cfg.URLPolicy.AllowedSchemes,
+		MaxLength:      c.cfg.URLPolicy.MaxLength,
+		DomainsFile:    c.cfg.URLPolicy.DomainsFile,
+		BlockPrivate:   c.cfg.URLPolicy.BlockPrivate,
+		ResolveHosts:   c.cfg.URLPolicy.ResolveHosts,
+		OwnDomains:     append(c.cfg.URLPolicy.OwnDomains, c.cfg.HTTPServer.Address),
+	})
+	if err != nil {
+		return nil, err
+	}
+	urlPolicy.AddOwnDomains(shortURLs.Hosts()...)
+
+	var normalizer *urlnorm.Normalizer
+	if c.cfg.Normalization.Enabled {
+		normalizer = urlnorm.New(urlnorm.Options{
+			StripTracking:  c.cfg.Normalization.StripTracking,
+			TrackingParams: c.cfg.Normalization.TrackingParams,
+		})
+	}
+
+	return linkcheck.New(linkcheck.Config{
+		Aliases:    aliasPolicy,
+		Domains:    shortURLs,
+		URLs:       urlPolicy,
+		Normalizer: normalizer,
+		Holds:      holds,
+	}), nil
+}
+
+func (c *command) auditLog(s Storage) *audit.Recorder {
+	return audit.New(c.log, s)
+}
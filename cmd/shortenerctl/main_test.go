@@ -0,0 +1,249 @@
+package main
+
+import (
+	"RestApi/internal/config"
+	"RestApi/internal/lib/alias"
+	"RestApi/internal/storage"
+	"RestApi/internal/storage/sqllite"
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  func(cfg *config.Config)
+		// setup runs before the command under test and must succeed.
+		setup [][]string
+		args  []string
+		// in is what the command reads from stdin, e.g. a confirmation.
+		in      string
+		out     string
+		wantErr string
+		// audit lists the actions expected in the audit log afterwards.
+		audit []string
+	}{
+		{
+			name:    "Unknown command",
+			args:    []string{"rename"},
+			wantErr: `unknown command "rename"`,
+		},
+		{
+			name:    "Create without url",
+			args:    []string{"create", "-alias", "promo"},
+			wantErr: "create: -url is required",
+		},
+		{
+			name:    "Create with unknown flag",
+			args:    []string{"create", "-target", "https://example.com"},
+			wantErr: "flag provided but not defined: -target",
+		},
+		{
+			name:  "Create",
+			args:  []string{"create", "-alias", "promo", "-url", "https://example.com/a"},
+			out:   `[{"id": 1, "domain": "", "alias": "promo", "url": "https://example.com/a"}]`,
+			audit: []string{"create"},
+		},
+		{
+			name: "Create normalizes the target",
+			cfg: func(cfg *config.Config) {
+				cfg.Normalization = config.Normalization{Enabled: true, StripTracking: true}
+			},
+			args:  []string{"create", "-alias", "promo", "-url", "HTTPS://Example.com:443/a?utm_source=x"},
+			out:   `[{"id": 1, "domain": "", "alias": "promo", "url": "https://example.com/a"}]`,
+			audit: []string{"create"},
+		},
+		{
+			name:    "Create rejected by url policy",
+			args:    []string{"create", "-alias", "promo", "-url", "ftp://files.example.com"},
+			wantErr: "create: url scheme is not allowed",
+		},
+		{
+			name:    "Create rejected by alias policy",
+			cfg:     func(cfg *config.Config) { cfg.Alias.Reserved = []string{"admin"} },
+			args:    []string{"create", "-alias", "admin", "-url", "https://example.com"},
+			wantErr: "create: " + alias.ErrReserved.Error(),
+		},
+		{
+			name:    "Create on unknown domain",
+			args:    []string{"create", "-alias", "promo", "-url", "https://example.com", "-domain", "brand.example"},
+			wantErr: "create: unknown domain",
+		},
+		{
+			name:  "Create on custom domain",
+			cfg:   func(cfg *config.Config) { cfg.Domains = []string{"brand.example"} },
+			args:  []string{"create", "-alias", "promo", "-url", "https://example.com", "-domain", "brand.example"},
+			out:   `[{"id": 1, "domain": "brand.example", "alias": "promo", "url": "https://example.com"}]`,
+			audit: []string{"create"},
+		},
+		{
+			name:  "Get",
+			setup: [][]string{{"create", "-alias", "promo", "-url", "https://example.com/a"}},
+			args:  []string{"get", "promo"},
+			out:   `[{"domain": "", "alias": "promo", "url": "https://example.com/a"}]`,
+			audit: []string{"create"},
+		},
+		{
+			name: "Get on custom domain",
+			cfg:  func(cfg *config.Config) { cfg.Domains = []string{"brand.example"} },
+			setup: [][]string{
+				{"create", "-alias", "promo", "-url", "https://example.com/a"},
+				{"create", "-alias", "promo", "-url", "https://example.com/b", "-domain", "brand.example"},
+			},
+			args:  []string{"get", "-domain", "brand.example", "promo"},
+			out:   `[{"domain": "brand.example", "alias": "promo", "url": "https://example.com/b"}]`,
+			audit: []string{"create", "create"},
+		},
+		{
+			name:    "Get without alias",
+			args:    []string{"get"},
+			wantErr: "get: expected exactly one alias",
+		},
+		{
+			name:    "Get missing",
+			args:    []string{"get", "promo"},
+			wantErr: "get: " + storage.ErrURLNotFound.Error(),
+		},
+		{
+			name:  "Get folds the alias",
+			cfg:   func(cfg *config.Config) { cfg.Alias.CaseMode = alias.CaseInsensitive },
+			setup: [][]string{{"create", "-alias", "Promo", "-url", "https://example.com/a"}},
+			args:  []string{"get", "PROMO"},
+			out:   `[{"domain": "", "alias": "promo", "url": "https://example.com/a"}]`,
+			audit: []string{"create"},
+		},
+		{
+			name:    "Update without url",
+			args:    []string{"update", "-alias", "promo"},
+			wantErr: "update: -alias and -url are required",
+		},
+		{
+			name: "Update normalizes the target",
+			cfg: func(cfg *config.Config) {
+				cfg.Normalization = config.Normalization{Enabled: true}
+			},
+			setup: [][]string{{"create", "-alias", "promo", "-url", "https://example.com/a"}},
+			args:  []string{"update", "-alias", "promo", "-url", "HTTPS://Example.com/b"},
+			out:   `[{"domain": "", "alias": "promo", "url": "https://example.com/b"}]`,
+			audit: []string{"create", "update"},
+		},
+		{
+			name:    "Update rejected by url policy",
+			setup:   [][]string{{"create", "-alias", "promo", "-url", "https://example.com/a"}},
+			args:    []string{"update", "-alias", "promo", "-url", "ftp://files.example.com"},
+			wantErr: "update: url scheme is not allowed",
+			audit:   []string{"create"},
+		},
+		{
+			name:    "Update missing",
+			args:    []string{"update", "-alias", "promo", "-url", "https://example.com"},
+			wantErr: "update: " + storage.ErrURLNotFound.Error(),
+		},
+		{
+			name:  "Delete",
+			setup: [][]string{{"create", "-alias", "promo", "-url", "https://example.com/a"}},
+			args:  []string{"delete", "promo"},
+			out:   `{"deleted": "promo"}`,
+			audit: []string{"create", "delete"},
+		},
+		{
+			name:    "Delete missing",
+			args:    []string{"delete", "promo"},
+			wantErr: "delete: " + storage.ErrURLNotFound.Error(),
+		},
+		{
+			name: "List",
+			setup: [][]string{
+				{"create", "-alias", "one", "-url", "https://example.com/1"},
+				{"create", "-alias", "two", "-url", "https://example.com/2"},
+			},
+			args:  []string{"list", "-limit", "1", "-offset", "1"},
+			out:   `[{"id": 2, "domain": "", "alias": "two", "url": "https://example.com/2"}]`,
+			audit: []string{"create", "create"},
+		},
+		{
+			name:    "Stats with arguments",
+			args:    []string{"stats", "all"},
+			wantErr: "stats: unexpected arguments",
+		},
+		{
+			name:    "Migrate without subcommand",
+			args:    []string{"migrate"},
+			wantErr: "migrate: expected up, down, goto, force or status",
+		},
+		{
+			name:    "Migrate down without step count",
+			args:    []string{"migrate", "down"},
+			wantErr: "migrate down: expected a step count, or -all to roll back everything",
+		},
+		{
+			name:    "Migrate down with invalid step count",
+			args:    []string{"migrate", "down", "0"},
+			wantErr: `migrate down: invalid step count "0"`,
+		},
+		{
+			name:    "Migrate down all with step count",
+			args:    []string{"migrate", "down", "-all", "2"},
+			wantErr: "migrate down: -all takes no step count",
+		},
+		{
+			name:    "Migrate down all not confirmed",
+			args:    []string{"migrate", "down", "-all"},
+			in:      "no\n",
+			wantErr: "migrate down: not confirmed",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "storage.db")
+			cfg := &config.Config{StoragePath: path}
+			if tc.cfg != nil {
+				tc.cfg(cfg)
+			}
+
+			var out bytes.Buffer
+			cmd := &command{
+				cfg:     cfg,
+				backend: backendSQLite,
+				format:  formatJSON,
+				actor:   "ops",
+				log:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+				in:      strings.NewReader(tc.in),
+				out:     io.Discard,
+			}
+			for _, args := range tc.setup {
+				require.NoError(t, cmd.run(args[0], args[1:]))
+			}
+
+			cmd.out = &out
+			err := cmd.run(tc.args[0], tc.args[1:])
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.JSONEq(t, tc.out, out.String())
+			}
+
+			s, err := sqllite.New(path)
+			require.NoError(t, err)
+			defer s.Close()
+
+			entries, err := s.ListAudit(storage.AuditFilter{}, 10, 0)
+			require.NoError(t, err)
+			actions := make([]string, 0, len(entries))
+			for _, e := range entries {
+				require.Equal(t, "ops", e.Actor)
+				actions = append(actions, e.Action)
+			}
+			require.ElementsMatch(t, tc.audit, actions)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"RestApi/internal/storage"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+type field struct {
+	Key   string
+	Value any
+}
+
+type linkRecord struct {
+	ID int64 `json:"id,omitempty"`
+	// Domain is empty for links on the default domain.
+	Domain string `json:"domain"`
+	Alias  string `json:"alias"`
+	URL    string `json:"url"`
+}
+
+func printLinks(w io.Writer, format string, links []storage.Link) error {
+	switch format {
+	case formatJSON:
+		records := make([]linkRecord, 0, len(links))
+		for _, link := range links {
+			records = append(records, linkRecord{ID: link.ID, Domain: link.Domain, Alias: link.Alias, URL: link.URL})
+		}
+		return writeJSON(w, records)
+	case formatCSV:
+		rows := [][]string{{"id", "domain", "alias", "url"}}
+		for _, link := range links {
+			rows = append(rows, []string{strconv.FormatInt(link.ID, 10), link.Domain, link.Alias, link.URL})
+		}
+		return writeCSV(w, rows)
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tDOMAIN\tALIAS\tURL")
+		for _, link := range links {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", link.ID, link.Domain, link.Alias, link.URL)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+func printValues(w io.Writer, format string, fields []field) error {
+	switch format {
+	case formatJSON:
+		obj := make(map[string]any, len(fields))
+		for _, f := range fields {
+			obj[f.Key] = f.Value
+		}
+		return writeJSON(w, obj)
+	case formatCSV:
+		header := make([]string, 0, len(fields))
+		values := make([]string, 0, len(fields))
+		for _, f := range fields {
+			header = append(header, f.Key)
+			values = append(values, fmt.Sprint(f.Value))
+		}
+		return writeCSV(w, [][]string{header, values})
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, f := range fields {
+			fmt.Fprintf(tw, "%s\t%v\n", f.Key, f.Value)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+func writeCSV(w io.Writer, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"RestApi/internal/storage"
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPrintLinks(t *testing.T) {
+	links := []storage.Link{
+		{ID: 1, Alias: "promo", URL: "https://example.com/a"},
+		{ID: 2, Domain: "brand.example", Alias: "promo", URL: "https://example.com/b"},
+	}
+
+	cases := []struct {
+		format string
+		out    string
+	}{
+		{
+			format: formatTable,
+			out: "ID  DOMAIN         ALIAS  URL\n" +
+				"1                  promo  https://example.com/a\n" +
+				"2   brand.example  promo  https://example.com/b\n",
+		},
+		{
+			format: formatCSV,
+			out: "id,domain,alias,url\n" +
+				"1,,promo,https://example.com/a\n" +
+				"2,brand.example,promo,https://example.com/b\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.format, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, printLinks(&out, tc.format, links))
+			require.Equal(t, tc.out, out.String())
+		})
+	}
+}
@@ -2,14 +2,38 @@ package main
 
 import (
 	"RestApi/internal/config"
+	"RestApi/internal/http-server/handlers/qrcode"
 	"RestApi/internal/http-server/handlers/redirect"
+	"RestApi/internal/http-server/handlers/url/auditlog"
+	"RestApi/internal/http-server/handlers/url/available"
 	"RestApi/internal/http-server/handlers/url/delete"
+	"RestApi/internal/http-server/handlers/url/export"
 	"RestApi/internal/http-server/handlers/url/get"
+	"RestApi/internal/http-server/handlers/url/imports"
+	"RestApi/internal/http-server/handlers/url/list"
+	"RestApi/internal/http-server/handlers/url/reserve"
+	"RestApi/internal/http-server/handlers/url/restore"
 	"RestApi/internal/http-server/handlers/url/save"
+	"RestApi/internal/http-server/handlers/url/stats"
+	"RestApi/internal/http-server/handlers/url/targets"
+	"RestApi/internal/http-server/handlers/url/targetstats"
+	"RestApi/internal/http-server/handlers/url/update"
+	"RestApi/internal/http-server/middleware/auth"
 	mwLogger "RestApi/internal/http-server/middleware/logger"
+	"RestApi/internal/lib/alias"
+	"RestApi/internal/lib/audit"
 	"RestApi/internal/lib/handlers/slogpretty"
+	"RestApi/internal/lib/linkauth"
+	"RestApi/internal/lib/linkcheck"
+	"RestApi/internal/lib/reachability"
+	"RestApi/internal/lib/retention"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/lib/targeting"
+	"RestApi/internal/lib/urlnorm"
+	"RestApi/internal/lib/urlpolicy"
 	"RestApi/internal/storage/postgres"
 	"RestApi/storage/scripts"
+	"context"
 	"flag"
 	"fmt"
 	"github.com/go-chi/chi/v5"
@@ -18,6 +42,8 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 )
 
 const (
@@ -26,6 +52,8 @@ const (
 	envProd  = "prod"
 )
 
+var migrateFlag = flag.Bool("migrate", false, "Run database migration")
+
 func main() {
 	cfg := initializeConfig()
 	fmt.Println("cfg__________->", cfg.GetDBURL())
@@ -39,7 +67,43 @@ func main() {
 	logStartupInfo(logger, cfg.Env)
 
 	storage := initializeStorage(logger, cfg)
-	router := setupRouter(logger, cfg, storage)
+	aliasPolicy := initializeAliasPolicy(logger, cfg)
+	shortURLs := initializeShortURLs(logger, cfg)
+	urlPolicy := initializeURLPolicy(logger, cfg)
+	urlPolicy.AddOwnDomains(shortURLs.Hosts()...)
+	go urlPolicy.Watch(context.Background(), logger, cfg.URLPolicy.ReloadInterval)
+
+	checker := reachability.New(reachability.Config{
+		Timeout:      cfg.Reachability.Timeout,
+		MaxRedirects: cfg.Reachability.MaxRedirects,
+	})
+	if cfg.Reachability.CheckInterval > 0 {
+		monitor := &reachability.Monitor{
+			Checker:  checker,
+			Store:    storage,
+			Log:      logger,
+			Interval: cfg.Reachability.CheckInterval,
+		}
+		go monitor.Run(context.Background())
+	}
+
+	if cfg.Deletion.PurgeInterval > 0 {
+		purger := &retention.Purger{
+			Store:     storage,
+			Log:       logger,
+			Retention: cfg.Deletion.Retention,
+			Interval:  cfg.Deletion.PurgeInterval,
+		}
+		go purger.Run(context.Background())
+	}
+
+	evaluator := initializeTargeting(logger, cfg)
+	if !redirect.ValidType(cfg.Redirect.Type) {
+		logger.Error("Unknown redirect type", slog.String("type", cfg.Redirect.Type))
+		os.Exit(1)
+	}
+
+	router := setupRouter(logger, cfg, storage, aliasPolicy, urlPolicy, shortURLs, checker, evaluator)
 
 	startServer(logger, cfg, router)
 }
@@ -50,12 +114,11 @@ func initializeConfig() *config.Config {
 }
 
 func shouldRunMigrations() bool {
-	migrateFlag := flag.Bool("migrate", false, "Run database migration")
 	return *migrateFlag
 }
 
 func runMigrations(cfg *config.Config) {
-	if err := scripts.RunMigrations(cfg.GetDBURL()); err != nil {
+	if err := scripts.RunMigrations(cfg.GetDBURL(), cfg.MigrationsPath); err != nil {
 		log.Fatalf("Migrations failed: %v", err)
 	}
 	log.Println("Migrations completed successfully")
@@ -93,7 +156,77 @@ func initializeStorage(logger *slog.Logger, cfg *config.Config) *postgres.Storag
 	return storage
 }
 
-func setupRouter(logger *slog.Logger, cfg *config.Config, storage *postgres.Storage) *chi.Mux {
+func initializeAliasPolicy(logger *slog.Logger, cfg *config.Config) *alias.Policy {
+	policy, err := alias.New(alias.Config{
+		Charset:       cfg.Alias.Charset,
+		MinLength:     cfg.Alias.MinLength,
+		MaxLength:     cfg.Alias.MaxLength,
+		CaseMode:      cfg.Alias.CaseMode,
+		Reserved:      cfg.Alias.Reserved,
+		BlocklistFile: cfg.Alias.BlocklistFile,
+	})
+	if err != nil {
+		logger.Error("Failed to initialize alias policy", "error", err.Error())
+		os.Exit(1)
+	}
+	return policy
+}
+
+func initializeURLPolicy(logger *slog.Logger, cfg *config.Config) *urlpolicy.Policy {
+	policy, err := urlpolicy.New(urlpolicy.Config{
+		AllowedSchemes: cfg.URLPolicy.AllowedSchemes,
+		MaxLength:      cfg.URLPolicy.MaxLength,
+		DomainsFile:    cfg.URLPolicy.DomainsFile,
+		BlockPrivate:   cfg.URLPolicy.BlockPrivate,
+		ResolveHosts:   cfg.URLPolicy.ResolveHosts,
+		OwnDomains:     append(cfg.URLPolicy.OwnDomains, cfg.HTTPServer.Address),
+	})
+	if err != nil {
+		logger.Error("Failed to initialize url policy", "error", err.Error())
+		os.Exit(1)
+	}
+	return policy
+}
+
+func initializeShortURLs(logger *slog.Logger, cfg *config.Config) *shorturl.Builder {
+	builder, err := shorturl.New(cfg.BaseURL, cfg.Domains)
+	if err != nil {
+		logger.Error("Failed to initialize short urls", "error", err.Error())
+		os.Exit(1)
+	}
+	return builder
+}
+
+func initializeTargeting(logger *slog.Logger, cfg *config.Config) *targeting.Evaluator {
+	loc, err := time.LoadLocation(cfg.Targeting.Timezone)
+	if err != nil {
+		logger.Error("Failed to load targeting timezone", "error", err.Error())
+		os.Exit(1)
+	}
+
+	targetingCfg := targeting.Config{Location: loc}
+	if cfg.Targeting.GeoIPFile != "" {
+		geo, err := targeting.OpenGeoIP(cfg.Targeting.GeoIPFile)
+		if err != nil {
+			logger.Error("Failed to open GeoIP database", "error", err.Error())
+			os.Exit(1)
+		}
+		targetingCfg.GeoIP = geo
+	}
+
+	return targeting.New(targetingCfg)
+}
+
+func setupRouter(
+	logger *slog.Logger,
+	cfg *config.Config,
+	storage *postgres.Storage,
+	aliasPolicy *alias.Policy,
+	urlPolicy *urlpolicy.Policy,
+	shortURLs *shorturl.Builder,
+	checker *reachability.Checker,
+	evaluator *targeting.Evaluator,
+) *chi.Mux {
 	router := chi.NewRouter()
 
 	// Common middleware
@@ -107,21 +240,141 @@ func setupRouter(logger *slog.Logger, cfg *config.Config, storage *postgres.Stor
 
 	// Protected routes
 	router.Route("/url", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("url-shortener", map[string]string{
+		r.Use(auth.New("url-shortener", map[string]string{
 			cfg.HTTPServer.User: cfg.HTTPServer.Password,
-		}))
+		}, cfg.HTTPServer.APIKeys))
+
+		auditLog := audit.New(logger, storage)
+
+		var normalizer *urlnorm.Normalizer
+		if cfg.Normalization.Enabled {
+			normalizer = urlnorm.New(urlnorm.Options{
+				StripTracking:  cfg.Normalization.StripTracking,
+				TrackingParams: cfg.Normalization.TrackingParams,
+			})
+		}
+		linkCheck := linkcheck.New(linkcheck.Config{
+			Aliases:    aliasPolicy,
+			Domains:    shortURLs,
+			URLs:       urlPolicy,
+			Normalizer: normalizer,
+			Holds:      storage,
+		})
+
+		saveOpts := []save.Option{
+			save.WithAliasPolicy(aliasPolicy),
+			save.WithURLPolicy(urlPolicy),
+			save.WithShortURL(shortURLs),
+			save.WithAudit(auditLog),
+			save.WithReservations(storage),
+		}
+		if normalizer != nil {
+			saveOpts = append(saveOpts, save.WithNormalizer(normalizer))
+		}
+
+		if cfg.Reachability.Enabled {
+			saveOpts = append(saveOpts, save.WithReachabilityCheck(checker, cfg.Reachability.Mode == "reject"))
+		}
+
+		r.Post("/", save.New(logger, storage, saveOpts...))
+		updateOpts := []update.Option{
+			update.WithAliasPolicy(aliasPolicy),
+			update.WithURLPolicy(urlPolicy),
+			update.WithShortURL(shortURLs),
+			update.WithNormalizer(normalizer),
+			update.WithAudit(auditLog),
+		}
+		if cfg.Reachability.Enabled {
+			updateOpts = append(updateOpts, update.WithReachabilityCheck(checker, cfg.Reachability.Mode == "reject"))
+		}
 
-		r.Post("/", save.New(logger, storage))
-		r.Post("/get-url", get.New(logger, storage))
-		r.Delete("/delete-url", delete.New(logger, storage))
+		r.Put("/update-url", update.New(logger, storage, updateOpts...))
+		r.Post("/get-url", get.New(logger, storage,
+			get.WithAliasPolicy(aliasPolicy),
+			get.WithShortURL(shortURLs),
+		))
+		r.Delete("/delete-url", delete.New(logger, storage,
+			delete.WithAliasPolicy(aliasPolicy),
+			delete.WithShortURL(shortURLs),
+			delete.WithAudit(auditLog),
+		))
+		r.Post("/restore", restore.New(logger, storage,
+			restore.WithAliasPolicy(aliasPolicy),
+			restore.WithShortURL(shortURLs),
+			restore.WithAudit(auditLog),
+		))
+		r.Get("/list", list.New(logger, storage, list.WithShortURL(shortURLs)))
+		r.Get("/stats", stats.New(logger, storage))
+		r.Get("/export", export.New(logger, storage))
+		r.Post("/import", imports.New(logger, storage,
+			imports.WithLinkCheck(linkCheck),
+			imports.WithAudit(auditLog),
+		))
+		r.Put("/targets", targets.New(logger, storage,
+			targets.WithAliasPolicy(aliasPolicy),
+			targets.WithURLPolicy(urlPolicy),
+			targets.WithShortURL(shortURLs),
+			targets.WithAudit(auditLog),
+		))
+		r.Get("/targets", targetstats.New(logger, storage,
+			targetstats.WithAliasPolicy(aliasPolicy),
+			targetstats.WithShortURL(shortURLs),
+		))
+		r.Get("/audit", auditlog.New(logger, storage, auditlog.WithAliasPolicy(aliasPolicy)))
+		r.Get("/available", available.New(logger, storage,
+			available.WithAliasPolicy(aliasPolicy),
+			available.WithShortURL(shortURLs),
+		))
+		r.Post("/reserve", reserve.New(logger, storage,
+			reserve.WithAliasPolicy(aliasPolicy),
+			reserve.WithShortURL(shortURLs),
+		))
 	})
 
-	// Public route
-	router.Get("/{alias}", redirect.New(logger, storage))
+	// Public route, POST carries the password form of protected links
+	redirectHandler := redirect.New(logger, storage,
+		redirect.WithAliasPolicy(aliasPolicy),
+		redirect.WithPlaceholder(cfg.Placeholder),
+		redirect.WithTargeting(evaluator),
+		redirect.WithRedirectType(cfg.Redirect.Type),
+		redirect.WithMetaDelay(cfg.Redirect.MetaDelay),
+		redirect.WithShortURL(shortURLs),
+		redirect.WithLinkAuth(linkauth.New(linkauth.Config{
+			Secret:        cfg.LinkAuth.CookieSecret,
+			CookieTTL:     cfg.LinkAuth.CookieTTL,
+			MaxAttempts:   cfg.LinkAuth.MaxAttempts,
+			LockoutWindow: cfg.LinkAuth.LockoutWindow,
+		})),
+	)
+	// Takes precedence over the path passthrough of the alias
+	router.Get("/{alias}/qr", qrcode.New(logger, storage,
+		qrcode.WithAliasPolicy(aliasPolicy),
+		qrcode.WithShortURL(shortURLs),
+	))
+	router.Get("/{alias}", redirectHandler)
+	router.Post("/{alias}", redirectHandler)
+	// Links with path passthrough use the alias as a prefix
+	router.Get("/{alias}/*", redirectHandler)
+	router.Post("/{alias}/*", redirectHandler)
+
+	// Aliases must never shadow a route
+	aliasPolicy.Reserve(routePrefixes(router)...)
 
 	return router
 }
 
+func routePrefixes(router chi.Routes) []string {
+	var prefixes []string
+	_ = chi.Walk(router, func(_ string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		prefix, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if prefix != "" && !strings.HasPrefix(prefix, "{") {
+			prefixes = append(prefixes, prefix)
+		}
+		return nil
+	})
+	return prefixes
+}
+
 func startServer(logger *slog.Logger, cfg *config.Config, router *chi.Mux) {
 	server := &http.Server{
 		Addr:              cfg.HTTPServer.Address,
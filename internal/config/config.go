@@ -11,15 +11,41 @@ import (
 )
 
 type Config struct {
-	Env         string `yaml:"env" env:"ENV" env-default:"local"`
-	StoragePath string `yaml:"storage_path" env:"STORAGE_PATH"`
-	Database    struct {
+	Env            string `yaml:"env" env:"ENV" env-default:"local"`
+	StoragePath    string `yaml:"storage_path" env:"STORAGE_PATH"`
+	MigrationsPath string `yaml:"migrations_path" env:"MIGRATIONS_PATH"`
+	Database       struct {
 		Host    string `yaml:"host" env:"DB_HOST"`
 		Port    string `yaml:"port" env:"DB_PORT"`
 		Name    string `yaml:"name" env:"DB_NAME"`
 		SSLMode string `yaml:"ssl_mode" env:"DB_SSLMODE"`
 	} `yaml:"database"`
-	HTTPServer `yaml:"http_server"`
+	HTTPServer    `yaml:"http_server"`
+	Alias         Alias         `yaml:"alias"`
+	URLPolicy     URLPolicy     `yaml:"url_policy"`
+	Normalization Normalization `yaml:"normalization"`
+	Reachability  Reachability  `yaml:"reachability"`
+	LinkAuth      LinkAuth      `yaml:"link_auth"`
+	Targeting     Targeting     `yaml:"targeting"`
+	Redirect      Redirect      `yaml:"redirect"`
+	Deletion      Deletion      `yaml:"deletion"`
+	// Placeholder is where links that are not active yet redirect to.
+	// They answer 404 when empty.
+	Placeholder string `yaml:"placeholder" env:"LINK_PLACEHOLDER"`
+	// BaseURL is the public URL short links are shared under, e.g.
+	// "https://sho.rt". The host requests are sent to is used when empty.
+	BaseURL string `yaml:"base_url" env:"BASE_URL"`
+	// Domains are custom hosts links may also be shared on.
+	Domains []string `yaml:"domains" env:"DOMAINS"`
+}
+
+type Alias struct {
+	Charset       string   `yaml:"charset" env:"ALIAS_CHARSET"`
+	MinLength     int      `yaml:"min_length" env:"ALIAS_MIN_LENGTH"`
+	MaxLength     int      `yaml:"max_length" env:"ALIAS_MAX_LENGTH"`
+	CaseMode      string   `yaml:"case_mode" env:"ALIAS_CASE_MODE"`
+	Reserved      []string `yaml:"reserved" env:"ALIAS_RESERVED"`
+	BlocklistFile string   `yaml:"blocklist_file" env:"ALIAS_BLOCKLIST_FILE"`
 }
 
 type HTTPServer struct {
@@ -28,6 +54,69 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
 	User        string        `yaml:"user" env:"HTTP_USER"`
 	Password    string        `yaml:"password" env:"HTTP_PASSWORD"`
+	// APIKeys maps keys accepted in the X-API-Key header to the user
+	// they act as, e.g. "ci" for a deploy pipeline.
+	APIKeys map[string]string `yaml:"api_keys" env:"HTTP_API_KEYS"`
+}
+
+type URLPolicy struct {
+	AllowedSchemes []string      `yaml:"allowed_schemes" env:"URL_ALLOWED_SCHEMES"`
+	MaxLength      int           `yaml:"max_length" env:"URL_MAX_LENGTH"`
+	DomainsFile    string        `yaml:"domains_file" env:"URL_DOMAINS_FILE"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"URL_RELOAD_INTERVAL"`
+	BlockPrivate   bool          `yaml:"block_private" env:"URL_BLOCK_PRIVATE"`
+	ResolveHosts   bool          `yaml:"resolve_hosts" env:"URL_RESOLVE_HOSTS"`
+	OwnDomains     []string      `yaml:"own_domains" env:"URL_OWN_DOMAINS"`
+}
+
+type Normalization struct {
+	Enabled        bool     `yaml:"enabled" env:"URL_NORMALIZE" env-default:"true"`
+	StripTracking  bool     `yaml:"strip_tracking" env:"URL_STRIP_TRACKING"`
+	TrackingParams []string `yaml:"tracking_params" env:"URL_TRACKING_PARAMS"`
+}
+
+type Reachability struct {
+	// Enabled checks targets on save. Mode is "flag" to store dead links
+	// marked as such, or "reject" to refuse them.
+	Enabled      bool          `yaml:"enabled" env:"REACHABILITY_ENABLED"`
+	Mode         string        `yaml:"mode" env:"REACHABILITY_MODE" env-default:"flag"`
+	Timeout      time.Duration `yaml:"timeout" env:"REACHABILITY_TIMEOUT"`
+	MaxRedirects int           `yaml:"max_redirects" env:"REACHABILITY_MAX_REDIRECTS"`
+	// CheckInterval re-checks every stored link periodically, 0 disables.
+	CheckInterval time.Duration `yaml:"check_interval" env:"REACHABILITY_CHECK_INTERVAL"`
+}
+
+type LinkAuth struct {
+	// CookieSecret signs access cookies of password-protected links. It
+	// must be shared by all instances; a random one is used when empty.
+	CookieSecret  string        `yaml:"cookie_secret" env:"LINK_COOKIE_SECRET"`
+	CookieTTL     time.Duration `yaml:"cookie_ttl" env:"LINK_COOKIE_TTL"`
+	MaxAttempts   int           `yaml:"max_attempts" env:"LINK_MAX_ATTEMPTS"`
+	LockoutWindow time.Duration `yaml:"lockout_window" env:"LINK_LOCKOUT_WINDOW"`
+}
+
+type Targeting struct {
+	// GeoIPFile is a MaxMind country database; country rules never match
+	// without one.
+	GeoIPFile string `yaml:"geoip_file" env:"TARGETING_GEOIP_FILE"`
+	// Timezone is the IANA zone hour rules are evaluated in.
+	Timezone string `yaml:"timezone" env:"TARGETING_TIMEZONE" env-default:"UTC"`
+}
+
+type Redirect struct {
+	// Type is used for links stored without one: 301, 302, 307, 308,
+	// meta or frame.
+	Type      string        `yaml:"type" env:"REDIRECT_TYPE" env-default:"302"`
+	MetaDelay time.Duration `yaml:"meta_delay" env:"REDIRECT_META_DELAY"`
+}
+
+type Deletion struct {
+	// Retention is how long deleted links can be restored. Their aliases
+	// are quarantined for as long and cannot be reused.
+	Retention time.Duration `yaml:"retention" env:"DELETION_RETENTION" env-default:"720h"`
+	// PurgeInterval is how often links past the retention are removed for
+	// good, 0 keeps them forever.
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"DELETION_PURGE_INTERVAL" env-default:"1h"`
 }
 
 func MustLoad() *Config {
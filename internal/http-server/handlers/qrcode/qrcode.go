@@ -0,0 +1,219 @@
+package qrcode
+
+import (
+	"RestApi/internal/lib/alias"
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/qr"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// cacheControl lets browsers and proxies keep codes for a day. A code
+// only depends on the short URL, which never changes for an alias.
+const cacheControl = "public, max-age=86400"
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=LinkGetter
+type LinkGetter interface {
+	GetLink(domain, alias string) (storage.Link, error)
+}
+
+type options struct {
+	aliasPolicy *alias.Policy
+	shortURLs   *shorturl.Builder
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy normalizes aliases the way the save handler stores them.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithShortURL sets how the encoded short URL is built. It is taken from
+// the request host otherwise.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+// New answers with a QR code of the short URL of the alias. The format
+// is taken from the format query parameter or a .png or .svg extension,
+// size, level, margin, fg and bg adjust the image.
+func New(log *slog.Logger, getter LinkGetter, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.qrcode.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := o.aliasPolicy.Normalize(chi.URLParam(r, "alias"))
+		if alias == "" {
+			log.Info("alias is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid request"))
+
+			return
+		}
+
+		format, qrOpts, err := parseParams(r)
+		if err != nil {
+			log.Info("invalid qr parameters", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+
+		link, err := getter.GetLink(o.shortURLs.Domain(r), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error("url not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", "error", err.Error())
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to get url"))
+
+			return
+		}
+		if !link.DeletedAt.IsZero() {
+			log.Info("link deleted", slog.String("alias", alias))
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, resp.Error("link deleted"))
+
+			return
+		}
+
+		content := o.shortURLs.URL(r, alias)
+		tag := etag(content, format, qrOpts)
+		w.Header().Set("ETag", tag)
+		w.Header().Set("Cache-Control", cacheControl)
+		if matches(r.Header.Get("If-None-Match"), tag) {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		var body []byte
+		contentType := "image/png"
+		if format == FormatSVG {
+			contentType = "image/svg+xml"
+			body, err = qr.SVG(content, qrOpts)
+		} else {
+			body, err = qr.PNG(content, qrOpts)
+		}
+		if err != nil {
+			log.Error("failed to render qr code", "error", err.Error())
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to render qr code"))
+
+			return
+		}
+
+		log.Info("qr code rendered", slog.String("alias", alias), slog.String("format", format))
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = w.Write(body)
+	}
+}
+
+// parseParams reads the image format and options from r, keeping the
+// defaults for parameters that are not set.
+func parseParams(r *http.Request) (string, qr.Options, error) {
+	q := r.URL.Query()
+	o := qr.DefaultOptions()
+
+	format := q.Get("format")
+	if format == "" {
+		format, _ = r.Context().Value(middleware.URLFormatCtxKey).(string)
+	}
+	switch format {
+	case "":
+		format = FormatPNG
+	case FormatPNG, FormatSVG:
+	default:
+		return "", o, errors.New("invalid format")
+	}
+
+	if v := q.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return "", o, qr.ErrInvalidSize
+		}
+		o.Size = size
+	}
+	if v := q.Get("level"); v != "" {
+		o.Level = strings.ToUpper(v)
+	}
+	if v := q.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil {
+			return "", o, qr.ErrInvalidMargin
+		}
+		o.Margin = margin
+	}
+
+	var err error
+	if v := q.Get("fg"); v != "" {
+		if o.Foreground, err = qr.ParseColor(v); err != nil {
+			return "", o, err
+		}
+	}
+	if v := q.Get("bg"); v != "" {
+		if o.Background, err = qr.ParseColor(v); err != nil {
+			return "", o, err
+		}
+	}
+
+	return format, o, o.Validate()
+}
+
+// etag identifies a rendered code by everything it is drawn from.
+func etag(content, format string, o qr.Options) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%d\x00%s\x00%d\x00%v\x00%v",
+		content, format, o.Size, o.Level, o.Margin, o.Foreground, o.Background))
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matches reports whether an If-None-Match header lists tag.
+func matches(header, tag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+
+	return false
+}
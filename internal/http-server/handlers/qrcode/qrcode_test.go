@@ -0,0 +1,121 @@
+package qrcode_test
+
+import (
+	"RestApi/internal/http-server/handlers/qrcode"
+	"RestApi/internal/http-server/handlers/qrcode/mocks"
+	"RestApi/internal/storage"
+	"bytes"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newRouter(getter qrcode.LinkGetter) http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.URLFormat)
+	r.Get("/{alias}/qr", qrcode.New(slog.New(slog.NewTextHandler(io.Discard, nil)), getter))
+
+	return r
+}
+
+func TestQRCode(t *testing.T) {
+	cases := []struct {
+		name        string
+		path        string
+		code        int
+		contentType string
+	}{
+		{name: "png by default", path: "/abc/qr", code: http.StatusOK, contentType: "image/png"},
+		{name: "svg by extension", path: "/abc/qr.svg", code: http.StatusOK, contentType: "image/svg+xml"},
+		{name: "svg by parameter", path: "/abc/qr?format=svg&level=h&fg=336699&bg=fff", code: http.StatusOK, contentType: "image/svg+xml"},
+		{name: "unknown format", path: "/abc/qr?format=gif", code: http.StatusBadRequest},
+		{name: "size out of range", path: "/abc/qr?size=10000", code: http.StatusBadRequest},
+		{name: "bad margin", path: "/abc/qr?margin=x", code: http.StatusBadRequest},
+		{name: "bad colour", path: "/abc/qr?fg=red", code: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			getter := mocks.NewLinkGetter(t)
+			if tc.code == http.StatusOK {
+				getter.On("GetLink", "", "abc").Return(storage.Link{Alias: "abc", URL: "https://example.com"}, nil).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			newRouter(getter).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.code, rr.Code)
+			if tc.contentType != "" {
+				require.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+				require.NotEmpty(t, rr.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestQRCodeSize(t *testing.T) {
+	getter := mocks.NewLinkGetter(t)
+	getter.On("GetLink", "", "abc").Return(storage.Link{Alias: "abc", URL: "https://example.com"}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	newRouter(getter).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/abc/qr.png?size=300&margin=0", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	img, err := png.Decode(bytes.NewReader(rr.Body.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 300, img.Bounds().Dx())
+}
+
+func TestQRCodeNotFound(t *testing.T) {
+	getter := mocks.NewLinkGetter(t)
+	getter.On("GetLink", "", "missing").Return(storage.Link{}, storage.ErrURLNotFound).Once()
+
+	rr := httptest.NewRecorder()
+	newRouter(getter).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/missing/qr", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestQRCodeDeleted(t *testing.T) {
+	getter := mocks.NewLinkGetter(t)
+	getter.On("GetLink", "", "gone").
+		Return(storage.Link{Alias: "gone", URL: "https://example.com", DeletedAt: time.Now()}, nil).
+		Once()
+
+	rr := httptest.NewRecorder()
+	newRouter(getter).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/gone/qr", nil))
+	require.Equal(t, http.StatusGone, rr.Code)
+}
+
+func TestQRCodeETag(t *testing.T) {
+	getter := mocks.NewLinkGetter(t)
+	getter.On("GetLink", "", "abc").Return(storage.Link{Alias: "abc", URL: "https://example.com"}, nil).Times(3)
+	router := newRouter(getter)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/abc/qr", nil))
+	tag := rr.Header().Get("ETag")
+	require.NotEmpty(t, tag)
+	require.Contains(t, rr.Header().Get("Cache-Control"), "max-age")
+
+	req := httptest.NewRequest(http.MethodGet, "/abc/qr", nil)
+	req.Header.Set("If-None-Match", `"other", `+tag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotModified, rr.Code)
+	require.Empty(t, rr.Body.Bytes())
+
+	// Other parameters make another code.
+	req = httptest.NewRequest(http.MethodGet, "/abc/qr?size=512", nil)
+	req.Header.Set("If-None-Match", tag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotEqual(t, tag, rr.Header().Get("ETag"))
+}
@@ -0,0 +1,34 @@
+package redirect
+
+import (
+	"html/template"
+	"net/http"
+)
+
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<form method="post">
+<p>This link is password protected.</p>
+{{if .}}<p role="alert">{{.}}</p>{{end}}
+<label>Password <input type="password" name="password" autofocus required></label>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// renderPasswordForm asks for the link's password. The form posts back
+// to the same URL, so the redirect handler has to be routed for POST too.
+func renderPasswordForm(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = passwordForm.Execute(w, message)
+}
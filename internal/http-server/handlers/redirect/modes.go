@@ -0,0 +1,107 @@
+package redirect
+
+import (
+	"RestApi/internal/storage"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// Redirect types a link can be stored with.
+const (
+	TypeMovedPermanently  = "301"
+	TypeFound             = "302"
+	TypeTemporaryRedirect = "307"
+	TypePermanentRedirect = "308"
+	// TypeMeta answers with a page that refreshes to the target.
+	TypeMeta = "meta"
+	// TypeFrame shows the target in a full-page frame under the short
+	// URL. Targets that forbid framing stay blank.
+	TypeFrame = "frame"
+)
+
+var statusCodes = map[string]int{
+	TypeMovedPermanently:  http.StatusMovedPermanently,
+	TypeFound:             http.StatusFound,
+	TypeTemporaryRedirect: http.StatusTemporaryRedirect,
+	TypePermanentRedirect: http.StatusPermanentRedirect,
+}
+
+// ValidType reports whether t is a known redirect type.
+func ValidType(t string) bool {
+	_, ok := statusCodes[t]
+
+	return ok || t == TypeMeta || t == TypeFrame
+}
+
+var metaPage = template.Must(template.New("meta").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Delay}};url={{.URL}}">
+<meta name="robots" content="noindex">
+<title>Redirecting</title>
+</head>
+<body>
+<p>You are being redirected to <a href="{{.URL}}">{{.URL}}</a>.</p>
+</body>
+</html>
+`))
+
+var framePage = template.Must(template.New("frame").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.URL}}</title>
+<style>html,body,iframe{margin:0;padding:0;border:0;width:100%;height:100%;overflow:hidden}</style>
+</head>
+<body>
+<iframe src="{{.URL}}" title="{{.URL}}"></iframe>
+</body>
+</html>
+`))
+
+type page struct {
+	URL   string
+	Delay int
+}
+
+// send forwards the visitor to target the way the link asks for.
+func (o *options) send(w http.ResponseWriter, r *http.Request, link storage.Link, target string) {
+	typ := link.RedirectType
+	if !ValidType(typ) {
+		typ = o.redirectType
+	}
+
+	switch typ {
+	case TypeMeta:
+		renderPage(w, metaPage, page{URL: target, Delay: int(o.metaDelay / time.Second)})
+	case TypeFrame:
+		renderPage(w, framePage, page{URL: target})
+	default:
+		code := statusCodes[typ]
+		if code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect {
+			// Browsers keep permanent redirects indefinitely, which
+			// would bypass click limits, expiry and per-visitor targets.
+			if dynamic(link) {
+				w.Header().Set("Cache-Control", "no-store")
+			}
+		}
+		http.Redirect(w, r, target, code)
+	}
+}
+
+func renderPage(w http.ResponseWriter, tmpl *template.Template, p page) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	_ = tmpl.Execute(w, p)
+}
+
+// dynamic reports whether the link may not lead to the same place for
+// every visit.
+func dynamic(link storage.Link) bool {
+	return link.PasswordHash != "" || link.MaxClicks > 0 || !link.NotAfter.IsZero() ||
+		len(link.Rules) > 0 || len(link.Targets) > 0
+}
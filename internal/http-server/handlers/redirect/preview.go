@@ -0,0 +1,117 @@
+package redirect
+
+import (
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/storage"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PreviewSuffix appended to an alias shows the preview page instead of
+// redirecting, as does the preview=1 query parameter.
+const PreviewSuffix = "+"
+
+type PreviewResponse struct {
+	resp.Response
+	Alias string `json:"alias"`
+	// URL is left out for password-protected links and for links that
+	// are not active, expired or out of clicks, which do not lead there.
+	URL       string     `json:"url,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Protected bool       `json:"protected,omitempty"`
+	// Conditional is set when visitors may be sent elsewhere than URL.
+	Conditional bool     `json:"conditional,omitempty"`
+	Active      bool     `json:"active"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Link preview</title>
+</head>
+<body>
+<h1>Where does this link go?</h1>
+{{if .Protected}}<p>This link is password protected, its destination is not shown.</p>
+{{else if not .Active}}<p>This link does not lead anywhere at the moment, its destination is not shown.</p>
+{{else}}<p>This short link leads to:</p>
+<p><code>{{.URL}}</code></p>
+{{if .Conditional}}<p>Some visitors are sent to a different destination.</p>{{end}}
+{{end}}{{with .CreatedAt}}<p>Created {{.Format "2 Jan 2006 15:04 MST"}}.</p>{{end}}
+<p><strong>Only continue if you trust the destination.</strong> Short links can hide where they lead.</p>
+{{range .Warnings}}<p role="alert">{{.}}</p>
+{{end}}{{if and .Active (not .Protected)}}<p><a href="{{.URL}}" rel="noopener noreferrer nofollow">Continue to the destination</a></p>{{end}}
+</body>
+</html>
+`))
+
+// isPreview reports whether r asks for the preview of the link and
+// returns the alias without the preview suffix.
+func isPreview(r *http.Request, alias string) (string, bool) {
+	if trimmed, ok := strings.CutSuffix(alias, PreviewSuffix); ok {
+		return trimmed, true
+	}
+
+	return alias, r.URL.Query().Get("preview") == "1"
+}
+
+func preview(link storage.Link, now time.Time) PreviewResponse {
+	res := PreviewResponse{
+		Response:    resp.OK(),
+		Alias:       link.Alias,
+		Protected:   link.PasswordHash != "",
+		Conditional: len(link.Rules) > 0 || len(link.Targets) > 0,
+		Active:      link.Active(now) && !exhausted(link),
+	}
+	if !res.Protected && res.Active {
+		res.URL = link.URL
+	}
+	if !link.CreatedAt.IsZero() {
+		res.CreatedAt = &link.CreatedAt
+	}
+
+	if !res.Active {
+		res.Warnings = append(res.Warnings, "This link is not active at the moment.")
+	}
+	if res.URL != "" {
+		if u, err := url.Parse(link.URL); err == nil && u.Scheme == "http" {
+			res.Warnings = append(res.Warnings, "The destination does not use an encrypted connection.")
+		}
+	}
+	if link.Status.Dead {
+		res.Warnings = append(res.Warnings, "The destination did not respond properly when last checked.")
+	}
+
+	return res
+}
+
+// exhausted reports whether link has used up its clicks.
+func exhausted(link storage.Link) bool {
+	return link.MaxClicks > 0 && link.ClicksUsed >= link.MaxClicks
+}
+
+// renderPreview answers with JSON for clients that ask for it, by
+// Accept header or a .json extension, and with an HTML page otherwise.
+func renderPreview(w http.ResponseWriter, r *http.Request, link storage.Link) {
+	res := preview(link, time.Now())
+
+	w.Header().Set("Cache-Control", "no-store")
+	format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string)
+	if format == "json" || render.GetAcceptedContentType(r) == render.ContentTypeJSON {
+		render.JSON(w, r, res)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	_ = previewPage.Execute(w, res)
+}
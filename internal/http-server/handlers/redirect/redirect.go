@@ -1,22 +1,119 @@
 package redirect
 
 import (
+	"RestApi/internal/lib/alias"
 	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/linkauth"
+	"RestApi/internal/lib/passthrough"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/lib/targeting"
 	"RestApi/internal/storage"
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
-//go:generate go run github.com/vektra/mockery/v2@latest --name=URLGetter
-type URLGetter interface {
-	GetURL(alias string) (string, error)
+// PasswordHeader carries the password of a protected link for clients
+// that cannot fill in the form.
+const PasswordHeader = "X-Link-Password"
+
+// maxFormSize bounds the password form body.
+const maxFormSize = 4 << 10
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=LinkResolver
+type LinkResolver interface {
+	GetLink(domain, alias string) (storage.Link, error)
+	ConsumeClick(domain, alias string) (int, error)
+	CountTargetHit(targetID int64) error
+}
+
+type options struct {
+	aliasPolicy  *alias.Policy
+	guard        *linkauth.Guard
+	placeholder  string
+	targeting    *targeting.Evaluator
+	redirectType string
+	metaDelay    time.Duration
+	shortURLs    *shorturl.Builder
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy normalizes aliases the way the save handler stores them.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithLinkAuth sets the guard protected links are checked with. A guard
+// with a random cookie secret is used otherwise.
+func WithLinkAuth(g *linkauth.Guard) Option {
+	return func(o *options) {
+		o.guard = g
+	}
+}
+
+// WithPlaceholder redirects visitors of links that are not active yet to
+// url. They get a 404 otherwise.
+func WithPlaceholder(url string) Option {
+	return func(o *options) {
+		o.placeholder = url
+	}
+}
+
+// WithTargeting sets the evaluator for links with conditional rules.
+// Without it country rules never match and hours are taken in UTC.
+func WithTargeting(e *targeting.Evaluator) Option {
+	return func(o *options) {
+		o.targeting = e
+	}
+}
+
+// WithRedirectType sets the redirect type of links stored without one.
+// Unknown types are ignored and 302 is used.
+func WithRedirectType(t string) Option {
+	return func(o *options) {
+		if ValidType(t) {
+			o.redirectType = t
+		}
+	}
+}
+
+// WithMetaDelay sets how long meta redirect pages are shown.
+func WithMetaDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.metaDelay = d
+	}
+}
+
+// WithShortURL sets the custom domains links are resolved on. Requests
+// to any other host use the default domain.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
 }
 
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+func New(log *slog.Logger, links LinkResolver, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), redirectType: TypeFound, shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.guard == nil {
+		o.guard = linkauth.New(linkauth.Config{})
+	}
+	if o.targeting == nil {
+		o.targeting = targeting.New(targeting.Config{})
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 
@@ -25,7 +122,8 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		alias := chi.URLParam(r, "alias")
+		alias, showPreview := isPreview(r, chi.URLParam(r, "alias"))
+		alias = o.aliasPolicy.Normalize(alias)
 		if alias == "" {
 			log.Info("alias is empty")
 			render.JSON(w, r, resp.Error("invalid request"))
@@ -33,9 +131,10 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			return
 		}
 
-		resURL, err := urlGetter.GetURL(alias)
+		domain := o.shortURLs.Domain(r)
+		link, err := links.GetLink(domain, alias)
 		if errors.Is(err, storage.ErrURLNotFound) {
-			log.Info("url not found", slog.String("alias", alias))
+			log.Info("url not found", slog.String("domain", domain), slog.String("alias", alias))
 			render.JSON(w, r, resp.Error("url not found"))
 
 			return
@@ -46,10 +145,187 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 
 			return
 		}
+		if !link.DeletedAt.IsZero() {
+			log.Info("link deleted", slog.String("alias", alias))
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, resp.Error("link deleted"))
+
+			return
+		}
+
+		if showPreview {
+			log.Info("preview shown", slog.String("alias", alias))
+			renderPreview(w, r, link)
+
+			return
+		}
 
-		log.Info("got url", slog.String("url", resURL))
+		rest := trailingPath(r)
+		if rest != "" && !link.PassPath {
+			log.Info("link does not take a path", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("url not found"))
+
+			return
+		}
+
+		now := time.Now()
+		switch {
+		case !link.NotAfter.IsZero() && !now.Before(link.NotAfter):
+			log.Info("link expired", slog.String("alias", alias))
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, resp.Error("link expired"))
+
+			return
+		case !link.Active(now):
+			log.Info("link not active yet", slog.String("alias", alias))
+			if o.placeholder != "" {
+				http.Redirect(w, r, o.placeholder, http.StatusFound)
+
+				return
+			}
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error("url not found"))
+
+			return
+		}
+
+		if link.PasswordHash != "" && !o.guard.Authorized(r, alias, link.PasswordHash) {
+			if !authorize(w, r, log, o.guard, link) {
+				return
+			}
+		}
+
+		if link.MaxClicks > 0 {
+			left, err := links.ConsumeClick(domain, alias)
+			if errors.Is(err, storage.ErrLinkExhausted) {
+				log.Info("link exhausted", slog.String("alias", alias))
+				render.Status(r, http.StatusGone)
+				render.JSON(w, r, resp.Error("link expired"))
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to consume click", "error", err.Error())
+				render.JSON(w, r, resp.Error("failed to get url"))
+
+				return
+			}
+			log.Info("click consumed", slog.String("alias", alias), slog.Int("left", left))
+		}
+
+		if len(link.Rules) > 0 || len(link.Targets) > 0 {
+			// The target depends on who asks, shared caches must not
+			// hand it to someone else.
+			w.Header().Set("Vary", "User-Agent, Accept-Language, Cookie")
+			w.Header().Set("Cache-Control", "private")
+		}
+
+		target, matched := o.targeting.Target(link, r)
+		if !matched {
+			target = pickTarget(w, r, log, links, link)
+		}
+
+		target, err = passThrough(r, link, target, rest)
+		if err != nil {
+			log.Info("failed to pass request through", "error", err.Error())
+			render.JSON(w, r, resp.Error("invalid request"))
+
+			return
+		}
+
+		log.Info("got url", slog.String("url", target))
 
 		//redirect to found url
-		http.Redirect(w, r, resURL, http.StatusFound)
+		o.send(w, r, link, target)
+	}
+}
+
+// trailingPath returns the escaped path following the alias segment.
+// It is read from the request URL rather than the route, as the router's
+// URLFormat middleware strips extensions from the routed path.
+func trailingPath(r *http.Request) string {
+	_, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+
+	return rest
+}
+
+// passThrough applies the link's query and path passthrough to target.
+func passThrough(r *http.Request, link storage.Link, target, rest string) (string, error) {
+	target, err := passthrough.Path(target, rest)
+	if err != nil {
+		return "", err
+	}
+
+	if link.PassQuery == "" {
+		return target, nil
 	}
+
+	return passthrough.Query(target, r.URL.Query(), link.PassQuery)
+}
+
+// pickTarget chooses a split variant of link and records the hit. It
+// falls back to the link's URL when there is nothing to split.
+func pickTarget(w http.ResponseWriter, r *http.Request, log *slog.Logger, links LinkResolver, link storage.Link) string {
+	if len(link.Targets) == 0 {
+		return link.URL
+	}
+
+	pick := targeting.Pick
+	if link.StickyTargets {
+		pick = func(targets []storage.Target) (storage.Target, bool) {
+			return targeting.PickSticky(targets, r, link.Domain)
+		}
+	}
+
+	t, ok := pick(link.Targets)
+	if !ok {
+		return link.URL
+	}
+	if link.StickyTargets {
+		http.SetCookie(w, targeting.VariantCookie(r, link.Domain, link.Alias, t.Variant))
+	}
+
+	if err := links.CountTargetHit(t.ID); err != nil {
+		log.Error("failed to count target hit", "error", err.Error())
+	}
+	log.Info("split target picked", slog.String("alias", link.Alias), slog.String("variant", t.Variant))
+
+	return t.URL
+}
+
+// authorize verifies the password sent with r and sets an access cookie.
+// On failure it answers with the password form and reports false.
+func authorize(w http.ResponseWriter, r *http.Request, log *slog.Logger, guard *linkauth.Guard, link storage.Link) bool {
+	password := r.Header.Get(PasswordHeader)
+	// The query string is not read, it ends up in access logs and
+	// browser history.
+	if password == "" && r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+		password = r.PostFormValue("password")
+	}
+	if password == "" {
+		renderPasswordForm(w, http.StatusUnauthorized, "")
+
+		return false
+	}
+
+	err := guard.Verify(link.Domain, link.Alias, link.PasswordHash, password)
+	switch {
+	case errors.Is(err, linkauth.ErrTooManyAttempts):
+		log.Warn("password attempts exceeded", slog.String("alias", link.Alias))
+		retry := int(math.Ceil(guard.RetryAfter(link.Domain, link.Alias).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		renderPasswordForm(w, http.StatusTooManyRequests, "Too many attempts, try again later.")
+
+		return false
+	case err != nil:
+		log.Info("wrong link password", slog.String("alias", link.Alias))
+		renderPasswordForm(w, http.StatusUnauthorized, "Wrong password.")
+
+		return false
+	}
+
+	http.SetCookie(w, guard.Cookie(r, link.Alias, link.PasswordHash))
+
+	return true
 }
@@ -2,15 +2,24 @@ package redirect_test
 
 import (
 	"RestApi/internal/http-server/handlers/redirect"
-	"RestApi/internal/http-server/handlers/url/get/mocks"
+	"RestApi/internal/http-server/handlers/redirect/mocks"
 	"RestApi/internal/lib/api"
+	"RestApi/internal/lib/linkauth"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/storage"
+	"encoding/json"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"io"
 	"log/slog"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -30,16 +39,16 @@ func TestSaveHandler(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			urlGetterMock := mocks.NewURLGetter(t)
+			linksMock := mocks.NewLinkResolver(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlGetterMock.On("GetURL", tc.alias).
-					Return(tc.url, tc.mockError).Once()
+				linksMock.On("GetLink", "", tc.alias).
+					Return(storage.Link{Alias: tc.alias, URL: tc.url}, tc.mockError).Once()
 			}
 
 			r := chi.NewRouter()
 			r.Get("/{alias}", redirect.New(slog.New(
-				slog.NewTextHandler(io.Discard, nil)), urlGetterMock))
+				slog.NewTextHandler(io.Discard, nil)), linksMock))
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -51,3 +60,527 @@ func TestSaveHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestRedirectHandler_Domains(t *testing.T) {
+	shortURLs, err := shorturl.New("https://sho.rt", []string{"brand.example"})
+	require.NoError(t, err)
+
+	linksMock := mocks.NewLinkResolver(t)
+	linksMock.On("GetLink", "brand.example", "sale").
+		Return(storage.Link{Domain: "brand.example", Alias: "sale", URL: "https://brand.example/sale"}, nil).Once()
+	linksMock.On("GetLink", "", "sale").
+		Return(storage.Link{Alias: "sale", URL: "https://example.com/sale"}, nil).Twice()
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linksMock,
+		redirect.WithShortURL(shortURLs)))
+
+	cases := []struct {
+		host string
+		want string
+	}{
+		{host: "Brand.example:443", want: "https://brand.example/sale"},
+		{host: "sho.rt", want: "https://example.com/sale"},
+		// Unknown hosts fall back to the default domain.
+		{host: "other.example", want: "https://example.com/sale"},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/sale", nil)
+		req.Host = tc.host
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusFound, rr.Code, tc.host)
+		require.Equal(t, tc.want, rr.Header().Get("Location"), tc.host)
+	}
+}
+
+func TestRedirectHandler_Password(t *testing.T) {
+	const target = "https://example.com/secret.pdf"
+
+	hash, err := linkauth.Hash("s3cret")
+	require.NoError(t, err)
+
+	linksMock := mocks.NewLinkResolver(t)
+	linksMock.On("GetLink", "", "doc").
+		Return(storage.Link{Alias: "doc", URL: target, PasswordHash: hash}, nil)
+
+	handler := redirect.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linksMock,
+		redirect.WithLinkAuth(linkauth.New(linkauth.Config{MaxAttempts: 2})))
+	r := chi.NewRouter()
+	r.Get("/{alias}", handler)
+	r.Post("/{alias}", handler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	do := func(req *http.Request) *http.Response {
+		t.Helper()
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		return res
+	}
+
+	// No password: the form is served.
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/doc", nil)
+	res := do(req)
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Contains(t, res.Header.Get("Content-Type"), "text/html")
+
+	// Form submission with the right password sets an access cookie.
+	req, _ = http.NewRequest(http.MethodPost, ts.URL+"/doc",
+		strings.NewReader(url.Values{"password": {"s3cret"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res = do(req)
+	require.Equal(t, http.StatusFound, res.StatusCode)
+	require.Equal(t, target, res.Header.Get("Location"))
+	require.Len(t, res.Cookies(), 1)
+	cookie := res.Cookies()[0]
+	require.Equal(t, "/doc", cookie.Path)
+
+	// The cookie lets repeat visits through without a password.
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/doc", nil)
+	req.AddCookie(cookie)
+	res = do(req)
+	require.Equal(t, http.StatusFound, res.StatusCode)
+
+	// The header works too.
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/doc", nil)
+	req.Header.Set(redirect.PasswordHeader, "s3cret")
+	require.Equal(t, http.StatusFound, do(req).StatusCode)
+
+	// The query string does not, it would leak into access logs.
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/doc?password=s3cret", nil)
+	require.Equal(t, http.StatusUnauthorized, do(req).StatusCode)
+
+	// Wrong passwords lock the alias.
+	for range 2 {
+		req, _ = http.NewRequest(http.MethodGet, ts.URL+"/doc", nil)
+		req.Header.Set(redirect.PasswordHeader, "guess")
+		require.Equal(t, http.StatusUnauthorized, do(req).StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/doc", nil)
+	req.Header.Set(redirect.PasswordHeader, "s3cret")
+	res = do(req)
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	require.NotEmpty(t, res.Header.Get("Retry-After"))
+}
+
+func TestRedirectHandler_MaxClicks(t *testing.T) {
+	linksMock := mocks.NewLinkResolver(t)
+	linksMock.On("GetLink", "", "invite").
+		Return(storage.Link{Alias: "invite", URL: "https://example.com/join", MaxClicks: 1}, nil)
+	linksMock.On("ConsumeClick", "", "invite").Return(0, nil).Once()
+	linksMock.On("ConsumeClick", "", "invite").Return(0, storage.ErrLinkExhausted).Once()
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linksMock))
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	redirectedToURL, err := api.GetRedirect(ts.URL + "/invite")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/join", redirectedToURL)
+
+	_, err = api.GetRedirect(ts.URL + "/invite")
+	require.ErrorIs(t, err, api.ErrInvalidStatusCode)
+	require.ErrorContains(t, err, "410")
+}
+
+func TestRedirectHandler_ActivationWindow(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name        string
+		link        storage.Link
+		placeholder string
+		status      int
+		location    string
+	}{
+		{
+			name:     "active",
+			link:     storage.Link{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour)},
+			status:   http.StatusFound,
+			location: "https://example.com/launch",
+		},
+		{
+			name:   "not active yet",
+			link:   storage.Link{NotBefore: now.Add(time.Hour)},
+			status: http.StatusNotFound,
+		},
+		{
+			name:        "not active yet with placeholder",
+			link:        storage.Link{NotBefore: now.Add(time.Hour)},
+			placeholder: "https://example.com/soon",
+			status:      http.StatusFound,
+			location:    "https://example.com/soon",
+		},
+		{
+			name:   "expired",
+			link:   storage.Link{NotAfter: now.Add(-time.Minute)},
+			status: http.StatusGone,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			link := tc.link
+			link.Alias, link.URL = "launch", "https://example.com/launch"
+
+			linksMock := mocks.NewLinkResolver(t)
+			linksMock.On("GetLink", "", "launch").Return(link, nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linksMock,
+				redirect.WithPlaceholder(tc.placeholder)))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/launch", nil))
+
+			require.Equal(t, tc.status, rr.Code)
+			require.Equal(t, tc.location, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectHandler_Deleted(t *testing.T) {
+	linksMock := mocks.NewLinkResolver(t)
+	linksMock.On("GetLink", "", "launch").
+		Return(storage.Link{Alias: "launch", URL: "https://example.com/launch", DeletedAt: time.Now()}, nil).
+		Twice()
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linksMock))
+
+	for _, path := range []string{"/launch", "/launch+"} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		require.Equal(t, http.StatusGone, rr.Code, path)
+		require.Empty(t, rr.Header().Get("Location"))
+		require.Contains(t, rr.Body.String(), "link deleted")
+	}
+}
+
+func TestRedirectHandler_Rules(t *testing.T) {
+	linksMock := mocks.NewLinkResolver(t)
+	linksMock.On("GetLink", "", "app").Return(storage.Link{
+		Alias: "app",
+		URL:   "https://example.com/app",
+		Rules: []storage.Rule{{Device: "ios", URL: "https://apps.apple.com/app/id1"}},
+	}, nil)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linksMock))
+
+	req := httptest.NewRequest(http.MethodGet, "/app", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, "https://apps.apple.com/app/id1", rr.Header().Get("Location"))
+	require.Contains(t, rr.Header().Get("Vary"), "User-Agent")
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/app", nil))
+	require.Equal(t, "https://example.com/app", rr.Header().Get("Location"))
+}
+
+func TestRedirectHandler_SplitTargets(t *testing.T) {
+	link := storage.Link{
+		Alias: "promo",
+		URL:   "https://example.com",
+		Targets: []storage.Target{
+			{ID: 1, Variant: "a", URL: "https://example.com/a", Weight: 1},
+			{ID: 2, Variant: "b", URL: "https://example.com/b", Weight: 0},
+		},
+		StickyTargets: true,
+	}
+
+	linksMock := mocks.NewLinkResolver(t)
+	linksMock.On("GetLink", "", "promo").Return(link, nil)
+	linksMock.On("CountTargetHit", int64(1)).Return(nil).Once()
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linksMock))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/promo", nil))
+
+	require.Equal(t, "https://example.com/a", rr.Header().Get("Location"))
+	res := rr.Result()
+	require.Len(t, res.Cookies(), 1)
+	require.Equal(t, "a@", res.Cookies()[0].Value)
+}
+
+func TestRedirectHandler_RedirectTypes(t *testing.T) {
+	cases := []struct {
+		name         string
+		linkType     string
+		defaultType  string
+		maxClicks    int
+		status       int
+		body         string
+		cacheControl string
+	}{
+		{name: "default", status: http.StatusFound},
+		{name: "server default", defaultType: "308", status: http.StatusPermanentRedirect},
+		{name: "unknown server default", defaultType: "999", status: http.StatusFound},
+		{name: "301", linkType: "301", defaultType: "307", status: http.StatusMovedPermanently},
+		{name: "307", linkType: "307", status: http.StatusTemporaryRedirect},
+		{name: "permanent but limited", linkType: "301", maxClicks: 5, status: http.StatusMovedPermanently, cacheControl: "no-store"},
+		{name: "meta", linkType: "meta", status: http.StatusOK, body: `http-equiv="refresh" content="0;url=https://example.com/?a=1&amp;b=2"`},
+		{name: "frame", linkType: "frame", status: http.StatusOK, body: `<iframe src="https://example.com/?a=1&amp;b=2"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			linksMock := mocks.NewLinkResolver(t)
+			linksMock.On("GetLink", "", "go").Return(storage.Link{
+				Alias:        "go",
+				URL:          "https://example.com/?a=1&b=2",
+				RedirectType: tc.linkType,
+				MaxClicks:    tc.maxClicks,
+			}, nil).Once()
+			if tc.maxClicks > 0 {
+				linksMock.On("ConsumeClick", "", "go").Return(1, nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linksMock,
+				redirect.WithRedirectType(tc.defaultType)))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/go", nil))
+
+			require.Equal(t, tc.status, rr.Code)
+			require.Equal(t, tc.cacheControl, rr.Header().Get("Cache-Control"))
+			if tc.body != "" {
+				require.Contains(t, rr.Body.String(), tc.body)
+			} else {
+				require.Equal(t, "https://example.com/?a=1&b=2", rr.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestRedirectHandler_Passthrough(t *testing.T) {
+	cases := []struct {
+		name      string
+		link      storage.Link
+		path      string
+		password  string
+		location  string
+		respError string
+	}{
+		{
+			name:     "query dropped by default",
+			link:     storage.Link{URL: "https://example.com/p?lang=en"},
+			path:     "/docs?lang=de",
+			location: "https://example.com/p?lang=en",
+		},
+		{
+			name:     "query replaced",
+			link:     storage.Link{URL: "https://example.com/p?lang=en", PassQuery: "replace"},
+			path:     "/docs?lang=de&ref=x",
+			location: "https://example.com/p?lang=de&ref=x",
+		},
+		{
+			// Passwords never travel in the query, so a password
+			// parameter belongs to the target like any other.
+			name:     "protected link forwards the whole query",
+			link:     storage.Link{URL: "https://example.com/p", PassQuery: "keep", PasswordHash: mustHash(t, "pw")},
+			path:     "/docs?password=reset&ref=x",
+			password: "pw",
+			location: "https://example.com/p?password=reset&ref=x",
+		},
+		{
+			name:     "path",
+			link:     storage.Link{URL: "https://docs.example.com/v2", PassPath: true},
+			path:     "/docs/guide/intro.html",
+			location: "https://docs.example.com/v2/guide/intro.html",
+		},
+		{
+			name:     "escaped path and query",
+			link:     storage.Link{URL: "https://docs.example.com/v2", PassPath: true, PassQuery: "keep"},
+			path:     "/docs/a%20b/c%2Fd?q=1",
+			location: "https://docs.example.com/v2/a%20b/c%2Fd?q=1",
+		},
+		{
+			name:      "path not allowed",
+			link:      storage.Link{URL: "https://docs.example.com/v2"},
+			path:      "/docs/guide",
+			respError: "url not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			link := tc.link
+			link.Alias = "docs"
+
+			linksMock := mocks.NewLinkResolver(t)
+			linksMock.On("GetLink", "", "docs").Return(link, nil).Once()
+
+			handler := redirect.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linksMock)
+			r := chi.NewRouter()
+			r.Use(middleware.URLFormat)
+			r.Get("/{alias}", handler)
+			r.Get("/{alias}/*", handler)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.password != "" {
+				req.Header.Set(redirect.PasswordHeader, tc.password)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			if tc.respError != "" {
+				require.Contains(t, rr.Body.String(), tc.respError)
+				return
+			}
+			require.Equal(t, http.StatusFound, rr.Code)
+			require.Equal(t, tc.location, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func mustHash(t *testing.T, password string) string {
+	t.Helper()
+
+	hash, err := linkauth.Hash(password)
+	require.NoError(t, err)
+
+	return hash
+}
+
+func TestRedirectHandler_Preview(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		path    string
+		accept  string
+		link    storage.Link
+		body    []string
+		notBody []string
+		json    *redirect.PreviewResponse
+	}{
+		{
+			name: "suffix",
+			path: "/docs+",
+			link: storage.Link{URL: "http://example.com/a?b=<c>", CreatedAt: created},
+			body: []string{
+				"http://example.com/a?b=&lt;c&gt;",
+				"Created 1 Mar 2024",
+				"does not use an encrypted connection",
+				"Continue to the destination",
+			},
+		},
+		{
+			name:    "query parameter, protected",
+			path:    "/docs?preview=1",
+			link:    storage.Link{URL: "https://example.com/secret", PasswordHash: "hash"},
+			body:    []string{"password protected"},
+			notBody: []string{"example.com/secret"},
+		},
+		{
+			name:   "json",
+			path:   "/docs+",
+			accept: "application/json",
+			link:   storage.Link{URL: "https://example.com", CreatedAt: created, Status: storage.LinkStatus{Dead: true}},
+			json: &redirect.PreviewResponse{
+				Alias:     "docs",
+				URL:       "https://example.com",
+				CreatedAt: &created,
+				Active:    true,
+				Warnings:  []string{"The destination did not respond properly when last checked."},
+			},
+		},
+		{
+			name: "json extension",
+			path: "/docs+.json",
+			link: storage.Link{URL: "https://example.com", NotBefore: time.Now().Add(time.Hour)},
+			json: &redirect.PreviewResponse{
+				Alias:    "docs",
+				Warnings: []string{"This link is not active at the moment."},
+			},
+		},
+		{
+			name:    "expired",
+			path:    "/docs+",
+			link:    storage.Link{URL: "http://example.com/old", NotAfter: time.Now().Add(-time.Hour)},
+			body:    []string{"not shown", "not active at the moment"},
+			notBody: []string{"example.com/old", "Continue to the destination", "encrypted connection"},
+		},
+		{
+			name:   "out of clicks",
+			path:   "/docs?preview=1",
+			accept: "application/json",
+			link:   storage.Link{URL: "https://example.com/invite", MaxClicks: 1, ClicksUsed: 1},
+			json: &redirect.PreviewResponse{
+				Alias:    "docs",
+				Warnings: []string{"This link is not active at the moment."},
+			},
+		},
+		{
+			name:   "clicks left",
+			path:   "/docs?preview=1",
+			accept: "application/json",
+			link:   storage.Link{URL: "https://example.com/invite", MaxClicks: 2, ClicksUsed: 1},
+			json: &redirect.PreviewResponse{
+				Alias:  "docs",
+				URL:    "https://example.com/invite",
+				Active: true,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			link := tc.link
+			link.Alias = "docs"
+
+			// Previews never consume clicks or count hits.
+			linksMock := mocks.NewLinkResolver(t)
+			linksMock.On("GetLink", "", "docs").Return(link, nil).Once()
+
+			r := chi.NewRouter()
+			r.Use(middleware.URLFormat)
+			r.Get("/{alias}", redirect.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linksMock))
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Accept", tc.accept)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.Empty(t, rr.Header().Get("Location"))
+
+			if tc.json != nil {
+				var got redirect.PreviewResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				tc.json.Response = got.Response
+				require.Equal(t, "OK", got.Status)
+				require.Equal(t, *tc.json, got)
+				return
+			}
+
+			for _, s := range tc.body {
+				require.Contains(t, rr.Body.String(), s)
+			}
+			for _, s := range tc.notBody {
+				require.NotContains(t, rr.Body.String(), s)
+			}
+		})
+	}
+}
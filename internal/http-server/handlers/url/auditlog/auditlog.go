@@ -0,0 +1,164 @@
+package auditlog
+
+import (
+	"RestApi/internal/lib/alias"
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/storage"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Entry struct {
+	ID        int64           `json:"id"`
+	At        time.Time       `json:"at"`
+	Action    string          `json:"action"`
+	Domain    string          `json:"domain,omitempty"`
+	Alias     string          `json:"alias,omitempty"`
+	Actor     string          `json:"actor,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	Old       json.RawMessage `json:"old,omitempty"`
+	New       json.RawMessage `json:"new,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Entries []Entry `json:"entries,omitempty"`
+}
+
+const (
+	defaultLimit = 50
+	maxLimit     = 1000
+)
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=AuditLister
+type AuditLister interface {
+	ListAudit(filter storage.AuditFilter, limit, offset int) ([]storage.AuditEntry, error)
+}
+
+type options struct {
+	aliasPolicy *alias.Policy
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy normalizes the alias filter the way the save handler
+// stores aliases.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// New lists audit entries newest first, filtered by the alias, actor,
+// from and to query parameters. from and to are RFC 3339 times and bound
+// the range inclusively.
+func New(log *slog.Logger, lister AuditLister, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.auditlog.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query := r.URL.Query()
+
+		limit, err := queryInt(r, "limit", defaultLimit)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			log.Info("invalid limit", slog.String("limit", query.Get("limit")))
+			render.JSON(w, r, resp.Error("invalid limit"))
+
+			return
+		}
+
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil || offset < 0 {
+			log.Info("invalid offset", slog.String("offset", query.Get("offset")))
+			render.JSON(w, r, resp.Error("invalid offset"))
+
+			return
+		}
+
+		filter := storage.AuditFilter{Actor: query.Get("actor")}
+		if a := query.Get("alias"); a != "" {
+			filter.Alias = o.aliasPolicy.Normalize(a)
+		}
+
+		if filter.From, err = queryTime(r, "from"); err != nil {
+			log.Info("invalid from", slog.String("from", query.Get("from")))
+			render.JSON(w, r, resp.Error("invalid from"))
+
+			return
+		}
+		if filter.To, err = queryTime(r, "to"); err != nil {
+			log.Info("invalid to", slog.String("to", query.Get("to")))
+			render.JSON(w, r, resp.Error("invalid to"))
+
+			return
+		}
+		if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+			log.Info("invalid time range")
+			render.JSON(w, r, resp.Error("invalid time range"))
+
+			return
+		}
+
+		entries, err := lister.ListAudit(filter, limit, offset)
+		if err != nil {
+			log.Error("failed to list audit entries", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to list audit entries"))
+
+			return
+		}
+
+		log.Info("audit entries listed", slog.Int("count", len(entries)))
+
+		res := make([]Entry, 0, len(entries))
+		for _, e := range entries {
+			res = append(res, Entry{
+				ID:        e.ID,
+				At:        e.At,
+				Action:    e.Action,
+				Domain:    e.Domain,
+				Alias:     e.Alias,
+				Actor:     e.Actor,
+				RequestID: e.RequestID,
+				Old:       e.Old,
+				New:       e.New,
+			})
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Entries:  res,
+		})
+	}
+}
+
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	raw := r.URL.Query().Get(key)
+	if raw == "" {
+		return def, nil
+	}
+
+	return strconv.Atoi(raw)
+}
+
+func queryTime(r *http.Request, key string) (time.Time, error) {
+	raw := r.URL.Query().Get(key)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, raw)
+}
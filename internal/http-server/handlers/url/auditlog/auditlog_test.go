@@ -0,0 +1,106 @@
+package auditlog_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/auditlog"
+	"RestApi/internal/http-server/handlers/url/auditlog/mocks"
+	"RestApi/internal/storage"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuditLogHandler(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		query     string
+		filter    storage.AuditFilter
+		limit     int
+		offset    int
+		entries   []storage.AuditEntry
+		respError string
+		mockError error
+	}{
+		{
+			name:  "success",
+			limit: 50,
+			entries: []storage.AuditEntry{
+				{ID: 2, Action: "delete", Alias: "promo", Actor: "alice", Old: json.RawMessage(`{"url":"https://example.com"}`)},
+				{ID: 1, Action: "create", Alias: "promo", Actor: "alice", New: json.RawMessage(`{"url":"https://example.com"}`)},
+			},
+		},
+		{
+			name:   "Filtered",
+			query:  "?alias=promo&actor=alice&from=2025-03-01T00:00:00Z&to=2025-03-31T23:59:59Z&limit=10&offset=20",
+			filter: storage.AuditFilter{Alias: "promo", Actor: "alice", From: from, To: to},
+			limit:  10,
+			offset: 20,
+		},
+		{
+			name:      "Invalid limit",
+			query:     "?limit=0",
+			respError: "invalid limit",
+		},
+		{
+			name:      "Invalid from",
+			query:     "?from=yesterday",
+			respError: "invalid from",
+		},
+		{
+			name:      "Invalid to",
+			query:     "?to=2025-03-31",
+			respError: "invalid to",
+		},
+		{
+			name:      "Inverted range",
+			query:     "?from=2025-03-31T23:59:59Z&to=2025-03-01T00:00:00Z",
+			respError: "invalid time range",
+		},
+		{
+			name:      "ListAudit Error",
+			limit:     50,
+			respError: "failed to list audit entries",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			listerMock := mocks.NewAuditLister(t)
+
+			if tc.respError == "" || tc.mockError != nil {
+				listerMock.On("ListAudit", tc.filter, tc.limit, tc.offset).
+					Return(tc.entries, tc.mockError).
+					Once()
+			}
+
+			handler := auditlog.New(slog.New(slog.NewTextHandler(io.Discard, nil)), listerMock)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/audit"+tc.query, nil))
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp auditlog.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Len(t, resp.Entries, len(tc.entries))
+			for i, e := range tc.entries {
+				require.Equal(t, e.ID, resp.Entries[i].ID)
+				require.Equal(t, e.Action, resp.Entries[i].Action)
+				require.Equal(t, e.Actor, resp.Entries[i].Actor)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package available
+
+import (
+	"RestApi/internal/lib/alias"
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/storage"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+// Reasons an alias is not available, besides the alias policy's own.
+const (
+	ReasonTaken    = "alias is taken"
+	ReasonDeleted  = "alias belongs to a deleted link"
+	ReasonReserved = "alias is held by a reservation"
+)
+
+type Response struct {
+	resp.Response
+	// Alias is the alias as it would be stored.
+	Alias     string `json:"alias,omitempty"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=AliasChecker
+type AliasChecker interface {
+	GetLink(domain, alias string) (storage.Link, error)
+	GetReservation(domain, alias string) (storage.Reservation, error)
+}
+
+type options struct {
+	aliasPolicy *alias.Policy
+	shortURLs   *shorturl.Builder
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy sets the policy aliases are checked against.
+// alias.Default() is used otherwise.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithShortURL sets the custom domains aliases may be checked on.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+// New reports whether the alias query parameter could be saved on the
+// domain parameter, the domain the request was sent to when empty. Aliases held by a
+// reservation count as available to the holder of the reservation
+// parameter's token.
+func New(log *slog.Logger, checker AliasChecker, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.available.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query := r.URL.Query()
+
+		raw := query.Get("alias")
+		if raw == "" {
+			log.Info("alias is empty")
+			render.JSON(w, r, resp.Error("invalid request"))
+
+			return
+		}
+
+		domain, ok := o.shortURLs.Resolve(r, query.Get("domain"))
+		if !ok {
+			log.Info("unknown domain", slog.String("domain", query.Get("domain")))
+			render.JSON(w, r, resp.Error("unknown domain"))
+
+			return
+		}
+
+		res := Response{Response: resp.OK(), Alias: o.aliasPolicy.Normalize(raw)}
+
+		if err := o.aliasPolicy.Check(raw); err != nil {
+			res.Reason = err.Error()
+			render.JSON(w, r, res)
+
+			return
+		}
+
+		link, err := checker.GetLink(domain, res.Alias)
+		switch {
+		case err == nil && !link.DeletedAt.IsZero():
+			res.Reason = ReasonDeleted
+		case err == nil:
+			res.Reason = ReasonTaken
+		case !errors.Is(err, storage.ErrURLNotFound):
+			log.Error("failed to get url", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to check alias"))
+
+			return
+		}
+		if res.Reason != "" {
+			render.JSON(w, r, res)
+
+			return
+		}
+
+		hold, err := checker.GetReservation(domain, res.Alias)
+		switch {
+		case err == nil && hold.Token != query.Get("reservation"):
+			res.Reason = ReasonReserved
+		case err != nil && !errors.Is(err, storage.ErrReservationNotFound):
+			log.Error("failed to get reservation", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to check alias"))
+
+			return
+		}
+
+		res.Available = res.Reason == ""
+		log.Info("alias checked", slog.String("alias", res.Alias), slog.Bool("available", res.Available))
+
+		render.JSON(w, r, res)
+	}
+}
@@ -0,0 +1,137 @@
+package available_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/available"
+	"RestApi/internal/http-server/handlers/url/available/mocks"
+	"RestApi/internal/lib/alias"
+	"RestApi/internal/storage"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAvailableHandler(t *testing.T) {
+	policy, err := alias.New(alias.Config{Reserved: []string{"admin"}})
+	require.NoError(t, err)
+
+	hold := storage.Reservation{Alias: "launch", Token: "abc123", ExpiresAt: time.Now().Add(10 * time.Minute)}
+
+	notFound := func(m *mocks.AliasChecker) {
+		m.On("GetLink", "", "launch").Return(storage.Link{}, storage.ErrURLNotFound).Once()
+	}
+	free := func(m *mocks.AliasChecker) {
+		notFound(m)
+		m.On("GetReservation", "", "launch").Return(storage.Reservation{}, storage.ErrReservationNotFound).Once()
+	}
+	held := func(m *mocks.AliasChecker) {
+		notFound(m)
+		m.On("GetReservation", "", "launch").Return(hold, nil).Once()
+	}
+
+	cases := []struct {
+		name      string
+		query     string
+		setup     func(m *mocks.AliasChecker)
+		available bool
+		reason    string
+		respError string
+	}{
+		{
+			name:      "Available",
+			query:     "?alias=launch",
+			setup:     free,
+			available: true,
+		},
+		{
+			name:   "Reserved word",
+			query:  "?alias=admin",
+			reason: alias.ErrReserved.Error(),
+		},
+		{
+			name:  "Taken",
+			query: "?alias=launch",
+			setup: func(m *mocks.AliasChecker) {
+				m.On("GetLink", "", "launch").Return(storage.Link{Alias: "launch"}, nil).Once()
+			},
+			reason: available.ReasonTaken,
+		},
+		{
+			name:  "Deleted",
+			query: "?alias=launch",
+			setup: func(m *mocks.AliasChecker) {
+				m.On("GetLink", "", "launch").Return(storage.Link{Alias: "launch", DeletedAt: time.Now()}, nil).Once()
+			},
+			reason: available.ReasonDeleted,
+		},
+		{
+			name:   "Held",
+			query:  "?alias=launch",
+			setup:  held,
+			reason: available.ReasonReserved,
+		},
+		{
+			name:      "Held by the caller",
+			query:     "?alias=launch&reservation=abc123",
+			setup:     held,
+			available: true,
+		},
+		{
+			name:      "Empty alias",
+			respError: "invalid request",
+		},
+		{
+			name:      "Unknown domain",
+			query:     "?alias=launch&domain=other.example",
+			respError: "unknown domain",
+		},
+		{
+			name:  "GetLink Error",
+			query: "?alias=launch",
+			setup: func(m *mocks.AliasChecker) {
+				m.On("GetLink", "", "launch").Return(storage.Link{}, errors.New("unexpected error")).Once()
+			},
+			respError: "failed to check alias",
+		},
+		{
+			name:  "GetReservation Error",
+			query: "?alias=launch",
+			setup: func(m *mocks.AliasChecker) {
+				notFound(m)
+				m.On("GetReservation", "", "launch").Return(storage.Reservation{}, errors.New("unexpected error")).Once()
+			},
+			respError: "failed to check alias",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			checkerMock := mocks.NewAliasChecker(t)
+			if tc.setup != nil {
+				tc.setup(checkerMock)
+			}
+
+			handler := available.New(slog.New(slog.NewTextHandler(io.Discard, nil)), checkerMock,
+				available.WithAliasPolicy(policy))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/available"+tc.query, nil))
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp available.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.available, resp.Available)
+			require.Equal(t, tc.reason, resp.Reason)
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package delete
 
 import (
+	"RestApi/internal/lib/alias"
 	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/audit"
+	"RestApi/internal/lib/shorturl"
 	"RestApi/internal/storage"
 	"errors"
 	"github.com/go-chi/chi/v5/middleware"
@@ -12,7 +15,8 @@ import (
 )
 
 type Request struct {
-	Alias string `json:"alias" validate:"required"`
+	Alias  string `json:"alias" validate:"required"`
+	Domain string `json:"domain,omitempty"`
 }
 
 type Response struct {
@@ -21,10 +25,45 @@ type Response struct {
 
 //go:generate go run github.com/vektra/mockery/v2@latest --name=DeleteURL
 type DeleteURL interface {
-	DeleteURL(alias string) error
+	DeleteURL(domain, alias string) error
 }
 
-func New(log *slog.Logger, deleteURL DeleteURL) http.HandlerFunc {
+type options struct {
+	aliasPolicy *alias.Policy
+	shortURLs   *shorturl.Builder
+	audit       *audit.Recorder
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy normalizes aliases the way the save handler stores them.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithShortURL sets the custom domains links may be managed on. Requests
+// act on the domain they name, or else on the domain they were sent to.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+// WithAudit records deleted links with their last state in the audit log.
+func WithAudit(rec *audit.Recorder) Option {
+	return func(o *options) {
+		o.audit = rec
+	}
+}
+
+func New(log *slog.Logger, deleteURL DeleteURL, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.delete-url.New"
 
@@ -37,6 +76,7 @@ func New(log *slog.Logger, deleteURL DeleteURL) http.HandlerFunc {
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
 			log.Error("failed to decode request body", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, resp.Error("failed to decode request"))
 
 			return
@@ -47,26 +87,42 @@ func New(log *slog.Logger, deleteURL DeleteURL) http.HandlerFunc {
 			var validateErr validator.ValidationErrors
 			errors.As(err, &validateErr)
 			log.Error("invalid request", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, resp.ValidationError(validateErr))
 
 			return
 		}
 
-		err = deleteURL.DeleteURL(req.Alias)
+		req.Alias = o.aliasPolicy.Normalize(req.Alias)
+
+		domain, ok := o.shortURLs.Resolve(r, req.Domain)
+		if !ok {
+			log.Info("unknown domain", slog.String("domain", req.Domain))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("unknown domain"))
+
+			return
+		}
+
+		old := o.audit.Snapshot(domain, req.Alias)
+		err = deleteURL.DeleteURL(domain, req.Alias)
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url not found", slog.String("alias", req.Alias))
+			render.Status(r, http.StatusNotFound)
 			render.JSON(w, r, resp.Error("url not found"))
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", "error", err.Error())
+			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, resp.Error("failed to delete url"))
 
 			return
 		}
 
-		log.Info("url retrieved", slog.String("alias", req.Alias))
+		log.Info("url deleted", slog.String("alias", req.Alias))
+		o.audit.Record(r, audit.ActionDelete, domain, req.Alias, old, nil)
 
 		render.JSON(w, r, Response{
 			Response: resp.OK(),
@@ -3,6 +3,7 @@ package delete_test
 import (
 	"RestApi/internal/http-server/handlers/url/delete"
 	"RestApi/internal/http-server/handlers/url/delete/mocks"
+	"RestApi/internal/lib/shorturl"
 	"RestApi/internal/storage"
 	"bytes"
 	"encoding/json"
@@ -21,28 +22,33 @@ func TestDeleteURLHandler(t *testing.T) {
 		name      string
 		alias     string
 		respError string
+		status    int
 		mockError error
 	}{
 		{
-			name:  "success",
-			alias: "test_alias",
+			name:   "success",
+			alias:  "test_alias",
+			status: http.StatusOK,
 		},
 		{
 			name:      "Empty alias",
 			alias:     "",
 			respError: "field Alias is a required field",
+			status:    http.StatusBadRequest,
 		},
 		{
 			name:      "Not found",
 			alias:     "test_bad_alias",
 			respError: "url not found",
 			mockError: storage.ErrURLNotFound,
+			status:    http.StatusNotFound,
 		},
 		{
 			name:      "DeleteURL Error",
 			alias:     "test_alias",
 			respError: "failed to delete url",
 			mockError: errors.New("failed to delete url"),
+			status:    http.StatusInternalServerError,
 		},
 	}
 
@@ -56,7 +62,7 @@ func TestDeleteURLHandler(t *testing.T) {
 
 			if tc.respError == "" || tc.mockError != nil {
 				urlDeleteMock.On(
-					"DeleteURL", tc.alias).
+					"DeleteURL", "", tc.alias).
 					Return(tc.mockError).
 					Once()
 			}
@@ -70,7 +76,7 @@ func TestDeleteURLHandler(t *testing.T) {
 
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
-			require.Equal(t, rr.Code, http.StatusOK)
+			require.Equal(t, tc.status, rr.Code)
 
 			body := rr.Body.String()
 			var resp delete.Response
@@ -79,3 +85,43 @@ func TestDeleteURLHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteURLHandler_Domain(t *testing.T) {
+	shortURLs, err := shorturl.New("https://sho.rt", []string{"brand.example"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		host      string
+		domain    string
+		want      string
+		respError string
+	}{
+		{name: "default domain", host: "sho.rt"},
+		{name: "requested domain", host: "sho.rt", domain: "Brand.example", want: "brand.example"},
+		{name: "domain of the request", host: "brand.example", want: "brand.example"},
+		{name: "unknown domain", host: "sho.rt", domain: "other.example", respError: "unknown domain"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlDeleteMock := mocks.NewDeleteURL(t)
+			if tc.respError == "" {
+				urlDeleteMock.On("DeleteURL", tc.want, "promo").Return(nil).Once()
+			}
+
+			handler := delete.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlDeleteMock,
+				delete.WithShortURL(shortURLs))
+			input := fmt.Sprintf(`{"alias": "promo", "domain": "%s"}`, tc.domain)
+			req := httptest.NewRequest(http.MethodDelete, "/url/delete-url", bytes.NewReader([]byte(input)))
+			req.Host = tc.host
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp delete.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/transfer"
+	"RestApi/internal/storage"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLLister
+type URLLister interface {
+	ListURLsAfter(afterID int64, limit int) ([]storage.Link, error)
+}
+
+func New(log *slog.Logger, lister URLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.export.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = transfer.FormatJSONL
+		}
+
+		enc, err := transfer.NewEncoder(w, format)
+		if errors.Is(err, transfer.ErrUnknownFormat) {
+			log.Info("unknown export format", slog.String("format", format))
+			render.JSON(w, r, resp.Error("unknown format"))
+
+			return
+		}
+
+		w.Header().Set("Content-Type", transfer.ContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+
+		// The status line is already sent once the first record is
+		// written, so a failure can only be logged and the body truncated.
+		count, err := transfer.Export(enc, lister)
+		if err != nil {
+			log.Error("failed to export urls", "error", err.Error(), slog.Int("exported", count))
+
+			return
+		}
+
+		log.Info("urls exported", slog.Int("count", count))
+	}
+}
@@ -0,0 +1,83 @@
+package export_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/export"
+	"RestApi/internal/http-server/handlers/url/export/mocks"
+	"RestApi/internal/storage"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExportHandler(t *testing.T) {
+	links := []storage.Link{
+		{ID: 1, Alias: "google", URL: "https://google.com"},
+		{ID: 2, Alias: "ya", URL: "https://ya.ru"},
+	}
+
+	cases := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+		respError   string
+	}{
+		{
+			name:        "JSON Lines by default",
+			contentType: "application/x-ndjson",
+			body: `{"alias":"google","url":"https://google.com"}` + "\n" +
+				`{"alias":"ya","url":"https://ya.ru"}` + "\n",
+		},
+		{
+			name:        "CSV",
+			query:       "?format=csv",
+			contentType: "text/csv",
+			body: "alias,url,domain,created_at,created_by,not_after\n" +
+				"google,https://google.com,,,,\nya,https://ya.ru,,,,\n",
+		},
+		{
+			name:      "Unknown format",
+			query:     "?format=xml",
+			respError: "unknown format",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlListerMock := mocks.NewURLLister(t)
+			if tc.respError == "" {
+				urlListerMock.On("ListURLsAfter", int64(0), 500).Return(links, nil).Once()
+			}
+
+			handler := export.New(slog.New(
+				slog.NewTextHandler(io.Discard, nil)), urlListerMock)
+			req, err := http.NewRequest(http.MethodGet, "/export"+tc.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, rr.Code, http.StatusOK)
+
+			if tc.respError != "" {
+				var resp struct {
+					Error string `json:"error"`
+				}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				require.Equal(t, tc.respError, resp.Error)
+
+				return
+			}
+
+			require.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+			require.Equal(t, tc.body, rr.Body.String())
+		})
+	}
+}
@@ -1,31 +1,81 @@
 package get
 
 import (
+	"RestApi/internal/lib/alias"
 	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/shorturl"
 	"RestApi/internal/storage"
+	"encoding/json"
 	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 	"log/slog"
 	"net/http"
+	"time"
 )
 
 type Request struct {
-	Alias string `json:"alias" validate:"required"`
+	Alias  string `json:"alias" validate:"required"`
+	Domain string `json:"domain,omitempty"`
 }
 
 type Response struct {
-	URL string `json:"url,omitempty"`
+	URL      string `json:"url,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	// Protected tells whether visitors need a password, which is never
+	// returned.
+	Protected    bool            `json:"protected,omitempty"`
+	MaxClicks    int             `json:"max_clicks,omitempty"`
+	NotBefore    *time.Time      `json:"not_before,omitempty"`
+	NotAfter     *time.Time      `json:"not_after,omitempty"`
+	RedirectType string          `json:"redirect_type,omitempty"`
+	PassQuery    string          `json:"pass_query,omitempty"`
+	PassPath     bool            `json:"pass_path,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	Description  string          `json:"description,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	Metadata     json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt    *time.Time      `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time      `json:"updated_at,omitempty"`
+	CreatedBy    string          `json:"created_by,omitempty"`
 	resp.Response
 }
 
-//go:generate go run github.com/vektra/mockery/v2@latest --name=URLGetter
-type URLGetter interface {
-	GetURL(alias string) (string, error)
+//go:generate go run github.com/vektra/mockery/v2@latest --name=LinkGetter
+type LinkGetter interface {
+	GetLink(domain, alias string) (storage.Link, error)
 }
 
-func New(log *slog.Logger, getter URLGetter) http.HandlerFunc {
+type options struct {
+	aliasPolicy *alias.Policy
+	shortURLs   *shorturl.Builder
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy normalizes aliases the way the save handler stores them.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithShortURL sets how the returned short URL is built. It is taken
+// from the request host otherwise.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+func New(log *slog.Logger, getter LinkGetter, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.get.New"
 
@@ -38,6 +88,7 @@ func New(log *slog.Logger, getter URLGetter) http.HandlerFunc {
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
 			log.Error("failed to decode request body", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, resp.Error("failed to decode request"))
 
 			return
@@ -48,20 +99,37 @@ func New(log *slog.Logger, getter URLGetter) http.HandlerFunc {
 			var validateErr validator.ValidationErrors
 			errors.As(err, &validateErr)
 			log.Error("invalid request", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, resp.ValidationError(validateErr))
 
 			return
 		}
 
-		resUrl, err := getter.GetURL(req.Alias)
+		req.Alias = o.aliasPolicy.Normalize(req.Alias)
+
+		domain, ok := o.shortURLs.Resolve(r, req.Domain)
+		if !ok {
+			log.Info("unknown domain", slog.String("domain", req.Domain))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("unknown domain"))
+
+			return
+		}
+
+		link, err := getter.GetLink(domain, req.Alias)
+		if err == nil && !link.DeletedAt.IsZero() {
+			err = storage.ErrURLNotFound
+		}
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url not found", slog.String("alias", req.Alias))
+			render.Status(r, http.StatusNotFound)
 			render.JSON(w, r, resp.Error("url not found"))
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", "error", err.Error())
+			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, resp.Error("failed to get url"))
 
 			return
@@ -69,9 +137,38 @@ func New(log *slog.Logger, getter URLGetter) http.HandlerFunc {
 
 		log.Info("url retrieved", slog.String("alias", req.Alias))
 
+		shortURL := o.shortURLs.URL(r, req.Alias)
+		if domain != "" {
+			shortURL = o.shortURLs.OnDomain(domain, req.Alias)
+		}
+
 		render.JSON(w, r, Response{
-			Response: resp.OK(),
-			URL:      resUrl,
+			Response:     resp.OK(),
+			URL:          link.URL,
+			ShortURL:     shortURL,
+			Domain:       link.Domain,
+			Protected:    link.PasswordHash != "",
+			MaxClicks:    link.MaxClicks,
+			NotBefore:    timeOrNil(link.NotBefore),
+			NotAfter:     timeOrNil(link.NotAfter),
+			RedirectType: link.RedirectType,
+			PassQuery:    link.PassQuery,
+			PassPath:     link.PassPath,
+			Title:        link.Meta.Title,
+			Description:  link.Meta.Description,
+			Tags:         link.Meta.Tags,
+			Metadata:     link.Meta.Metadata,
+			CreatedAt:    timeOrNil(link.CreatedAt),
+			UpdatedAt:    timeOrNil(link.UpdatedAt),
+			CreatedBy:    link.CreatedBy,
 		})
 	}
 }
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
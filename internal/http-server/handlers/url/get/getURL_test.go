@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestGetURLHandler(t *testing.T) {
@@ -22,49 +23,62 @@ func TestGetURLHandler(t *testing.T) {
 		alias     string
 		url       string
 		respError string
+		status    int
 		mockError error
 	}{
 		{
-			name:  "success",
-			alias: "test_alias",
-			url:   "https://google.com",
+			name:   "success",
+			alias:  "test_alias",
+			url:    "https://google.com",
+			status: http.StatusOK,
 		},
 		{
 			name:      "Empty alias",
 			alias:     "",
 			respError: "field Alias is a required field",
+			status:    http.StatusBadRequest,
 		},
 		{
 			name:      "Not found",
 			alias:     "test_bad_alias",
 			respError: "url not found",
 			mockError: storage.ErrURLNotFound,
+			status:    http.StatusNotFound,
 		},
 		{
 			name:      "GetURL Error",
 			alias:     "test_alias",
 			respError: "failed to get url",
 			mockError: errors.New("unexpected error"),
+			status:    http.StatusInternalServerError,
 		},
 	}
 
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
 	for _, tc := range cases {
 		tc := tc
 
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			urlGetMock := mocks.NewURLGetter(t)
+			linkGetMock := mocks.NewLinkGetter(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlGetMock.On(
-					"GetURL", tc.alias).
-					Return(tc.url, tc.mockError).
+				linkGetMock.On(
+					"GetLink", "", tc.alias).
+					Return(storage.Link{Alias: tc.alias, URL: tc.url, CreatedAt: created, CreatedBy: "alice",
+						PasswordHash: "hash", MaxClicks: 3, NotAfter: created.Add(time.Hour), RedirectType: "307",
+						Meta: storage.Meta{
+							Title:    "Spring sale",
+							Tags:     []string{"sale"},
+							Metadata: json.RawMessage(`{"campaign":"spring"}`),
+						}}, tc.mockError).
 					Once()
 			}
 
 			handler := get.New(slog.New(
-				slog.NewTextHandler(io.Discard, nil)), urlGetMock)
+				slog.NewTextHandler(io.Discard, nil)), linkGetMock)
 			input := fmt.Sprintf(`{"alias": "%s"}`, tc.alias)
 			req, err := http.NewRequest(
 				http.MethodPost, "/get-url", bytes.NewReader([]byte(input)))
@@ -72,12 +86,43 @@ func TestGetURLHandler(t *testing.T) {
 
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
-			require.Equal(t, rr.Code, http.StatusOK)
+			require.Equal(t, tc.status, rr.Code)
 
 			body := rr.Body.String()
 			var resp get.Response
 			require.NoError(t, json.Unmarshal([]byte(body), &resp))
 			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, tc.url, resp.URL)
+				require.Equal(t, "http://"+req.Host+"/"+tc.alias, resp.ShortURL)
+				require.Equal(t, "Spring sale", resp.Title)
+				require.Equal(t, []string{"sale"}, resp.Tags)
+				require.JSONEq(t, `{"campaign":"spring"}`, string(resp.Metadata))
+				require.True(t, created.Equal(*resp.CreatedAt))
+				require.Nil(t, resp.UpdatedAt)
+				require.Equal(t, "alice", resp.CreatedBy)
+				require.True(t, resp.Protected)
+				require.Equal(t, 3, resp.MaxClicks)
+				require.Nil(t, resp.NotBefore)
+				require.True(t, created.Add(time.Hour).Equal(*resp.NotAfter))
+				require.Equal(t, "307", resp.RedirectType)
+			}
 		})
 	}
 }
+
+func TestGetURLHandler_Deleted(t *testing.T) {
+	linkGetMock := mocks.NewLinkGetter(t)
+	linkGetMock.On("GetLink", "", "gone").
+		Return(storage.Link{Alias: "gone", URL: "https://example.com", DeletedAt: time.Now()}, nil).
+		Once()
+
+	handler := get.New(slog.New(slog.NewTextHandler(io.Discard, nil)), linkGetMock)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/get-url", bytes.NewReader([]byte(`{"alias": "gone"}`))))
+
+	var resp get.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, "url not found", resp.Error)
+	require.Empty(t, resp.URL)
+}
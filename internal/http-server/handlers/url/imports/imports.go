@@ -0,0 +1,105 @@
+package imports
+
+import (
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/audit"
+	"RestApi/internal/lib/linkcheck"
+	"RestApi/internal/lib/transfer"
+	"RestApi/internal/storage"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	resp.Response
+	transfer.Result
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLImporter
+type URLImporter interface {
+	SaveLink(link storage.Link) (int64, error)
+	UpdateURL(domain, alias string, urlToSave, originalURL string) error
+}
+
+type options struct {
+	check *linkcheck.Checker
+	audit *audit.Recorder
+}
+
+type Option func(o *options)
+
+// WithLinkCheck sets the checks imported links go through. Only the
+// default alias policy and the default domain apply otherwise.
+func WithLinkCheck(c *linkcheck.Checker) Option {
+	return func(o *options) {
+		o.check = c
+	}
+}
+
+// WithAudit records every import and its result in the audit log. The
+// imported links are not recorded one by one.
+func WithAudit(rec *audit.Recorder) Option {
+	return func(o *options) {
+		o.audit = rec
+	}
+}
+
+func New(log *slog.Logger, importer URLImporter, opts ...Option) http.HandlerFunc {
+	o := options{check: linkcheck.New(linkcheck.Config{})}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.imports.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		policy, err := transfer.ParseConflictPolicy(r.URL.Query().Get("conflict"))
+		if err != nil {
+			log.Info("unknown conflict policy", "error", err.Error())
+			render.JSON(w, r, resp.Error("unknown conflict policy"))
+
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = transfer.FormatJSONL
+		}
+
+		dec, err := transfer.NewDecoder(r.Body, format)
+		if err != nil {
+			log.Info("unknown import format", slog.String("format", format))
+			render.JSON(w, r, resp.Error("unknown format"))
+
+			return
+		}
+
+		res, err := transfer.Import(r.Context(), dec, importer, policy, o.check)
+		// Records stored before a failure are kept, so failed imports are
+		// recorded too.
+		o.audit.Record(r, audit.ActionImport, "", "", nil, res)
+		if err != nil {
+			log.Error("failed to import urls", "error", err.Error(), slog.Any("result", res))
+			render.JSON(w, r, Response{
+				Response: resp.Error("failed to import urls: " + err.Error()),
+				Result:   res,
+			})
+
+			return
+		}
+
+		log.Info("urls imported", slog.Any("result", res))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Result:   res,
+		})
+	}
+}
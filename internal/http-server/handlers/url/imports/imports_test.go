@@ -0,0 +1,105 @@
+package imports_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/imports"
+	"RestApi/internal/http-server/handlers/url/imports/mocks"
+	"RestApi/internal/lib/transfer"
+	"RestApi/internal/storage"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImportHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		body      string
+		setup     func(m *mocks.URLImporter)
+		result    transfer.Result
+		respError string
+	}{
+		{
+			name: "JSON Lines",
+			body: `{"alias":"google","url":"https://google.com"}`,
+			setup: func(m *mocks.URLImporter) {
+				m.On("SaveLink", storage.Link{Alias: "google", URL: "https://google.com"}).Return(int64(1), nil).Once()
+			},
+			result: transfer.Result{Created: 1},
+		},
+		{
+			name:  "CSV with overwrite",
+			query: "?format=csv&conflict=overwrite",
+			body:  "alias,url\ngoogle,https://google.com\n",
+			setup: func(m *mocks.URLImporter) {
+				m.On("SaveLink", storage.Link{Alias: "google", URL: "https://google.com"}).Return(int64(0), storage.ErrURLExists).Once()
+				m.On("UpdateURL", "", "google", "https://google.com", "").Return(nil).Once()
+			},
+			result: transfer.Result{Overwritten: 1},
+		},
+		{
+			name: "Conflict fails by default",
+			body: `{"alias":"google","url":"https://google.com"}`,
+			setup: func(m *mocks.URLImporter) {
+				m.On("SaveLink", storage.Link{Alias: "google", URL: "https://google.com"}).Return(int64(0), storage.ErrURLExists).Once()
+			},
+			respError: `failed to import urls: record 1: alias already exists: "google"`,
+		},
+		{
+			name: "Invalid records are reported",
+			body: `{"alias":"bad alias","url":"https://google.com"}
+{"alias":"google","url":"https://google.com"}
+{"alias":"ya","url":"ftp:/nowhere"}`,
+			setup: func(m *mocks.URLImporter) {
+				m.On("SaveLink", storage.Link{Alias: "google", URL: "https://google.com"}).Return(int64(1), nil).Once()
+			},
+			result: transfer.Result{Created: 1, Failed: 2, Errors: []transfer.RowError{
+				{Record: 1, Alias: "bad alias", Error: "alias contains characters that are not allowed"},
+				{Record: 3, Alias: "ya", Error: "url is not a valid URL"},
+			}},
+		},
+		{
+			name:      "Unknown policy",
+			query:     "?conflict=merge",
+			respError: "unknown conflict policy",
+		},
+		{
+			name:      "Unknown format",
+			query:     "?format=xml",
+			respError: "unknown format",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlImporterMock := mocks.NewURLImporter(t)
+			if tc.setup != nil {
+				tc.setup(urlImporterMock)
+			}
+
+			handler := imports.New(slog.New(
+				slog.NewTextHandler(io.Discard, nil)), urlImporterMock)
+			req, err := http.NewRequest(
+				http.MethodPost, "/import"+tc.query, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, rr.Code, http.StatusOK)
+
+			var resp imports.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.result, resp.Result)
+		})
+	}
+}
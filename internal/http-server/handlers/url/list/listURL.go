@@ -0,0 +1,142 @@
+package list
+
+import (
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/linkmeta"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/storage"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type Link struct {
+	Domain   string   `json:"domain,omitempty"`
+	Alias    string   `json:"alias"`
+	URL      string   `json:"url"`
+	ShortURL string   `json:"short_url"`
+	Title    string   `json:"title,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Links []Link `json:"links,omitempty"`
+}
+
+const (
+	defaultLimit = 50
+	maxLimit     = 1000
+)
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLLister
+type URLLister interface {
+	ListURLs(limit, offset int) ([]storage.Link, error)
+	ListURLsByTag(tag string, limit, offset int) ([]storage.Link, error)
+}
+
+type options struct {
+	shortURLs *shorturl.Builder
+}
+
+type Option func(o *options)
+
+// WithShortURL sets how the listed short URLs are built. They are taken
+// from the request host otherwise.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+// New lists links page by page, only those tagged with the tag query
+// parameter when it is set.
+func New(log *slog.Logger, lister URLLister, opts ...Option) http.HandlerFunc {
+	o := options{shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, err := queryInt(r, "limit", defaultLimit)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			log.Info("invalid limit", slog.String("limit", r.URL.Query().Get("limit")))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid limit"))
+
+			return
+		}
+
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil || offset < 0 {
+			log.Info("invalid offset", slog.String("offset", r.URL.Query().Get("offset")))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid offset"))
+
+			return
+		}
+
+		var links []storage.Link
+		if tag := r.URL.Query().Get("tag"); tag != "" {
+			tag, err = linkmeta.NormalizeTag(tag)
+			if err != nil {
+				log.Info("invalid tag", slog.String("tag", r.URL.Query().Get("tag")))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("invalid tag"))
+
+				return
+			}
+			links, err = lister.ListURLsByTag(tag, limit, offset)
+		} else {
+			links, err = lister.ListURLs(limit, offset)
+		}
+		if err != nil {
+			log.Error("failed to list urls", "error", err.Error())
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to list urls"))
+
+			return
+		}
+
+		log.Info("urls listed", slog.Int("count", len(links)))
+
+		res := make([]Link, 0, len(links))
+		for _, link := range links {
+			shortURL := o.shortURLs.URL(r, link.Alias)
+			if link.Domain != "" {
+				shortURL = o.shortURLs.OnDomain(link.Domain, link.Alias)
+			}
+			res = append(res, Link{
+				Domain:   link.Domain,
+				Alias:    link.Alias,
+				URL:      link.URL,
+				ShortURL: shortURL,
+				Title:    link.Meta.Title,
+				Tags:     link.Meta.Tags,
+			})
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Links:    res,
+		})
+	}
+}
+
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	raw := r.URL.Query().Get(key)
+	if raw == "" {
+		return def, nil
+	}
+
+	return strconv.Atoi(raw)
+}
@@ -0,0 +1,131 @@
+package list_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/list"
+	"RestApi/internal/http-server/handlers/url/list/mocks"
+	"RestApi/internal/storage"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListURLHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		limit     int
+		offset    int
+		links     []storage.Link
+		respError string
+		status    int
+		mockError error
+	}{
+		{
+			name:  "success",
+			limit: 50,
+			links: []storage.Link{
+				{ID: 1, Alias: "first", URL: "https://google.com"},
+				{ID: 2, Alias: "second", URL: "https://ya.ru"},
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Custom page",
+			query:  "?limit=10&offset=20",
+			limit:  10,
+			offset: 20,
+			status: http.StatusOK,
+		},
+		{
+			name:      "Invalid limit",
+			query:     "?limit=abc",
+			respError: "invalid limit",
+			status:    http.StatusBadRequest,
+		},
+		{
+			name:      "Limit too large",
+			query:     "?limit=100000",
+			respError: "invalid limit",
+			status:    http.StatusBadRequest,
+		},
+		{
+			name:      "Negative offset",
+			query:     "?offset=-1",
+			respError: "invalid offset",
+			status:    http.StatusBadRequest,
+		},
+		{
+			name:      "ListURLs Error",
+			limit:     50,
+			respError: "failed to list urls",
+			mockError: errors.New("unexpected error"),
+			status:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlListerMock := mocks.NewURLLister(t)
+
+			if tc.respError == "" || tc.mockError != nil {
+				urlListerMock.On("ListURLs", tc.limit, tc.offset).
+					Return(tc.links, tc.mockError).
+					Once()
+			}
+
+			handler := list.New(slog.New(
+				slog.NewTextHandler(io.Discard, nil)), urlListerMock)
+			req, err := http.NewRequest(http.MethodGet, "/list"+tc.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+
+			var resp list.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Len(t, resp.Links, len(tc.links))
+			for i, link := range tc.links {
+				require.Equal(t, link.Alias, resp.Links[i].Alias)
+				require.Equal(t, link.URL, resp.Links[i].URL)
+				require.Equal(t, "http://"+req.Host+"/"+link.Alias, resp.Links[i].ShortURL)
+			}
+		})
+	}
+}
+
+func TestListURLHandler_Tag(t *testing.T) {
+	urlListerMock := mocks.NewURLLister(t)
+	urlListerMock.On("ListURLsByTag", "spring-sale", 50, 0).
+		Return([]storage.Link{
+			{ID: 1, Alias: "poster", URL: "https://example.com", Meta: storage.Meta{Title: "Poster", Tags: []string{"spring-sale"}}},
+		}, nil).
+		Once()
+
+	handler := list.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlListerMock)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/list?tag=Spring-Sale", nil))
+
+	var resp list.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Empty(t, resp.Error)
+	require.Len(t, resp.Links, 1)
+	require.Equal(t, "Poster", resp.Links[0].Title)
+	require.Equal(t, []string{"spring-sale"}, resp.Links[0].Tags)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/list?tag=a,b", nil))
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, "invalid tag", resp.Error)
+}
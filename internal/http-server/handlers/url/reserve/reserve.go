@@ -0,0 +1,177 @@
+package reserve
+
+import (
+	"RestApi/internal/lib/alias"
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/audit"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/storage"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultMinutes = 10
+	MaxMinutes     = 60
+)
+
+// Request holds Alias on Domain, the domain the request was sent to when
+// empty, for Minutes, DefaultMinutes when zero.
+type Request struct {
+	Alias   string `json:"alias" validate:"required,alias"`
+	Domain  string `json:"domain,omitempty"`
+	Minutes int    `json:"minutes,omitempty" validate:"min=0,max=60"`
+}
+
+// Response carries the token the link has to be saved with while the
+// reservation lasts.
+type Response struct {
+	resp.Response
+	Alias     string     `json:"alias,omitempty"`
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=AliasReserver
+type AliasReserver interface {
+	ReserveAlias(res storage.Reservation) error
+}
+
+type options struct {
+	aliasPolicy *alias.Policy
+	shortURLs   *shorturl.Builder
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy sets the policy aliases are validated against.
+// alias.Default() is used otherwise.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithShortURL sets the custom domains aliases may be reserved on.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+func New(log *slog.Logger, reserver AliasReserver, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	validate := validator.New()
+	// Registration can only fail for an empty tag or a nil function.
+	_ = o.aliasPolicy.RegisterValidation(validate)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.reserve.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to decode request"))
+
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+		if err := validate.Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			log.Error("invalid request", "error", err.Error())
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		domain, ok := o.shortURLs.Resolve(r, req.Domain)
+		if !ok {
+			log.Info("unknown domain", slog.String("domain", req.Domain))
+			render.JSON(w, r, resp.Error("unknown domain"))
+
+			return
+		}
+
+		minutes := req.Minutes
+		if minutes == 0 {
+			minutes = DefaultMinutes
+		}
+
+		token, err := newToken()
+		if err != nil {
+			log.Error("failed to generate token", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to reserve alias"))
+
+			return
+		}
+
+		res := storage.Reservation{
+			Domain:     domain,
+			Alias:      o.aliasPolicy.Normalize(req.Alias),
+			Token:      token,
+			ReservedBy: audit.Actor(r),
+			ExpiresAt:  time.Now().UTC().Add(time.Duration(minutes) * time.Minute).Truncate(time.Second),
+		}
+
+		err = reserver.ReserveAlias(res)
+		switch {
+		case errors.Is(err, storage.ErrURLExists):
+			log.Info("alias is taken", slog.String("alias", res.Alias))
+			render.JSON(w, r, resp.Error("url already exists"))
+
+			return
+		case errors.Is(err, storage.ErrAliasQuarantined):
+			log.Info("alias of a deleted link", slog.String("alias", res.Alias))
+			render.JSON(w, r, resp.Error("alias belongs to a deleted link"))
+
+			return
+		case errors.Is(err, storage.ErrAliasHeld):
+			log.Info("alias is held", slog.String("alias", res.Alias))
+			render.JSON(w, r, resp.Error("alias is held by a reservation"))
+
+			return
+		case err != nil:
+			log.Error("failed to reserve alias", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to reserve alias"))
+
+			return
+		}
+
+		log.Info("alias reserved", slog.String("alias", res.Alias), slog.Time("expires_at", res.ExpiresAt))
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			Alias:     res.Alias,
+			Token:     res.Token,
+			ExpiresAt: &res.ExpiresAt,
+		})
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
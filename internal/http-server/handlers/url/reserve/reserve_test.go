@@ -0,0 +1,122 @@
+package reserve_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/reserve"
+	"RestApi/internal/http-server/handlers/url/reserve/mocks"
+	"RestApi/internal/storage"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReserveHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		minutes   int
+		respError string
+		mockError error
+	}{
+		{
+			name:    "success",
+			input:   `{"alias": "launch", "minutes": 30}`,
+			minutes: 30,
+		},
+		{
+			name:    "Default duration",
+			input:   `{"alias": "launch"}`,
+			minutes: reserve.DefaultMinutes,
+		},
+		{
+			name:      "Empty alias",
+			input:     `{"minutes": 5}`,
+			respError: "field Alias is a required field",
+		},
+		{
+			name:      "Too long",
+			input:     `{"alias": "launch", "minutes": 61}`,
+			respError: "field Minutes is not valid",
+		},
+		{
+			name:      "Unknown domain",
+			input:     `{"alias": "launch", "domain": "other.example"}`,
+			respError: "unknown domain",
+		},
+		{
+			name:      "Alias exists",
+			input:     `{"alias": "launch"}`,
+			minutes:   reserve.DefaultMinutes,
+			respError: "url already exists",
+			mockError: storage.ErrURLExists,
+		},
+		{
+			name:      "Alias of a deleted link",
+			input:     `{"alias": "launch"}`,
+			minutes:   reserve.DefaultMinutes,
+			respError: "alias belongs to a deleted link",
+			mockError: storage.ErrAliasQuarantined,
+		},
+		{
+			name:      "Already held",
+			input:     `{"alias": "launch"}`,
+			minutes:   reserve.DefaultMinutes,
+			respError: "alias is held by a reservation",
+			mockError: storage.ErrAliasHeld,
+		},
+		{
+			name:      "ReserveAlias Error",
+			input:     `{"alias": "launch"}`,
+			minutes:   reserve.DefaultMinutes,
+			respError: "failed to reserve alias",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			reserverMock := mocks.NewAliasReserver(t)
+
+			start := time.Now().UTC()
+			if tc.respError == "" || tc.mockError != nil {
+				reserverMock.On("ReserveAlias", mock.MatchedBy(func(res storage.Reservation) bool {
+					d := res.ExpiresAt.Sub(start)
+					want := time.Duration(tc.minutes) * time.Minute
+
+					return res.Alias == "launch" && res.ReservedBy == "alice" && len(res.Token) == 32 &&
+						d > want-2*time.Second && d <= want+time.Second
+				})).
+					Return(tc.mockError).
+					Once()
+			}
+
+			handler := reserve.New(slog.New(slog.NewTextHandler(io.Discard, nil)), reserverMock)
+			req := httptest.NewRequest(http.MethodPost, "/url/reserve", bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("alice", "secret")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp reserve.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, "launch", resp.Alias)
+				require.Len(t, resp.Token, 32)
+				require.NotNil(t, resp.ExpiresAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package restore
+
+import (
+	"RestApi/internal/lib/alias"
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/audit"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/storage"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+)
+
+type Request struct {
+	Alias  string `json:"alias" validate:"required"`
+	Domain string `json:"domain,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLRestorer
+type URLRestorer interface {
+	RestoreURL(domain, alias string) error
+}
+
+type options struct {
+	aliasPolicy *alias.Policy
+	shortURLs   *shorturl.Builder
+	audit       *audit.Recorder
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy normalizes aliases the way the save handler stores them.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithShortURL sets the custom domains links may be managed on. Requests
+// act on the domain they name, or else on the domain they were sent to.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+// WithAudit records restored links in the audit log.
+func WithAudit(rec *audit.Recorder) Option {
+	return func(o *options) {
+		o.audit = rec
+	}
+}
+
+// New brings back a deleted link that has not been purged yet.
+func New(log *slog.Logger, restorer URLRestorer, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.restore.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to decode request"))
+
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			log.Error("invalid request", "error", err.Error())
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		req.Alias = o.aliasPolicy.Normalize(req.Alias)
+
+		domain, ok := o.shortURLs.Resolve(r, req.Domain)
+		if !ok {
+			log.Info("unknown domain", slog.String("domain", req.Domain))
+			render.JSON(w, r, resp.Error("unknown domain"))
+
+			return
+		}
+
+		err = restorer.RestoreURL(domain, req.Alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("no deleted url", slog.String("alias", req.Alias))
+			render.JSON(w, r, resp.Error("no deleted url with this alias"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to restore url", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to restore url"))
+
+			return
+		}
+
+		log.Info("url restored", slog.String("alias", req.Alias))
+		o.audit.Record(r, audit.ActionRestore, domain, req.Alias, nil, o.audit.Snapshot(domain, req.Alias))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
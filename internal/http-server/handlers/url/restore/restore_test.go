@@ -0,0 +1,122 @@
+package restore_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/restore"
+	"RestApi/internal/http-server/handlers/url/restore/mocks"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/storage"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRestoreURLHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		alias     string
+		respError string
+		mockError error
+	}{
+		{
+			name:  "success",
+			alias: "test_alias",
+		},
+		{
+			name:      "Empty alias",
+			alias:     "",
+			respError: "field Alias is a required field",
+		},
+		{
+			name:      "Not found",
+			alias:     "test_bad_alias",
+			respError: "no deleted url with this alias",
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "RestoreURL Error",
+			alias:     "test_alias",
+			respError: "failed to restore url",
+			mockError: errors.New("failed to restore url"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlRestoreMock := mocks.NewURLRestorer(t)
+
+			if tc.respError == "" || tc.mockError != nil {
+				urlRestoreMock.On(
+					"RestoreURL", "", tc.alias).
+					Return(tc.mockError).
+					Once()
+			}
+
+			handler := restore.New(slog.New(
+				slog.NewTextHandler(io.Discard, nil)), urlRestoreMock)
+			input := fmt.Sprintf(`{"alias": "%s"}`, tc.alias)
+			req, err := http.NewRequest(
+				http.MethodPost, "/restore", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, rr.Code, http.StatusOK)
+
+			body := rr.Body.String()
+			var resp restore.Response
+			require.NoError(t, json.Unmarshal([]byte(body), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestRestoreURLHandler_Domain(t *testing.T) {
+	shortURLs, err := shorturl.New("https://sho.rt", []string{"brand.example"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		host      string
+		domain    string
+		want      string
+		respError string
+	}{
+		{name: "default domain", host: "sho.rt"},
+		{name: "requested domain", host: "sho.rt", domain: "Brand.example", want: "brand.example"},
+		{name: "domain of the request", host: "brand.example", want: "brand.example"},
+		{name: "unknown domain", host: "sho.rt", domain: "other.example", respError: "unknown domain"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlRestoreMock := mocks.NewURLRestorer(t)
+			if tc.respError == "" {
+				urlRestoreMock.On("RestoreURL", tc.want, "promo").Return(nil).Once()
+			}
+
+			handler := restore.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlRestoreMock,
+				restore.WithShortURL(shortURLs))
+			input := fmt.Sprintf(`{"alias": "promo", "domain": "%s"}`, tc.domain)
+			req := httptest.NewRequest(http.MethodPost, "/url/restore", bytes.NewReader([]byte(input)))
+			req.Host = tc.host
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp restore.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
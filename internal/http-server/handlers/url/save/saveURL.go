@@ -1,25 +1,128 @@
 package save
 
 import (
+	"RestApi/internal/lib/alias"
 	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/audit"
+	"RestApi/internal/lib/linkauth"
+	"RestApi/internal/lib/linkcheck"
+	"RestApi/internal/lib/linkmeta"
 	"RestApi/internal/lib/random"
+	"RestApi/internal/lib/reachability"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/lib/targeting"
+	"RestApi/internal/lib/urlnorm"
+	"RestApi/internal/lib/urlpolicy"
 	"RestApi/internal/storage"
+	"context"
+	"encoding/json"
 	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 	"log/slog"
 	"net/http"
+	"time"
 )
 
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
-	Alias string `json:"alias,omitempty"`
+	Alias string `json:"alias,omitempty" validate:"omitempty,alias"`
+	// Password protects the link. bcrypt ignores anything past 72 bytes,
+	// so longer passwords are refused rather than silently truncated.
+	Password string `json:"password,omitempty" validate:"omitempty,max=72"`
+	// MaxClicks turns the link into a limited-use one, e.g. 1 for
+	// one-time invites.
+	MaxClicks int `json:"max_clicks,omitempty" validate:"omitempty,min=1"`
+	// NotBefore and NotAfter (RFC 3339) limit when the link redirects.
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+	// Rules send matching visitors to other targets, see storage.Rule.
+	Rules []storage.Rule `json:"rules,omitempty" validate:"max=20"`
+	// RedirectType is 301, 302, 307, 308, meta or frame; the server
+	// default applies when empty.
+	RedirectType string `json:"redirect_type,omitempty" validate:"omitempty,oneof=301 302 307 308 meta frame"`
+	// PassQuery forwards the visitor's query string, resolving clashes
+	// with the target's parameters by keep, replace or append.
+	PassQuery string `json:"pass_query,omitempty" validate:"omitempty,oneof=keep replace append"`
+	// PassPath forwards whatever follows the alias in the path.
+	PassPath bool `json:"pass_path,omitempty"`
+	// Domain is a configured custom domain the link is created on instead
+	// of the one the request was sent to. Aliases only need to be unique
+	// per domain.
+	Domain string `json:"domain,omitempty"`
+	// Title, Description, Tags and Metadata, a free-form JSON object,
+	// help organise links and are never shown to visitors.
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	Metadata    json.RawMessage `json:"metadata,omitempty"`
+	// Reservation is the token of a reservation held on Alias, see
+	// handlers/url/reserve.
+	Reservation string `json:"reservation,omitempty"`
+}
+
+// LogValue keeps the password out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("url", r.URL),
+		slog.String("alias", r.Alias),
+		slog.Bool("password", r.Password != ""),
+		slog.Int("max_clicks", r.MaxClicks),
+		slog.Any("not_before", r.NotBefore),
+		slog.Any("not_after", r.NotAfter),
+		slog.Int("rules", len(r.Rules)),
+		slog.String("redirect_type", r.RedirectType),
+		slog.String("pass_query", r.PassQuery),
+		slog.Bool("pass_path", r.PassPath),
+		slog.String("domain", r.Domain),
+		slog.String("title", r.Title),
+		slog.Any("tags", r.Tags),
+		slog.Bool("reservation", r.Reservation != ""),
+	)
+}
+
+// prepareMeta validates the descriptive fields of req.
+func prepareMeta(req Request) (storage.Meta, error) {
+	if err := linkmeta.ValidateText(req.Title, req.Description); err != nil {
+		return storage.Meta{}, err
+	}
+	tags, err := linkmeta.NormalizeTags(req.Tags)
+	if err != nil {
+		return storage.Meta{}, err
+	}
+	metadata, err := linkmeta.CompactMetadata(req.Metadata)
+	if err != nil {
+		return storage.Meta{}, err
+	}
+
+	return storage.Meta{
+		Title:       req.Title,
+		Description: req.Description,
+		Tags:        tags,
+		Metadata:    metadata,
+	}, nil
+}
+
+// validateWindow checks the activation window of a new link.
+func validateWindow(req Request, now time.Time) error {
+	if req.NotAfter == nil {
+		return nil
+	}
+	if req.NotBefore != nil && !req.NotAfter.After(*req.NotBefore) {
+		return errors.New("not_after must be later than not_before")
+	}
+	if !req.NotAfter.After(now) {
+		return errors.New("not_after is in the past")
+	}
+
+	return nil
 }
 
 type Response struct {
 	resp.Response
-	Alias string `json:"alias,omitempty"`
+	Alias    string `json:"alias,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
 }
 
 // TODO: move to config
@@ -27,10 +130,120 @@ const aliasLength = 6
 
 //go:generate go run github.com/vektra/mockery/v2@latest --name=URLSaver
 type URLSaver interface {
-	SaveURL(urlToSave string, alias string) (int64, error)
+	SaveLink(link storage.Link) (int64, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=ReservationStore
+type ReservationStore interface {
+	GetReservation(domain, alias string) (storage.Reservation, error)
+	ReleaseReservation(domain, alias string) error
+}
+
+type options struct {
+	aliasPolicy *alias.Policy
+	urlPolicy   *urlpolicy.Policy
+	normalizer  *urlnorm.Normalizer
+	checker     *reachability.Checker
+	rejectDead  bool
+	shortURLs   *shorturl.Builder
+	audit       *audit.Recorder
+	holds       ReservationStore
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy sets the policy custom aliases are validated against.
+// alias.Default() is used otherwise.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithURLPolicy rejects target URLs the policy does not allow.
+func WithURLPolicy(p *urlpolicy.Policy) Option {
+	return func(o *options) {
+		o.urlPolicy = p
+	}
+}
+
+// WithNormalizer stores target URLs in canonical form, keeping the
+// submitted URL as the link's original URL.
+func WithNormalizer(n *urlnorm.Normalizer) Option {
+	return func(o *options) {
+		o.normalizer = n
+	}
+}
+
+// WithReachabilityCheck requests the target before saving it and stores
+// the outcome with the link. With rejectDead set, targets that cannot be
+// reached or answer with an error status are refused instead of being
+// saved as dead.
+func WithReachabilityCheck(c *reachability.Checker, rejectDead bool) Option {
+	return func(o *options) {
+		o.checker = c
+		o.rejectDead = rejectDead
+	}
+}
+
+// WithShortURL sets how the returned short URL is built. It is taken
+// from the request host otherwise.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+// WithAudit records created links in the audit log.
+func WithAudit(rec *audit.Recorder) Option {
+	return func(o *options) {
+		o.audit = rec
+	}
+}
+
+// WithReservations refuses aliases held by a reservation unless the
+// request carries its token, and releases the reservation once the link
+// is saved.
+func WithReservations(store ReservationStore) Option {
+	return func(o *options) {
+		o.holds = store
+	}
+}
+
+// prepareRule validates rule and puts its target through the same
+// normalization and policy as the default target.
+func prepareRule(ctx context.Context, validate *validator.Validate, check *linkcheck.Checker, rule storage.Rule) (storage.Rule, error) {
+	if validate.Var(rule.URL, "required,url") != nil {
+		return rule, errors.New("rule url is not a valid URL")
+	}
+	if err := targeting.Validate(rule); err != nil {
+		return rule, err
+	}
+
+	var err error
+	rule.URL, _, err = check.Target(ctx, rule.URL)
+
+	return rule, err
 }
 
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+func New(log *slog.Logger, urlSaver URLSaver, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	validate := validator.New()
+	// Registration can only fail for an empty tag or a nil function.
+	_ = o.aliasPolicy.RegisterValidation(validate)
+
+	check := linkcheck.New(linkcheck.Config{
+		Aliases:    o.aliasPolicy,
+		Domains:    o.shortURLs,
+		URLs:       o.urlPolicy,
+		Normalizer: o.normalizer,
+		Holds:      o.holds,
+	})
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
 
@@ -43,46 +256,174 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
 			log.Error("failed to decode request body", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, resp.Error("failed to decode request"))
 
 			return
 		}
 
 		log.Info("request body decoded", slog.Any("request", req))
-		if err := validator.New().Struct(req); err != nil {
+		if err := validate.Struct(req); err != nil {
 			var validateErr validator.ValidationErrors
 			errors.As(err, &validateErr)
 			log.Error("invalid request", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, resp.ValidationError(validateErr))
 
 			return
 		}
 
+		if err := validateWindow(req, time.Now()); err != nil {
+			log.Info("invalid activation window", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+
+		domain, ok := o.shortURLs.Resolve(r, req.Domain)
+		if !ok {
+			log.Info("unknown domain", slog.String("domain", req.Domain))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("unknown domain"))
+
+			return
+		}
+
+		meta, err := prepareMeta(req)
+		if err != nil {
+			log.Info("invalid link metadata", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+
+		link := storage.Link{
+			Meta:         meta,
+			Domain:       domain,
+			URL:          req.URL,
+			MaxClicks:    req.MaxClicks,
+			RedirectType: req.RedirectType,
+			PassQuery:    req.PassQuery,
+			PassPath:     req.PassPath,
+			CreatedBy:    audit.Actor(r),
+		}
+		if req.NotBefore != nil {
+			link.NotBefore = req.NotBefore.UTC()
+		}
+		if req.NotAfter != nil {
+			link.NotAfter = req.NotAfter.UTC()
+		}
+		link.URL, link.OriginalURL, err = check.Target(r.Context(), req.URL)
+		if err != nil {
+			log.Info("url rejected", slog.String("url", req.URL), "error", err.Error())
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+
+		for _, rule := range req.Rules {
+			rule, err := prepareRule(r.Context(), validate, check, rule)
+			if err != nil {
+				log.Info("invalid rule", slog.Any("rule", rule), "error", err.Error())
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(err.Error()))
+
+				return
+			}
+			link.Rules = append(link.Rules, rule)
+		}
+
+		if o.checker != nil {
+			status, err := o.checker.Status(r.Context(), link.URL)
+			if err != nil {
+				log.Info("url is not reachable", slog.String("url", link.URL), "error", err.Error())
+			}
+			if status.Dead && o.rejectDead {
+				log.Info("dead url rejected", slog.String("url", link.URL), slog.Int("status", status.StatusCode))
+				render.Status(r, http.StatusUnprocessableEntity)
+				render.JSON(w, r, resp.Error("url is not reachable"))
+
+				return
+			}
+			link.Status = status
+		}
+
+		if req.Password != "" {
+			link.PasswordHash, err = linkauth.Hash(req.Password)
+			if err != nil {
+				log.Error("failed to hash password", "error", err.Error())
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("failed to add url"))
+
+				return
+			}
+		}
+
 		alias := req.Alias
 		if alias == "" {
 			alias = random.NewRandomString(aliasLength)
 			//TODO: if alias already exists, write an implementation
 		}
+		link.Alias = o.aliasPolicy.Normalize(alias)
+
+		if err := check.Hold(link.Domain, link.Alias, req.Reservation); err != nil {
+			if errors.Is(err, storage.ErrAliasHeld) {
+				log.Info("alias is held", slog.String("alias", link.Alias))
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error("alias is held by a reservation"))
 
-		id, err := urlSaver.SaveURL(req.URL, alias)
+				return
+			}
+			log.Error("failed to get reservation", "error", err.Error())
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to add url"))
+
+			return
+		}
+
+		id, err := urlSaver.SaveLink(link)
 		if errors.Is(err, storage.ErrURLExists) {
 			log.Info("url already exists", slog.String("url", req.URL))
+			render.Status(r, http.StatusConflict)
 			render.JSON(w, r, resp.Error("url already exists"))
 
 			return
 		}
+		if errors.Is(err, storage.ErrAliasQuarantined) {
+			log.Info("alias of a deleted link", slog.String("alias", link.Alias))
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, resp.Error("alias belongs to a deleted link"))
+
+			return
+		}
 		if err != nil {
 			log.Error("failed to add url", "error", err.Error())
+			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, resp.Error("failed to add url"))
 
 			return
 		}
 
 		log.Info("url added", slog.Int64("id", id))
+		if o.holds != nil && req.Reservation != "" {
+			if err := o.holds.ReleaseReservation(link.Domain, link.Alias); err != nil {
+				log.Error("failed to release reservation", slog.String("alias", link.Alias), "error", err.Error())
+			}
+		}
+		o.audit.Record(r, audit.ActionCreate, link.Domain, link.Alias, nil, audit.StateOf(link))
+
+		shortURL := o.shortURLs.URL(r, link.Alias)
+		if link.Domain != "" {
+			shortURL = o.shortURLs.OnDomain(link.Domain, link.Alias)
+		}
 
 		render.JSON(w, r, Response{
 			Response: resp.OK(),
-			Alias:    alias,
+			Alias:    link.Alias,
+			ShortURL: shortURL,
 		})
 	}
 }
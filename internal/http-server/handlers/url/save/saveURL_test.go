@@ -3,17 +3,24 @@ package save_test
 import (
 	"RestApi/internal/http-server/handlers/url/save"
 	"RestApi/internal/http-server/handlers/url/save/mocks"
+	"RestApi/internal/lib/reachability"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/lib/urlnorm"
+	"RestApi/internal/storage"
 	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -22,29 +29,64 @@ func TestSaveHandler(t *testing.T) {
 		alias     string
 		url       string
 		respError string
+		status    int
 		mockError error
 	}{
 		{
-			name:  "success",
-			alias: "test_alias",
-			url:   "https://google.com",
+			name:   "success",
+			alias:  "test_alias",
+			url:    "https://google.com",
+			status: http.StatusOK,
 		},
 		{
-			name:  "Empty alias",
-			alias: "",
-			url:   "https://google.com",
+			name:   "Empty alias",
+			alias:  "",
+			url:    "https://google.com",
+			status: http.StatusOK,
 		},
 		{
 			name:      "Empty URL",
 			url:       "",
 			alias:     "some_alias",
 			respError: "field URL is a required field",
+			status:    http.StatusBadRequest,
 		},
 		{
 			name:      "Invalid URL",
 			url:       "some invalid URL",
 			alias:     "some_alias",
 			respError: "field URL is not a valid URL",
+			status:    http.StatusBadRequest,
+		},
+		{
+			name:      "Alias with slash",
+			url:       "https://google.com",
+			alias:     "some/alias",
+			respError: "field Alias is not an allowed alias",
+			status:    http.StatusBadRequest,
+		},
+		{
+			name:      "Alias with space",
+			url:       "https://google.com",
+			alias:     "some alias",
+			respError: "field Alias is not an allowed alias",
+			status:    http.StatusBadRequest,
+		},
+		{
+			name:      "Alias exists",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			respError: "url already exists",
+			mockError: storage.ErrURLExists,
+			status:    http.StatusConflict,
+		},
+		{
+			name:      "Alias of a deleted link",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			respError: "alias belongs to a deleted link",
+			mockError: storage.ErrAliasQuarantined,
+			status:    http.StatusConflict,
 		},
 		{
 			name:      "SaveURL Error",
@@ -52,6 +94,7 @@ func TestSaveHandler(t *testing.T) {
 			url:       "https://google.com",
 			respError: "failed to add url",
 			mockError: errors.New("unexpected error"),
+			status:    http.StatusInternalServerError,
 		},
 	}
 
@@ -64,8 +107,9 @@ func TestSaveHandler(t *testing.T) {
 			urlSaverMock := mocks.NewURLSaver(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On(
-					"SaveURL", tc.url, mock.AnythingOfType("string")).
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return link.URL == tc.url && link.Alias != ""
+				})).
 					Return(int64(1), tc.mockError).
 					Once()
 			}
@@ -81,7 +125,7 @@ func TestSaveHandler(t *testing.T) {
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
-			require.Equal(t, rr.Code, http.StatusOK)
+			require.Equal(t, tc.status, rr.Code)
 
 			body := rr.Body.String()
 			var resp save.Response
@@ -91,3 +135,327 @@ func TestSaveHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveHandler_Normalization(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveLink", storage.Link{
+		Alias:       "test_alias",
+		URL:         "http://example.com/b",
+		OriginalURL: "HTTP://Example.com:80/a/../b?utm_source=x",
+	}).Return(int64(1), nil).Once()
+
+	handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlSaverMock,
+		save.WithNormalizer(urlnorm.New(urlnorm.Options{StripTracking: true})))
+	input := `{"url": "HTTP://Example.com:80/a/../b?utm_source=x", "alias": "test_alias"}`
+	req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var resp save.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Empty(t, resp.Error)
+	require.Equal(t, "test_alias", resp.Alias)
+}
+
+func TestSaveHandler_ShortURL(t *testing.T) {
+	shortURLs, err := shorturl.New("https://sho.rt", []string{"brand.example"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		domain    string
+		shortURL  string
+		respError string
+	}{
+		{name: "base url", shortURL: "https://sho.rt/test_alias"},
+		{name: "custom domain", domain: "Brand.example", shortURL: "https://brand.example/test_alias"},
+		{name: "unknown domain", domain: "other.example", respError: "unknown domain"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", storage.Link{Domain: strings.ToLower(tc.domain), Alias: "test_alias", URL: "https://example.com"}).
+					Return(int64(1), nil).Once()
+			}
+
+			handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlSaverMock,
+				save.WithShortURL(shortURLs))
+			input := fmt.Sprintf(`{"url": "https://example.com", "alias": "test_alias", "domain": "%s"}`, tc.domain)
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.shortURL, resp.ShortURL)
+		})
+	}
+}
+
+func TestSaveHandler_Reachability(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	checker := reachability.New(reachability.Config{AllowPrivate: true})
+
+	cases := []struct {
+		name       string
+		path       string
+		rejectDead bool
+		respError  string
+		dead       bool
+	}{
+		{name: "reachable", path: "/ok"},
+		{name: "dead flagged", path: "/gone", dead: true},
+		{name: "dead rejected", path: "/gone", rejectDead: true, respError: "url is not reachable"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return link.Status.Dead == tc.dead && !link.Status.CheckedAt.IsZero()
+				})).Return(int64(1), nil).Once()
+			}
+
+			handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlSaverMock,
+				save.WithReachabilityCheck(checker, tc.rejectDead))
+			input := fmt.Sprintf(`{"url": "%s%s", "alias": "test_alias"}`, srv.URL, tc.path)
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveHandler_Password(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+		return bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte("s3cret")) == nil
+	})).Return(int64(1), nil).Once()
+
+	handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlSaverMock)
+	input := `{"url": "https://example.com/doc.pdf", "alias": "doc", "password": "s3cret"}`
+	req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var resp save.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Empty(t, resp.Error)
+}
+
+func TestSaveHandler_ActivationWindow(t *testing.T) {
+	launch := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	cases := []struct {
+		name      string
+		window    string
+		respError string
+	}{
+		{
+			name:   "window",
+			window: fmt.Sprintf(`"not_before": %q, "not_after": %q`, launch.Format(time.RFC3339), launch.Add(time.Hour).Format(time.RFC3339)),
+		},
+		{
+			name:      "reversed",
+			window:    fmt.Sprintf(`"not_before": %q, "not_after": %q`, launch.Format(time.RFC3339), launch.Add(-time.Hour).Format(time.RFC3339)),
+			respError: "not_after must be later than not_before",
+		},
+		{
+			name:      "past",
+			window:    fmt.Sprintf(`"not_after": %q`, time.Now().Add(-time.Hour).Format(time.RFC3339)),
+			respError: "not_after is in the past",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return link.NotBefore.Equal(launch) && link.NotAfter.Equal(launch.Add(time.Hour))
+				})).Return(int64(1), nil).Once()
+			}
+
+			handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlSaverMock)
+			input := fmt.Sprintf(`{"url": "https://example.com", "alias": "launch", %s}`, tc.window)
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveHandler_Rules(t *testing.T) {
+	cases := []struct {
+		name      string
+		rules     string
+		respError string
+	}{
+		{
+			name:  "valid",
+			rules: `[{"device": "ios", "url": "https://apps.apple.com/app/id1"}, {"country": "DE", "hours": "08:00-20:00", "url": "https://example.de"}]`,
+		},
+		{
+			name:      "bad device",
+			rules:     `[{"device": "tv", "url": "https://example.com/tv"}]`,
+			respError: "device must be ios, android or desktop",
+		},
+		{
+			name:      "bad url",
+			rules:     `[{"device": "ios", "url": "not a url"}]`,
+			respError: "rule url is not a valid URL",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return len(link.Rules) == 2 && link.Rules[1].Country == "DE"
+				})).Return(int64(1), nil).Once()
+			}
+
+			handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlSaverMock)
+			input := fmt.Sprintf(`{"url": "https://example.com", "alias": "app", "rules": %s}`, tc.rules)
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveHandler_RedirectType(t *testing.T) {
+	for _, tc := range []struct {
+		redirectType string
+		respError    string
+	}{
+		{redirectType: "301"},
+		{redirectType: "frame"},
+		{redirectType: "303", respError: "field RedirectType is not valid"},
+	} {
+		t.Run(tc.redirectType, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return link.RedirectType == tc.redirectType
+				})).Return(int64(1), nil).Once()
+			}
+
+			handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlSaverMock)
+			input := fmt.Sprintf(`{"url": "https://example.com", "alias": "seo", "redirect_type": %q}`, tc.redirectType)
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveHandler_Reservation(t *testing.T) {
+	hold := storage.Reservation{Alias: "launch", Token: "abc123", ExpiresAt: time.Now().Add(10 * time.Minute)}
+
+	cases := []struct {
+		name      string
+		token     string
+		hold      *storage.Reservation
+		respError string
+	}{
+		{
+			name: "Not reserved",
+		},
+		{
+			name:  "Holder",
+			token: "abc123",
+			hold:  &hold,
+		},
+		{
+			name:      "Someone else's reservation",
+			hold:      &hold,
+			respError: "alias is held by a reservation",
+		},
+		{
+			name:      "Wrong token",
+			token:     "def456",
+			hold:      &hold,
+			respError: "alias is held by a reservation",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+			holdsMock := mocks.NewReservationStore(t)
+
+			if tc.hold != nil {
+				holdsMock.On("GetReservation", "", "launch").Return(*tc.hold, nil).Once()
+			} else {
+				holdsMock.On("GetReservation", "", "launch").
+					Return(storage.Reservation{}, storage.ErrReservationNotFound).
+					Once()
+			}
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.Anything).Return(int64(1), nil).Once()
+			}
+			if tc.respError == "" && tc.token != "" {
+				holdsMock.On("ReleaseReservation", "", "launch").Return(nil).Once()
+			}
+
+			handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlSaverMock,
+				save.WithReservations(holdsMock))
+			input := fmt.Sprintf(`{"url": "https://example.com", "alias": "launch", "reservation": "%s"}`, tc.token)
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
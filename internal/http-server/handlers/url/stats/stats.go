@@ -0,0 +1,46 @@
+package stats
+
+import (
+	resp "RestApi/internal/lib/api/response"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	resp.Response
+	Total int64 `json:"total"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLCounter
+type URLCounter interface {
+	CountURLs() (int64, error)
+}
+
+func New(log *slog.Logger, counter URLCounter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		total, err := counter.CountURLs()
+		if err != nil {
+			log.Error("failed to count urls", "error", err.Error())
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to get stats"))
+
+			return
+		}
+
+		log.Info("stats retrieved", slog.Int64("total", total))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Total:    total,
+		})
+	}
+}
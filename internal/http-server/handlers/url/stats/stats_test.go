@@ -0,0 +1,63 @@
+package stats_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/stats"
+	"RestApi/internal/http-server/handlers/url/stats/mocks"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatsHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		total     int64
+		respError string
+		status    int
+		mockError error
+	}{
+		{
+			name:   "success",
+			total:  42,
+			status: http.StatusOK,
+		},
+		{
+			name:      "CountURLs Error",
+			respError: "failed to get stats",
+			mockError: errors.New("unexpected error"),
+			status:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlCounterMock := mocks.NewURLCounter(t)
+			urlCounterMock.On("CountURLs").
+				Return(tc.total, tc.mockError).
+				Once()
+
+			handler := stats.New(slog.New(
+				slog.NewTextHandler(io.Discard, nil)), urlCounterMock)
+			req, err := http.NewRequest(http.MethodGet, "/stats", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+
+			var resp stats.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.total, resp.Total)
+		})
+	}
+}
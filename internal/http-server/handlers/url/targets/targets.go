@@ -0,0 +1,184 @@
+package targets
+
+import (
+	"RestApi/internal/lib/alias"
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/audit"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/lib/urlpolicy"
+	"RestApi/internal/storage"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+)
+
+type Target struct {
+	// Variant names the target in hit counts and sticky cookies.
+	Variant string `json:"variant" validate:"required,max=32,alphanum"`
+	URL     string `json:"url" validate:"required,url"`
+	// Weight is the target's share of visits, 0 pauses it.
+	Weight int `json:"weight" validate:"min=0,max=1000"`
+}
+
+// Request replaces the split targets of a link. An empty list turns the
+// split off.
+type Request struct {
+	Alias   string   `json:"alias" validate:"required"`
+	Domain  string   `json:"domain,omitempty"`
+	Sticky  bool     `json:"sticky,omitempty"`
+	Targets []Target `json:"targets" validate:"max=20,dive"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=TargetSetter
+type TargetSetter interface {
+	SetTargets(domain, alias string, sticky bool, targets []storage.Target) error
+}
+
+type options struct {
+	aliasPolicy *alias.Policy
+	shortURLs   *shorturl.Builder
+	urlPolicy   *urlpolicy.Policy
+	audit       *audit.Recorder
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy normalizes aliases the way the save handler stores them.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithShortURL sets the custom domains links may be managed on. Requests
+// act on the domain they name, or else on the domain they were sent to.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+// WithURLPolicy rejects target URLs the policy does not allow.
+func WithURLPolicy(p *urlpolicy.Policy) Option {
+	return func(o *options) {
+		o.urlPolicy = p
+	}
+}
+
+// WithAudit records changed split targets in the audit log.
+func WithAudit(rec *audit.Recorder) Option {
+	return func(o *options) {
+		o.audit = rec
+	}
+}
+
+func New(log *slog.Logger, setter TargetSetter, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.targets.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to decode request"))
+
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			log.Error("invalid request", "error", err.Error())
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		targets, err := o.targets(r, req.Targets)
+		if err != nil {
+			log.Info("invalid targets", "error", err.Error())
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+
+		req.Alias = o.aliasPolicy.Normalize(req.Alias)
+
+		domain, ok := o.shortURLs.Resolve(r, req.Domain)
+		if !ok {
+			log.Info("unknown domain", slog.String("domain", req.Domain))
+			render.JSON(w, r, resp.Error("unknown domain"))
+
+			return
+		}
+
+		old := o.audit.Snapshot(domain, req.Alias)
+		err = setter.SetTargets(domain, req.Alias, req.Sticky, targets)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", req.Alias))
+			render.JSON(w, r, resp.Error("url not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to set targets", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to set targets"))
+
+			return
+		}
+
+		log.Info("targets set", slog.String("alias", req.Alias), slog.Int("targets", len(targets)))
+		o.audit.Record(r, audit.ActionUpdate, domain, req.Alias, old, o.audit.Snapshot(domain, req.Alias))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
+
+func (o *options) targets(r *http.Request, reqTargets []Target) ([]storage.Target, error) {
+	seen := make(map[string]bool, len(reqTargets))
+	total := 0
+
+	targets := make([]storage.Target, 0, len(reqTargets))
+	for _, t := range reqTargets {
+		if seen[t.Variant] {
+			return nil, fmt.Errorf("duplicate variant %s", t.Variant)
+		}
+		seen[t.Variant] = true
+		total += t.Weight
+
+		if o.urlPolicy != nil {
+			if err := o.urlPolicy.Check(r.Context(), t.URL); err != nil {
+				return nil, err
+			}
+		}
+
+		targets = append(targets, storage.Target{Variant: t.Variant, URL: t.URL, Weight: t.Weight})
+	}
+
+	if len(targets) > 0 && total == 0 {
+		return nil, errors.New("at least one target needs a weight")
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,89 @@
+package targets_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/targets"
+	"RestApi/internal/http-server/handlers/url/targets/mocks"
+	"RestApi/internal/storage"
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTargetsHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		sticky    bool
+		targets   []storage.Target
+		respError string
+		mockError error
+	}{
+		{
+			name: "success",
+			input: `{"alias": "promo", "sticky": true, "targets": [
+				{"variant": "a", "url": "https://example.com/a", "weight": 70},
+				{"variant": "b", "url": "https://example.com/b", "weight": 30}]}`,
+			sticky: true,
+			targets: []storage.Target{
+				{Variant: "a", URL: "https://example.com/a", Weight: 70},
+				{Variant: "b", URL: "https://example.com/b", Weight: 30},
+			},
+		},
+		{
+			name:    "clear",
+			input:   `{"alias": "promo", "targets": []}`,
+			targets: []storage.Target{},
+		},
+		{
+			name: "duplicate variant",
+			input: `{"alias": "promo", "targets": [
+				{"variant": "a", "url": "https://example.com/a", "weight": 1},
+				{"variant": "a", "url": "https://example.com/b", "weight": 1}]}`,
+			respError: "duplicate variant a",
+		},
+		{
+			name:      "all paused",
+			input:     `{"alias": "promo", "targets": [{"variant": "a", "url": "https://example.com/a", "weight": 0}]}`,
+			respError: "at least one target needs a weight",
+		},
+		{
+			name:      "invalid url",
+			input:     `{"alias": "promo", "targets": [{"variant": "a", "url": "nope", "weight": 1}]}`,
+			respError: "field URL is not a valid URL",
+		},
+		{
+			name:      "not found",
+			input:     `{"alias": "promo", "targets": [{"variant": "a", "url": "https://example.com/a", "weight": 1}]}`,
+			targets:   []storage.Target{{Variant: "a", URL: "https://example.com/a", Weight: 1}},
+			respError: "url not found",
+			mockError: storage.ErrURLNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setterMock := mocks.NewTargetSetter(t)
+			if tc.targets != nil {
+				setterMock.On("SetTargets", "", "promo", tc.sticky, tc.targets).
+					Return(tc.mockError).Once()
+			}
+
+			handler := targets.New(slog.New(slog.NewTextHandler(io.Discard, nil)), setterMock)
+			req, err := http.NewRequest(http.MethodPut, "/targets", bytes.NewReader([]byte(tc.input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp targets.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
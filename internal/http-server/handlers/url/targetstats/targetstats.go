@@ -0,0 +1,115 @@
+package targetstats
+
+import (
+	"RestApi/internal/lib/alias"
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/storage"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type Target struct {
+	Variant string `json:"variant"`
+	URL     string `json:"url"`
+	Weight  int    `json:"weight"`
+	Hits    int64  `json:"hits"`
+}
+
+type Response struct {
+	resp.Response
+	Targets []Target `json:"targets"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=TargetGetter
+type TargetGetter interface {
+	GetTargets(domain, alias string) ([]storage.Target, error)
+}
+
+type options struct {
+	aliasPolicy *alias.Policy
+	shortURLs   *shorturl.Builder
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy normalizes aliases the way the save handler stores them.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithShortURL sets the custom domains links may be managed on. Requests
+// act on the domain they name, or else on the domain they were sent to.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+// New lists the split targets of the link named by the alias and domain
+// query parameters, with how often each was served.
+func New(log *slog.Logger, getter TargetGetter, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.targetstats.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := o.aliasPolicy.Normalize(r.URL.Query().Get("alias"))
+		if alias == "" {
+			log.Info("alias is empty")
+			render.JSON(w, r, resp.Error("invalid request"))
+
+			return
+		}
+
+		requested := r.URL.Query().Get("domain")
+		domain, ok := o.shortURLs.Resolve(r, requested)
+		if !ok {
+			log.Info("unknown domain", slog.String("domain", requested))
+			render.JSON(w, r, resp.Error("unknown domain"))
+
+			return
+		}
+
+		targets, err := getter.GetTargets(domain, alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("url not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get targets", "error", err.Error())
+			render.JSON(w, r, resp.Error("failed to get targets"))
+
+			return
+		}
+
+		res := Response{Response: resp.OK(), Targets: make([]Target, 0, len(targets))}
+		for _, t := range targets {
+			res.Targets = append(res.Targets, Target{
+				Variant: t.Variant,
+				URL:     t.URL,
+				Weight:  t.Weight,
+				Hits:    t.Hits,
+			})
+		}
+
+		log.Info("targets retrieved", slog.String("alias", alias), slog.Int("targets", len(targets)))
+
+		render.JSON(w, r, res)
+	}
+}
@@ -0,0 +1,45 @@
+package targetstats_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/targetstats"
+	"RestApi/internal/http-server/handlers/url/targetstats/mocks"
+	"RestApi/internal/storage"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTargetStatsHandler(t *testing.T) {
+	getterMock := mocks.NewTargetGetter(t)
+	getterMock.On("GetTargets", "", "promo").Return([]storage.Target{
+		{ID: 1, Variant: "a", URL: "https://example.com/a", Weight: 70, Hits: 7},
+		{ID: 2, Variant: "b", URL: "https://example.com/b", Weight: 30, Hits: 3},
+	}, nil).Once()
+	getterMock.On("GetTargets", "", "missing").Return(nil, storage.ErrURLNotFound).Once()
+
+	handler := targetstats.New(slog.New(slog.NewTextHandler(io.Discard, nil)), getterMock)
+
+	get := func(query string) targetstats.Response {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/targets"+query, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp targetstats.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := get("?alias=promo")
+	require.Empty(t, resp.Error)
+	require.Equal(t, []targetstats.Target{
+		{Variant: "a", URL: "https://example.com/a", Weight: 70, Hits: 7},
+		{Variant: "b", URL: "https://example.com/b", Weight: 30, Hits: 3},
+	}, resp.Targets)
+
+	require.Equal(t, "url not found", get("?alias=missing").Error)
+	require.Equal(t, "invalid request", get("").Error)
+}
@@ -0,0 +1,291 @@
+package update
+
+import (
+	"RestApi/internal/lib/alias"
+	resp "RestApi/internal/lib/api/response"
+	"RestApi/internal/lib/audit"
+	"RestApi/internal/lib/linkcheck"
+	"RestApi/internal/lib/linkmeta"
+	"RestApi/internal/lib/reachability"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/lib/urlnorm"
+	"RestApi/internal/lib/urlpolicy"
+	"RestApi/internal/storage"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+)
+
+// Request changes the target and descriptive fields of a link. Fields
+// left out keep their value; an empty title or description, empty tags
+// and null metadata clear them.
+type Request struct {
+	Alias       string          `json:"alias" validate:"required"`
+	Domain      string          `json:"domain,omitempty"`
+	URL         string          `json:"url,omitempty" validate:"omitempty,url"`
+	Title       *string         `json:"title,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Tags        *[]string       `json:"tags,omitempty"`
+	Metadata    json.RawMessage `json:"metadata,omitempty"`
+}
+
+func (r Request) updatesMeta() bool {
+	return r.Title != nil || r.Description != nil || r.Tags != nil || r.Metadata != nil
+}
+
+type Response struct {
+	resp.Response
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLUpdater
+type URLUpdater interface {
+	UpdateURL(domain, alias string, urlToSave, originalURL string) error
+	GetLink(domain, alias string) (storage.Link, error)
+	SetMeta(domain, alias string, meta storage.Meta) error
+	SetLinkStatus(domain, alias string, status storage.LinkStatus) error
+}
+
+type options struct {
+	aliasPolicy *alias.Policy
+	shortURLs   *shorturl.Builder
+	urlPolicy   *urlpolicy.Policy
+	normalizer  *urlnorm.Normalizer
+	checker     *reachability.Checker
+	rejectDead  bool
+	audit       *audit.Recorder
+}
+
+type Option func(o *options)
+
+// WithAliasPolicy normalizes aliases the way the save handler stores them.
+func WithAliasPolicy(p *alias.Policy) Option {
+	return func(o *options) {
+		o.aliasPolicy = p
+	}
+}
+
+// WithShortURL sets the custom domains links may be managed on. Requests
+// act on the domain they name, or else on the domain they were sent to.
+func WithShortURL(b *shorturl.Builder) Option {
+	return func(o *options) {
+		o.shortURLs = b
+	}
+}
+
+// WithURLPolicy rejects target URLs the policy does not allow.
+func WithURLPolicy(p *urlpolicy.Policy) Option {
+	return func(o *options) {
+		o.urlPolicy = p
+	}
+}
+
+// WithNormalizer stores target URLs in canonical form, keeping the
+// submitted URL as the link's original URL, as the save handler does.
+func WithNormalizer(n *urlnorm.Normalizer) Option {
+	return func(o *options) {
+		o.normalizer = n
+	}
+}
+
+// WithReachabilityCheck requests a new target before storing it and
+// records the outcome, as the save handler does. With rejectDead set,
+// targets that cannot be reached are refused.
+func WithReachabilityCheck(c *reachability.Checker, rejectDead bool) Option {
+	return func(o *options) {
+		o.checker = c
+		o.rejectDead = rejectDead
+	}
+}
+
+// WithAudit records changes to links in the audit log.
+func WithAudit(rec *audit.Recorder) Option {
+	return func(o *options) {
+		o.audit = rec
+	}
+}
+
+func New(log *slog.Logger, updater URLUpdater, opts ...Option) http.HandlerFunc {
+	o := options{aliasPolicy: alias.Default(), shortURLs: shorturl.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	check := linkcheck.New(linkcheck.Config{
+		Aliases:    o.aliasPolicy,
+		Domains:    o.shortURLs,
+		URLs:       o.urlPolicy,
+		Normalizer: o.normalizer,
+	})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			log.Error("invalid request", "error", err.Error())
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		if req.URL == "" && !req.updatesMeta() {
+			log.Info("nothing to update")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("nothing to update"))
+
+			return
+		}
+
+		var target, original string
+		var status storage.LinkStatus
+		if req.URL != "" {
+			target, original, err = check.Target(r.Context(), req.URL)
+			if err != nil {
+				log.Info("url rejected", slog.String("url", req.URL), "error", err.Error())
+				render.Status(r, http.StatusUnprocessableEntity)
+				render.JSON(w, r, resp.Error(err.Error()))
+
+				return
+			}
+
+			if o.checker != nil {
+				status, err = o.checker.Status(r.Context(), target)
+				if err != nil {
+					log.Info("url is not reachable", slog.String("url", target), "error", err.Error())
+				}
+				if status.Dead && o.rejectDead {
+					log.Info("dead url rejected", slog.String("url", target), slog.Int("status", status.StatusCode))
+					render.Status(r, http.StatusUnprocessableEntity)
+					render.JSON(w, r, resp.Error("url is not reachable"))
+
+					return
+				}
+			}
+		}
+
+		req.Alias = o.aliasPolicy.Normalize(req.Alias)
+
+		domain, ok := o.shortURLs.Resolve(r, req.Domain)
+		if !ok {
+			log.Info("unknown domain", slog.String("domain", req.Domain))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("unknown domain"))
+
+			return
+		}
+
+		old := o.audit.Snapshot(domain, req.Alias)
+
+		var meta storage.Meta
+		if req.updatesMeta() {
+			link, err := updater.GetLink(domain, req.Alias)
+			if errors.Is(err, storage.ErrURLNotFound) {
+				log.Info("url not found", slog.String("alias", req.Alias))
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("url not found"))
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to get url", "error", err.Error())
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("failed to update url"))
+
+				return
+			}
+
+			meta, err = mergeMeta(link.Meta, req)
+			if err != nil {
+				log.Info("invalid link metadata", "error", err.Error())
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(err.Error()))
+
+				return
+			}
+		}
+
+		if req.URL != "" {
+			err = updater.UpdateURL(domain, req.Alias, target, original)
+		}
+		if err == nil && req.URL != "" && o.checker != nil {
+			err = updater.SetLinkStatus(domain, req.Alias, status)
+		}
+		if err == nil && req.updatesMeta() {
+			err = updater.SetMeta(domain, req.Alias, meta)
+		}
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", req.Alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error("url not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to update url", "error", err.Error())
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to update url"))
+
+			return
+		}
+
+		log.Info("url updated", slog.String("alias", req.Alias))
+		o.audit.Record(r, audit.ActionUpdate, domain, req.Alias, old, o.audit.Snapshot(domain, req.Alias))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
+
+// mergeMeta applies the descriptive fields set in req to meta.
+func mergeMeta(meta storage.Meta, req Request) (storage.Meta, error) {
+	if req.Title != nil {
+		meta.Title = *req.Title
+	}
+	if req.Description != nil {
+		meta.Description = *req.Description
+	}
+	if err := linkmeta.ValidateText(meta.Title, meta.Description); err != nil {
+		return meta, err
+	}
+
+	if req.Tags != nil {
+		tags, err := linkmeta.NormalizeTags(*req.Tags)
+		if err != nil {
+			return meta, err
+		}
+		meta.Tags = tags
+	}
+
+	if req.Metadata != nil {
+		metadata, err := linkmeta.CompactMetadata(req.Metadata)
+		if err != nil {
+			return meta, err
+		}
+		meta.Metadata = metadata
+	}
+
+	return meta, nil
+}
@@ -0,0 +1,290 @@
+package update_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/update"
+	"RestApi/internal/http-server/handlers/url/update/mocks"
+	"RestApi/internal/lib/reachability"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/lib/urlnorm"
+	"RestApi/internal/lib/urlpolicy"
+	"RestApi/internal/storage"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUpdateURLHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		alias     string
+		url       string
+		respError string
+		status    int
+		mockError error
+	}{
+		{
+			name:   "success",
+			alias:  "test_alias",
+			url:    "https://google.com",
+			status: http.StatusOK,
+		},
+		{
+			name:      "Empty alias",
+			alias:     "",
+			url:       "https://google.com",
+			respError: "field Alias is a required field",
+			status:    http.StatusBadRequest,
+		},
+		{
+			name:      "Invalid URL",
+			alias:     "test_alias",
+			url:       "some invalid URL",
+			respError: "field URL is not a valid URL",
+			status:    http.StatusBadRequest,
+		},
+		{
+			name:      "Rejected by policy",
+			alias:     "test_alias",
+			url:       "ftp://files.example.com",
+			respError: "url scheme is not allowed",
+			status:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "Not found",
+			alias:     "test_bad_alias",
+			url:       "https://google.com",
+			respError: "url not found",
+			mockError: storage.ErrURLNotFound,
+			status:    http.StatusNotFound,
+		},
+		{
+			name:      "UpdateURL Error",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			respError: "failed to update url",
+			mockError: errors.New("unexpected error"),
+			status:    http.StatusInternalServerError,
+		},
+	}
+
+	urlPolicy, err := urlpolicy.New(urlpolicy.Config{})
+	require.NoError(t, err)
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlUpdaterMock := mocks.NewURLUpdater(t)
+
+			if tc.respError == "" || tc.mockError != nil {
+				urlUpdaterMock.On("UpdateURL", "", tc.alias, tc.url, "").
+					Return(tc.mockError).
+					Once()
+			}
+
+			handler := update.New(slog.New(
+				slog.NewTextHandler(io.Discard, nil)), urlUpdaterMock,
+				update.WithURLPolicy(urlPolicy))
+			input := fmt.Sprintf(`{"alias": "%s", "url": "%s"}`, tc.alias, tc.url)
+			req, err := http.NewRequest(
+				http.MethodPut, "/update-url", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+
+			var resp update.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestUpdateURLHandler_Normalization(t *testing.T) {
+	urlUpdaterMock := mocks.NewURLUpdater(t)
+	urlUpdaterMock.On("UpdateURL", "", "test_alias",
+		"http://example.com/b", "HTTP://Example.com:80/a/../b?utm_source=x").
+		Return(nil).Once()
+
+	handler := update.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlUpdaterMock,
+		update.WithNormalizer(urlnorm.New(urlnorm.Options{StripTracking: true})))
+	input := `{"alias": "test_alias", "url": "HTTP://Example.com:80/a/../b?utm_source=x"}`
+	req, err := http.NewRequest(http.MethodPut, "/update-url", bytes.NewReader([]byte(input)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var resp update.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Empty(t, resp.Error)
+}
+
+func TestUpdateURLHandler_Reachability(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	checker := reachability.New(reachability.Config{AllowPrivate: true})
+
+	cases := []struct {
+		name       string
+		path       string
+		rejectDead bool
+		respError  string
+		dead       bool
+	}{
+		{name: "reachable", path: "/ok"},
+		{name: "dead flagged", path: "/gone", dead: true},
+		{name: "dead rejected", path: "/gone", rejectDead: true, respError: "url is not reachable"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlUpdaterMock := mocks.NewURLUpdater(t)
+			if tc.respError == "" {
+				urlUpdaterMock.On("UpdateURL", "", "test_alias", srv.URL+tc.path, "").Return(nil).Once()
+				urlUpdaterMock.On("SetLinkStatus", "", "test_alias", mock.MatchedBy(func(status storage.LinkStatus) bool {
+					return status.Dead == tc.dead && !status.CheckedAt.IsZero()
+				})).Return(nil).Once()
+			}
+
+			handler := update.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlUpdaterMock,
+				update.WithReachabilityCheck(checker, tc.rejectDead))
+			input := fmt.Sprintf(`{"alias": "test_alias", "url": "%s%s"}`, srv.URL, tc.path)
+			req, err := http.NewRequest(http.MethodPut, "/update-url", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp update.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestUpdateURLHandler_Meta(t *testing.T) {
+	current := storage.Meta{
+		Title:    "Spring sale",
+		Tags:     []string{"sale"},
+		Metadata: json.RawMessage(`{"campaign":"spring"}`),
+	}
+
+	cases := []struct {
+		name      string
+		input     string
+		meta      storage.Meta
+		respError string
+	}{
+		{
+			name:  "Fields left out are kept",
+			input: `{"alias": "spring", "description": "Posters", "tags": ["Sale", "Poster"]}`,
+			meta: storage.Meta{
+				Title:       "Spring sale",
+				Description: "Posters",
+				Tags:        []string{"sale", "poster"},
+				Metadata:    json.RawMessage(`{"campaign":"spring"}`),
+			},
+		},
+		{
+			name:  "Empty values clear",
+			input: `{"alias": "spring", "title": "", "tags": [], "metadata": null}`,
+			meta:  storage.Meta{},
+		},
+		{
+			name:      "Invalid tag",
+			input:     `{"alias": "spring", "tags": ["two words"]}`,
+			respError: `invalid tag: "two words"`,
+		},
+		{
+			name:      "Metadata must be an object",
+			input:     `{"alias": "spring", "metadata": [1]}`,
+			respError: "metadata must be a JSON object",
+		},
+		{
+			name:      "Nothing to update",
+			input:     `{"alias": "spring"}`,
+			respError: "nothing to update",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlUpdaterMock := mocks.NewURLUpdater(t)
+			if tc.respError != "nothing to update" {
+				urlUpdaterMock.On("GetLink", "", "spring").
+					Return(storage.Link{Alias: "spring", Meta: current}, nil).Once()
+			}
+			if tc.respError == "" {
+				urlUpdaterMock.On("SetMeta", "", "spring", tc.meta).Return(nil).Once()
+			}
+
+			handler := update.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlUpdaterMock)
+			req, err := http.NewRequest(http.MethodPut, "/update-url", bytes.NewReader([]byte(tc.input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp update.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestUpdateURLHandler_Domain(t *testing.T) {
+	shortURLs, err := shorturl.New("https://sho.rt", []string{"brand.example"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		host      string
+		domain    string
+		want      string
+		respError string
+	}{
+		{name: "default domain", host: "sho.rt"},
+		{name: "requested domain", host: "sho.rt", domain: "Brand.example", want: "brand.example"},
+		{name: "domain of the request", host: "brand.example", want: "brand.example"},
+		{name: "unknown domain", host: "sho.rt", domain: "other.example", respError: "unknown domain"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlUpdaterMock := mocks.NewURLUpdater(t)
+			if tc.respError == "" {
+				urlUpdaterMock.On("UpdateURL", tc.want, "promo", "https://example.com/new", "").Return(nil).Once()
+			}
+
+			handler := update.New(slog.New(slog.NewTextHandler(io.Discard, nil)), urlUpdaterMock,
+				update.WithShortURL(shortURLs))
+			input := fmt.Sprintf(`{"alias": "promo", "url": "https://example.com/new", "domain": "%s"}`, tc.domain)
+			req := httptest.NewRequest(http.MethodPut, "/url/update-url", bytes.NewReader([]byte(input)))
+			req.Host = tc.host
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp update.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
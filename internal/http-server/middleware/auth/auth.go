@@ -0,0 +1,62 @@
+// Package auth protects the management API with HTTP Basic credentials or
+// API keys.
+package auth
+
+import (
+	"RestApi/internal/lib/audit"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+)
+
+// APIKeyHeader carries an API key in place of Basic credentials.
+const APIKeyHeader = "X-API-Key"
+
+// New lets through requests that send an API key from keys in
+// APIKeyHeader, or Basic credentials matching users. keys maps every key
+// to the user it acts as, which is what the audit log records. Anything
+// else is answered with 401.
+func New(realm string, users, keys map[string]string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			user, ok := authenticate(r, users, keys)
+			if !ok {
+				w.Header().Add("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, realm))
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(audit.WithActor(r.Context(), user)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func authenticate(r *http.Request, users, keys map[string]string) (string, bool) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		// Every key is compared so the time taken does not tell which
+		// one came close.
+		var found string
+		ok := false
+		for k, user := range keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+				found, ok = user, true
+			}
+		}
+
+		return found, ok
+	}
+
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	want, known := users[user]
+	if !known || subtle.ConstantTimeCompare([]byte(password), []byte(want)) != 1 {
+		return "", false
+	}
+
+	return user, true
+}
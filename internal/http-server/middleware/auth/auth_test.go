@@ -0,0 +1,60 @@
+package auth_test
+
+import (
+	"RestApi/internal/http-server/middleware/auth"
+	"RestApi/internal/lib/audit"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		name     string
+		user     string
+		password string
+		key      string
+		status   int
+		actor    string
+	}{
+		{name: "Basic credentials", user: "admin", password: "pass", status: http.StatusOK, actor: "admin"},
+		{name: "Wrong password", user: "admin", password: "wrong", status: http.StatusUnauthorized},
+		{name: "API key", key: "secret-key", status: http.StatusOK, actor: "ci"},
+		{name: "Unknown API key", key: "other-key", status: http.StatusUnauthorized},
+		{
+			// A wrong key is not rescued by valid Basic credentials.
+			name: "Unknown API key with credentials", user: "admin", password: "pass", key: "other-key",
+			status: http.StatusUnauthorized,
+		},
+		{name: "Nothing", status: http.StatusUnauthorized},
+	}
+
+	mw := auth.New("test", map[string]string{"admin": "pass"}, map[string]string{"secret-key": "ci"})
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actor string
+			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actor = audit.Actor(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/url/stats", nil)
+			if tc.user != "" {
+				req.SetBasicAuth(tc.user, tc.password)
+			}
+			if tc.key != "" {
+				req.Header.Set(auth.APIKeyHeader, tc.key)
+			}
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+			require.Equal(t, tc.actor, actor)
+			if tc.status == http.StatusUnauthorized {
+				require.Equal(t, `Basic realm="test"`, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Package alias implements the policy user supplied aliases must follow.
+package alias
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tag is the validator tag checking a field against the policy.
+const Tag = "alias"
+
+const (
+	CaseSensitive   = "sensitive"
+	CaseInsensitive = "insensitive"
+)
+
+const (
+	DefaultCharset   = "a-zA-Z0-9_-"
+	DefaultMinLength = 1
+	DefaultMaxLength = 64
+)
+
+var (
+	ErrTooShort = errors.New("alias is too short")
+	ErrTooLong  = errors.New("alias is too long")
+	ErrCharset  = errors.New("alias contains characters that are not allowed")
+	ErrReserved = errors.New("alias is reserved")
+	ErrBlocked  = errors.New("alias contains a blocked word")
+)
+
+type Config struct {
+	// Charset is the content of a regexp character class, e.g. "a-z0-9".
+	Charset       string
+	MinLength     int
+	MaxLength     int
+	CaseMode      string
+	Reserved      []string
+	BlocklistFile string
+}
+
+type Policy struct {
+	charset   *regexp.Regexp
+	minLength int
+	maxLength int
+	fold      bool
+	reserved  map[string]struct{}
+	blocked   []string
+}
+
+func New(cfg Config) (*Policy, error) {
+	const op = "lib.alias.New"
+
+	if cfg.Charset == "" {
+		cfg.Charset = DefaultCharset
+	}
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = DefaultMinLength
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = DefaultMaxLength
+	}
+	if cfg.MinLength > cfg.MaxLength {
+		return nil, fmt.Errorf("%s: min length %d exceeds max length %d", op, cfg.MinLength, cfg.MaxLength)
+	}
+
+	charset, err := regexp.Compile("^[" + cfg.Charset + "]+$")
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid charset: %w", op, err)
+	}
+
+	p := &Policy{
+		charset:   charset,
+		minLength: cfg.MinLength,
+		maxLength: cfg.MaxLength,
+		reserved:  make(map[string]struct{}),
+	}
+
+	switch cfg.CaseMode {
+	case "", CaseSensitive:
+	case CaseInsensitive:
+		p.fold = true
+	default:
+		return nil, fmt.Errorf("%s: unknown case mode %q", op, cfg.CaseMode)
+	}
+
+	p.Reserve(cfg.Reserved...)
+
+	if cfg.BlocklistFile != "" {
+		if p.blocked, err = readBlocklist(cfg.BlocklistFile); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return p, nil
+}
+
+// Default returns the policy used when none is configured.
+func Default() *Policy {
+	p, err := New(Config{})
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// Reserve adds words that can never be used as aliases. Reserved words
+// are matched case-insensitively. It must not be called once the policy
+// is in use by running handlers.
+func (p *Policy) Reserve(words ...string) {
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			p.reserved[strings.ToLower(w)] = struct{}{}
+		}
+	}
+}
+
+// Check reports why alias violates the policy, or nil.
+func (p *Policy) Check(alias string) error {
+	n := utf8.RuneCountInString(alias)
+	if n < p.minLength {
+		return ErrTooShort
+	}
+	if n > p.maxLength {
+		return ErrTooLong
+	}
+	if !p.charset.MatchString(alias) {
+		return ErrCharset
+	}
+
+	lower := strings.ToLower(alias)
+	if _, ok := p.reserved[lower]; ok {
+		return ErrReserved
+	}
+	for _, word := range p.blocked {
+		if strings.Contains(lower, word) {
+			return ErrBlocked
+		}
+	}
+
+	return nil
+}
+
+// Normalize maps alias to the form it is stored and looked up in.
+func (p *Policy) Normalize(alias string) string {
+	if p.fold {
+		return strings.ToLower(alias)
+	}
+
+	return alias
+}
+
+// RegisterValidation registers Tag on v.
+func (p *Policy) RegisterValidation(v *validator.Validate) error {
+	return v.RegisterValidation(Tag, func(fl validator.FieldLevel) bool {
+		return p.Check(fl.Field().String()) == nil
+	})
+}
+
+// readBlocklist reads one word per line, skipping blank lines and lines
+// starting with #.
+func readBlocklist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blocklist: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, strings.ToLower(line))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+
+	return words, nil
+}
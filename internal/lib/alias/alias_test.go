@@ -0,0 +1,84 @@
+package alias_test
+
+import (
+	"RestApi/internal/lib/alias"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(blocklist, []byte("# comment\n\nBadWord\n"), 0o644))
+
+	p, err := alias.New(alias.Config{
+		MinLength:     3,
+		MaxLength:     10,
+		Reserved:      []string{"metrics"},
+		BlocklistFile: blocklist,
+	})
+	require.NoError(t, err)
+	p.Reserve("url")
+
+	cases := []struct {
+		alias string
+		err   error
+	}{
+		{alias: "my-alias_1"},
+		{alias: "ab", err: alias.ErrTooShort},
+		{alias: "abcdefghijk", err: alias.ErrTooLong},
+		{alias: "has space", err: alias.ErrCharset},
+		{alias: "a/b/c", err: alias.ErrCharset},
+		{alias: "привет", err: alias.ErrCharset},
+		{alias: "metrics", err: alias.ErrReserved},
+		{alias: "URL", err: alias.ErrReserved},
+		{alias: "xxbadwordx", err: alias.ErrBlocked},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.alias, func(t *testing.T) {
+			require.ErrorIs(t, p.Check(tc.alias), tc.err)
+		})
+	}
+}
+
+func TestPolicyCaseMode(t *testing.T) {
+	sensitive := alias.Default()
+	require.Equal(t, "MyAlias", sensitive.Normalize("MyAlias"))
+
+	insensitive, err := alias.New(alias.Config{CaseMode: alias.CaseInsensitive})
+	require.NoError(t, err)
+	require.Equal(t, "myalias", insensitive.Normalize("MyAlias"))
+
+	_, err = alias.New(alias.Config{CaseMode: "upper"})
+	require.Error(t, err)
+}
+
+func TestPolicyConfigErrors(t *testing.T) {
+	_, err := alias.New(alias.Config{MinLength: 10, MaxLength: 5})
+	require.Error(t, err)
+
+	_, err = alias.New(alias.Config{Charset: "a-"})
+	require.NoError(t, err)
+
+	_, err = alias.New(alias.Config{Charset: "z-a"})
+	require.Error(t, err)
+
+	_, err = alias.New(alias.Config{BlocklistFile: "does-not-exist.txt"})
+	require.Error(t, err)
+}
+
+func TestRegisterValidation(t *testing.T) {
+	v := validator.New()
+	require.NoError(t, alias.Default().RegisterValidation(v))
+
+	type request struct {
+		Alias string `validate:"omitempty,alias"`
+	}
+
+	require.NoError(t, v.Struct(request{}))
+	require.NoError(t, v.Struct(request{Alias: "ok_alias"}))
+	require.Error(t, v.Struct(request{Alias: "not ok"}))
+}
@@ -0,0 +1,205 @@
+// Package audit records who created, changed and deleted which link.
+package audit
+
+import (
+	"RestApi/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	// ActionImport covers a whole import, which is recorded as one entry
+	// holding its result.
+	ActionImport = "import"
+)
+
+type Store interface {
+	GetLink(domain, alias string) (storage.Link, error)
+	AppendAudit(entry storage.AuditEntry) error
+}
+
+// Recorder appends an audit entry for every request that changes a link.
+// A nil Recorder records nothing, so handlers can take one optionally.
+type Recorder struct {
+	store Store
+	log   *slog.Logger
+}
+
+func New(log *slog.Logger, store Store) *Recorder {
+	return &Recorder{
+		store: store,
+		log:   log.With(slog.String("component", "audit")),
+	}
+}
+
+// State is what the audit log keeps of a link. The password hash is left
+// out, only whether the link is protected is kept.
+type State struct {
+	URL           string          `json:"url"`
+	OriginalURL   string          `json:"original_url,omitempty"`
+	Title         string          `json:"title,omitempty"`
+	Description   string          `json:"description,omitempty"`
+	Tags          []string        `json:"tags,omitempty"`
+	Metadata      json.RawMessage `json:"metadata,omitempty"`
+	Protected     bool            `json:"protected,omitempty"`
+	MaxClicks     int             `json:"max_clicks,omitempty"`
+	NotBefore     *time.Time      `json:"not_before,omitempty"`
+	NotAfter      *time.Time      `json:"not_after,omitempty"`
+	Rules         []storage.Rule  `json:"rules,omitempty"`
+	Targets       []Target        `json:"targets,omitempty"`
+	StickyTargets bool            `json:"sticky_targets,omitempty"`
+	RedirectType  string          `json:"redirect_type,omitempty"`
+	PassQuery     string          `json:"pass_query,omitempty"`
+	PassPath      bool            `json:"pass_path,omitempty"`
+}
+
+// Target is a split target without its hit count, which changes with
+// every visit.
+type Target struct {
+	Variant string `json:"variant"`
+	URL     string `json:"url"`
+	Weight  int    `json:"weight"`
+}
+
+// StateOf returns the audited state of link.
+func StateOf(link storage.Link) *State {
+	s := &State{
+		URL:           link.URL,
+		OriginalURL:   link.OriginalURL,
+		Title:         link.Meta.Title,
+		Description:   link.Meta.Description,
+		Tags:          link.Meta.Tags,
+		Metadata:      link.Meta.Metadata,
+		Protected:     link.PasswordHash != "",
+		MaxClicks:     link.MaxClicks,
+		NotBefore:     timeOrNil(link.NotBefore),
+		NotAfter:      timeOrNil(link.NotAfter),
+		Rules:         link.Rules,
+		StickyTargets: link.StickyTargets,
+		RedirectType:  link.RedirectType,
+		PassQuery:     link.PassQuery,
+		PassPath:      link.PassPath,
+	}
+	for _, t := range link.Targets {
+		s.Targets = append(s.Targets, Target{Variant: t.Variant, URL: t.URL, Weight: t.Weight})
+	}
+
+	return s
+}
+
+// Snapshot returns the state of alias on domain, to be recorded as the
+// old or new value of a change. It is nil when the link does not exist or
+// cannot be read.
+func (rec *Recorder) Snapshot(domain, alias string) *State {
+	if rec == nil {
+		return nil
+	}
+
+	link, err := rec.store.GetLink(domain, alias)
+	if err != nil {
+		if !errors.Is(err, storage.ErrURLNotFound) {
+			rec.log.Error("failed to read link state", slog.String("alias", alias), "error", err.Error())
+		}
+
+		return nil
+	}
+
+	return StateOf(link)
+}
+
+// Record appends an entry for the change r made to alias on domain. old
+// and new are stored as JSON, nil values as nothing. The change already
+// happened, so failures are logged rather than returned.
+func (rec *Recorder) Record(r *http.Request, action, domain, alias string, old, new any) {
+	if rec == nil {
+		return
+	}
+
+	rec.append(Actor(r), middleware.GetReqID(r.Context()), action, domain, alias, old, new)
+}
+
+// RecordAs appends an entry for a change actor made outside an HTTP
+// request, e.g. with the administration tool.
+func (rec *Recorder) RecordAs(actor, action, domain, alias string, old, new any) {
+	if rec == nil {
+		return
+	}
+
+	rec.append(actor, "", action, domain, alias, old, new)
+}
+
+func (rec *Recorder) append(actor, requestID, action, domain, alias string, old, new any) {
+	entry := storage.AuditEntry{
+		At:        time.Now().UTC(),
+		Action:    action,
+		Domain:    domain,
+		Alias:     alias,
+		Actor:     actor,
+		RequestID: requestID,
+		Old:       rec.encode(old),
+		New:       rec.encode(new),
+	}
+
+	if err := rec.store.AppendAudit(entry); err != nil {
+		rec.log.Error("failed to append audit entry",
+			slog.String("action", action),
+			slog.String("alias", alias),
+			slog.String("request_id", entry.RequestID),
+			"error", err.Error(),
+		)
+	}
+}
+
+func (rec *Recorder) encode(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		rec.log.Error("failed to encode audit value", "error", err.Error())
+
+		return nil
+	}
+	// Typed nil pointers, e.g. a missing snapshot, encode as null.
+	if string(b) == "null" {
+		return nil
+	}
+
+	return b
+}
+
+// Actor returns the user r was authenticated as, empty when there is none.
+func Actor(r *http.Request) string {
+	if actor, ok := r.Context().Value(actorKey{}).(string); ok {
+		return actor
+	}
+	user, _, _ := r.BasicAuth()
+
+	return user
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that Actor reports actor for. It is used
+// when a request is authenticated by other means than Basic credentials.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,94 @@
+package audit_test
+
+import (
+	"RestApi/internal/lib/audit"
+	"RestApi/internal/storage"
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeStore struct {
+	links   map[string]storage.Link
+	entries []storage.AuditEntry
+	err     error
+}
+
+func (s *fakeStore) GetLink(_, alias string) (storage.Link, error) {
+	link, ok := s.links[alias]
+	if !ok {
+		return storage.Link{}, storage.ErrURLNotFound
+	}
+
+	return link, nil
+}
+
+func (s *fakeStore) AppendAudit(entry storage.AuditEntry) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, entry)
+
+	return nil
+}
+
+func TestRecorder(t *testing.T) {
+	store := &fakeStore{links: map[string]storage.Link{
+		"promo": {
+			Alias:        "promo",
+			URL:          "https://example.com",
+			PasswordHash: "$2a$10$secret",
+			Meta:         storage.Meta{Title: "Promo"},
+			Targets:      []storage.Target{{ID: 7, Variant: "a", URL: "https://example.com/a", Weight: 1, Hits: 42}},
+		},
+	}}
+	rec := audit.New(slog.New(slog.NewTextHandler(io.Discard, nil)), store)
+
+	req := httptest.NewRequest("DELETE", "/url/delete-url", nil)
+	req.SetBasicAuth("alice", "secret")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "req-1"))
+
+	old := rec.Snapshot("", "promo")
+	require.NotNil(t, old)
+	rec.Record(req, audit.ActionDelete, "", "promo", old, rec.Snapshot("", "missing"))
+
+	require.Len(t, store.entries, 1)
+	e := store.entries[0]
+	require.Equal(t, audit.ActionDelete, e.Action)
+	require.Equal(t, "promo", e.Alias)
+	require.Equal(t, "alice", e.Actor)
+	require.Equal(t, "req-1", e.RequestID)
+	require.False(t, e.At.IsZero())
+	require.Nil(t, e.New)
+	require.JSONEq(t, `{
+		"url": "https://example.com",
+		"title": "Promo",
+		"protected": true,
+		"targets": [{"variant": "a", "url": "https://example.com/a", "weight": 1}]
+	}`, string(e.Old))
+	require.NotContains(t, string(e.Old), "secret")
+
+	rec.RecordAs("ops", audit.ActionUpdate, "brand.example", "promo", old, old)
+	require.Len(t, store.entries, 2)
+	e = store.entries[1]
+	require.Equal(t, "ops", e.Actor)
+	require.Equal(t, "brand.example", e.Domain)
+	require.Empty(t, e.RequestID)
+
+	store.err = errors.New("disk full")
+	rec.Record(req, audit.ActionCreate, "", "promo", nil, old)
+	require.Len(t, store.entries, 2)
+}
+
+func TestNilRecorder(t *testing.T) {
+	var rec *audit.Recorder
+
+	require.Nil(t, rec.Snapshot("", "promo"))
+	rec.Record(httptest.NewRequest("POST", "/url", nil), audit.ActionCreate, "", "promo", nil, nil)
+	rec.RecordAs("ops", audit.ActionCreate, "", "promo", nil, nil)
+}
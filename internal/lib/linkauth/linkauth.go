@@ -0,0 +1,240 @@
+// Package linkauth protects short links with a password.
+package linkauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCookieTTL     = time.Hour
+	DefaultMaxAttempts   = 5
+	DefaultLockoutWindow = 15 * time.Minute
+
+	cookieName = "link_auth"
+	// maxTracked bounds the attempt table; expired entries are dropped
+	// once it grows past this size.
+	maxTracked = 10000
+)
+
+var (
+	ErrWrongPassword   = errors.New("wrong password")
+	ErrTooManyAttempts = errors.New("too many attempts")
+)
+
+// Hash returns the bcrypt hash of password.
+func Hash(password string) (string, error) {
+	const op = "lib.linkauth.Hash"
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return string(hash), nil
+}
+
+type Config struct {
+	// Secret signs access cookies. A random secret is generated when
+	// empty, so cookies do not survive restarts and are not shared
+	// between instances.
+	Secret        string
+	CookieTTL     time.Duration
+	MaxAttempts   int
+	LockoutWindow time.Duration
+}
+
+type attempts struct {
+	failed int
+	// inFlight counts attempts being compared right now. They count
+	// against MaxAttempts so parallel guesses cannot get past it.
+	inFlight int
+	since    time.Time
+}
+
+// Guard verifies passwords, limits failed attempts per link and issues
+// signed cookies so visitors are not prompted again until they expire.
+type Guard struct {
+	secret        []byte
+	cookieTTL     time.Duration
+	maxAttempts   int
+	lockoutWindow time.Duration
+
+	mu       sync.Mutex
+	attempts map[string]*attempts
+	now      func() time.Time
+}
+
+func New(cfg Config) *Guard {
+	secret := []byte(cfg.Secret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+	}
+	if cfg.CookieTTL <= 0 {
+		cfg.CookieTTL = DefaultCookieTTL
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.LockoutWindow <= 0 {
+		cfg.LockoutWindow = DefaultLockoutWindow
+	}
+
+	return &Guard{
+		secret:        secret,
+		cookieTTL:     cfg.CookieTTL,
+		maxAttempts:   cfg.MaxAttempts,
+		lockoutWindow: cfg.LockoutWindow,
+		attempts:      make(map[string]*attempts),
+		now:           time.Now,
+	}
+}
+
+// Verify checks password against hash. Once the link alias names on
+// domain has seen MaxAttempts failures within LockoutWindow every attempt
+// fails with ErrTooManyAttempts until the window has passed.
+func (g *Guard) Verify(domain, alias, hash, password string) error {
+	key := linkKey(domain, alias)
+	a, err := g.begin(key)
+	if err != nil {
+		return err
+	}
+
+	// bcrypt is slow on purpose, so it runs outside the lock and only
+	// guesses at the same link wait for each other, through inFlight.
+	ok := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	a.inFlight--
+	if !ok {
+		a.failed++
+
+		return ErrWrongPassword
+	}
+
+	a.failed = 0
+	if a.inFlight == 0 {
+		delete(g.attempts, key)
+	}
+
+	return nil
+}
+
+// begin counts an attempt at key as in flight, or fails with
+// ErrTooManyAttempts when failed and in flight attempts already reach
+// MaxAttempts.
+func (g *Guard) begin(key string) (*attempts, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	a := g.attempts[key]
+	switch {
+	case a == nil:
+		g.prune(now)
+		a = &attempts{since: now}
+		g.attempts[key] = a
+	case now.Sub(a.since) >= g.lockoutWindow:
+		a.failed, a.since = 0, now
+	}
+	if a.failed+a.inFlight >= g.maxAttempts {
+		return nil, ErrTooManyAttempts
+	}
+	a.inFlight++
+
+	return a, nil
+}
+
+// RetryAfter reports how long alias on domain stays locked.
+func (g *Guard) RetryAfter(domain, alias string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	a := g.attempts[linkKey(domain, alias)]
+	if a == nil || a.failed < g.maxAttempts {
+		return 0
+	}
+
+	return max(a.since.Add(g.lockoutWindow).Sub(g.now()), 0)
+}
+
+func (g *Guard) prune(now time.Time) {
+	if len(g.attempts) < maxTracked {
+		return
+	}
+	for key, a := range g.attempts {
+		if a.inFlight == 0 && now.Sub(a.since) >= g.lockoutWindow {
+			delete(g.attempts, key)
+		}
+	}
+}
+
+func linkKey(domain, alias string) string {
+	return domain + "\x00" + alias
+}
+
+// Cookie returns an access cookie for alias, scoped to its path. It is
+// bound to the host of r, as aliases are only unique per domain, and to
+// the password hash, so changing the password revokes it.
+func (g *Guard) Cookie(r *http.Request, alias, hash string) *http.Cookie {
+	expires := g.now().Add(g.cookieTTL)
+
+	return &http.Cookie{
+		Name:     cookieName,
+		Value:    g.sign(r.Host, alias, hash, expires.Unix()),
+		Path:     "/" + url.PathEscape(alias),
+		Expires:  expires,
+		MaxAge:   int(g.cookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Authorized reports whether r carries an unexpired access cookie for
+// alias protected by hash.
+func (g *Guard) Authorized(r *http.Request, alias, hash string) bool {
+	for _, c := range r.Cookies() {
+		if c.Name == cookieName && g.valid(r.Host, alias, hash, c.Value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *Guard) valid(host, alias, hash, value string) bool {
+	expiresStr, _, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || g.now().Unix() >= expires {
+		return false
+	}
+
+	return hmac.Equal([]byte(value), []byte(g.sign(host, alias, hash, expires)))
+}
+
+func (g *Guard) sign(host, alias, hash string, expires int64) string {
+	exp := strconv.FormatInt(expires, 10)
+
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write([]byte(strings.ToLower(host) + "\x00" + alias + "\x00" + hash + "\x00" + exp))
+
+	return exp + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
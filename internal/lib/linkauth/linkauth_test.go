@@ -0,0 +1,111 @@
+package linkauth_test
+
+import (
+	"RestApi/internal/lib/linkauth"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGuardVerify(t *testing.T) {
+	hash, err := linkauth.Hash("s3cret")
+	require.NoError(t, err)
+	require.NotEqual(t, "s3cret", hash)
+
+	g := linkauth.New(linkauth.Config{MaxAttempts: 2, LockoutWindow: 100 * time.Millisecond})
+
+	require.NoError(t, g.Verify("", "a", hash, "s3cret"))
+	require.ErrorIs(t, g.Verify("", "a", hash, "nope"), linkauth.ErrWrongPassword)
+	require.ErrorIs(t, g.Verify("", "a", hash, "nope"), linkauth.ErrWrongPassword)
+
+	// Locked, even for the right password, but only for this link.
+	require.ErrorIs(t, g.Verify("", "a", hash, "s3cret"), linkauth.ErrTooManyAttempts)
+	require.Positive(t, g.RetryAfter("", "a"))
+	require.NoError(t, g.Verify("", "b", hash, "s3cret"))
+
+	// The same alias on another domain is another link.
+	require.NoError(t, g.Verify("brand.example", "a", hash, "s3cret"))
+	require.Zero(t, g.RetryAfter("brand.example", "a"))
+
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, g.Verify("", "a", hash, "s3cret"))
+	require.Zero(t, g.RetryAfter("", "a"))
+}
+
+func TestGuardVerifyConcurrent(t *testing.T) {
+	hash, err := linkauth.Hash("s3cret")
+	require.NoError(t, err)
+
+	g := linkauth.New(linkauth.Config{MaxAttempts: 3, LockoutWindow: time.Minute})
+
+	// Parallel guesses are compared at the same time, but no more of
+	// them than MaxAttempts get compared at all.
+	const guesses = 10
+	errs := make(chan error, guesses)
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- g.Verify("", "a", hash, "nope")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	wrong := 0
+	for err := range errs {
+		if errors.Is(err, linkauth.ErrWrongPassword) {
+			wrong++
+		} else {
+			require.ErrorIs(t, err, linkauth.ErrTooManyAttempts)
+		}
+	}
+	require.Equal(t, 3, wrong)
+	require.ErrorIs(t, g.Verify("", "a", hash, "s3cret"), linkauth.ErrTooManyAttempts)
+	require.Positive(t, g.RetryAfter("", "a"))
+}
+
+func TestGuardCookie(t *testing.T) {
+	const hash = "$2a$10$hash"
+
+	g := linkauth.New(linkauth.Config{Secret: "secret", CookieTTL: time.Second})
+	r := httptest.NewRequest(http.MethodGet, "/a", nil)
+
+	cookie := g.Cookie(r, "a", hash)
+	require.Equal(t, "/a", cookie.Path)
+	require.True(t, cookie.HttpOnly)
+
+	withCookie := func(c *http.Cookie) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/a", nil)
+		req.AddCookie(c)
+		return req
+	}
+
+	require.True(t, g.Authorized(withCookie(cookie), "a", hash))
+	require.False(t, g.Authorized(r, "a", hash))
+
+	// Bound to the alias and the secret.
+	require.False(t, g.Authorized(withCookie(cookie), "b", hash))
+	other := linkauth.New(linkauth.Config{Secret: "other"})
+	require.False(t, other.Authorized(withCookie(cookie), "a", hash))
+
+	// And to the host, aliases on other domains are other links.
+	onBrand := withCookie(cookie)
+	onBrand.Host = "brand.example"
+	require.False(t, g.Authorized(onBrand, "a", hash))
+
+	// And to the password, changing it revokes issued cookies.
+	require.False(t, g.Authorized(withCookie(cookie), "a", "$2a$10$other"))
+
+	tampered := *cookie
+	tampered.Value = "9999999999" + tampered.Value[len("9999999999"):]
+	require.False(t, g.Authorized(withCookie(&tampered), "a", hash))
+
+	time.Sleep(time.Second)
+	require.False(t, g.Authorized(withCookie(cookie), "a", hash))
+}
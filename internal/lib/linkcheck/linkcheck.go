@@ -0,0 +1,153 @@
+// Package linkcheck holds the checks every new or changed link goes
+// through, so links created by the API, imports and the CLI are held to
+// the same alias and URL policies.
+package linkcheck
+
+import (
+	"RestApi/internal/lib/alias"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/lib/urlnorm"
+	"RestApi/internal/lib/urlpolicy"
+	"RestApi/internal/storage"
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	ErrInvalidURL    = errors.New("url is not a valid URL")
+	ErrUnknownDomain = errors.New("unknown domain")
+)
+
+type ReservationStore interface {
+	GetReservation(domain, alias string) (storage.Reservation, error)
+}
+
+type Config struct {
+	// Aliases defaults to alias.Default().
+	Aliases *alias.Policy
+	// Domains are the custom domains links may be created on. Only the
+	// default domain is allowed when nil.
+	Domains *shorturl.Builder
+	// URLs, Normalizer and Holds are skipped when nil.
+	URLs       *urlpolicy.Policy
+	Normalizer *urlnorm.Normalizer
+	Holds      ReservationStore
+}
+
+type Checker struct {
+	aliases    *alias.Policy
+	domains    *shorturl.Builder
+	urls       *urlpolicy.Policy
+	normalizer *urlnorm.Normalizer
+	holds      ReservationStore
+	validate   *validator.Validate
+}
+
+func New(cfg Config) *Checker {
+	c := &Checker{
+		aliases:    cfg.Aliases,
+		domains:    cfg.Domains,
+		urls:       cfg.URLs,
+		normalizer: cfg.Normalizer,
+		holds:      cfg.Holds,
+		validate:   validator.New(),
+	}
+	if c.aliases == nil {
+		c.aliases = alias.Default()
+	}
+	if c.domains == nil {
+		c.domains = shorturl.Default()
+	}
+
+	return c
+}
+
+// Alias checks raw against the alias policy and returns it in the form it
+// is stored in.
+func (c *Checker) Alias(raw string) (string, error) {
+	if err := c.aliases.Check(raw); err != nil {
+		return "", err
+	}
+
+	return c.aliases.Normalize(raw), nil
+}
+
+// Domain checks domain is empty or a configured custom domain and returns
+// it in the form it is stored in.
+func (c *Checker) Domain(domain string) (string, error) {
+	if domain == "" {
+		return "", nil
+	}
+	if !c.domains.Known(domain) {
+		return "", ErrUnknownDomain
+	}
+
+	return shorturl.NormalizeHost(domain), nil
+}
+
+// Target checks raw is a URL links may point to and returns the target to
+// store. original is raw when targets are normalized, the URL as submitted
+// that is kept next to the normalized one, and empty otherwise.
+func (c *Checker) Target(ctx context.Context, raw string) (target, original string, err error) {
+	if c.validate.Var(raw, "required,url") != nil {
+		return "", "", ErrInvalidURL
+	}
+
+	target = raw
+	if c.normalizer != nil {
+		if target, err = c.normalizer.Normalize(raw); err != nil {
+			return "", "", err
+		}
+		original = raw
+	}
+
+	if c.urls != nil {
+		if err := c.urls.Check(ctx, target); err != nil {
+			return "", "", err
+		}
+	}
+
+	return target, original, nil
+}
+
+// Hold returns storage.ErrAliasHeld when alias on domain is reserved for
+// someone holding a token other than token.
+func (c *Checker) Hold(domain, alias, token string) error {
+	if c.holds == nil {
+		return nil
+	}
+
+	res, err := c.holds.GetReservation(domain, alias)
+	if errors.Is(err, storage.ErrReservationNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if res.Token != token {
+		return storage.ErrAliasHeld
+	}
+
+	return nil
+}
+
+// Link runs Domain, Alias, Target and Hold on link, which holds no
+// reservation token, and returns it as it is to be stored.
+func (c *Checker) Link(ctx context.Context, link storage.Link) (storage.Link, error) {
+	var err error
+	if link.Domain, err = c.Domain(link.Domain); err != nil {
+		return link, err
+	}
+	if link.Alias, err = c.Alias(link.Alias); err != nil {
+		return link, err
+	}
+	if link.URL, link.OriginalURL, err = c.Target(ctx, link.URL); err != nil {
+		return link, err
+	}
+	if err := c.Hold(link.Domain, link.Alias, ""); err != nil {
+		return link, err
+	}
+
+	return link, nil
+}
@@ -0,0 +1,76 @@
+package linkcheck_test
+
+import (
+	"RestApi/internal/lib/alias"
+	"RestApi/internal/lib/linkcheck"
+	"RestApi/internal/lib/shorturl"
+	"RestApi/internal/lib/urlnorm"
+	"RestApi/internal/lib/urlpolicy"
+	"RestApi/internal/storage"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type holds map[string]string
+
+func (h holds) GetReservation(_, alias string) (storage.Reservation, error) {
+	if alias == "broken" {
+		return storage.Reservation{}, errors.New("unexpected error")
+	}
+	token, ok := h[alias]
+	if !ok {
+		return storage.Reservation{}, storage.ErrReservationNotFound
+	}
+
+	return storage.Reservation{Alias: alias, Token: token}, nil
+}
+
+func TestChecker(t *testing.T) {
+	aliases, err := alias.New(alias.Config{Reserved: []string{"admin"}})
+	require.NoError(t, err)
+	domains, err := shorturl.New("https://sho.rt", []string{"brand.example"})
+	require.NoError(t, err)
+	urls, err := urlpolicy.New(urlpolicy.Config{})
+	require.NoError(t, err)
+	urls.AddOwnDomains("sho.rt")
+
+	c := linkcheck.New(linkcheck.Config{
+		Aliases:    aliases,
+		Domains:    domains,
+		URLs:       urls,
+		Normalizer: urlnorm.New(urlnorm.Options{}),
+		Holds:      holds{"launch": "abc123"},
+	})
+
+	_, err = c.Alias("admin")
+	require.ErrorIs(t, err, alias.ErrReserved)
+
+	domain, err := c.Domain("Brand.example")
+	require.NoError(t, err)
+	require.Equal(t, "brand.example", domain)
+	_, err = c.Domain("other.example")
+	require.ErrorIs(t, err, linkcheck.ErrUnknownDomain)
+
+	target, original, err := c.Target(context.Background(), "HTTPS://Example.com/a/../b")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/b", target)
+	require.Equal(t, "HTTPS://Example.com/a/../b", original)
+	_, _, err = c.Target(context.Background(), "not a url")
+	require.ErrorIs(t, err, linkcheck.ErrInvalidURL)
+	_, _, err = c.Target(context.Background(), "https://sho.rt/abc")
+	require.Error(t, err)
+
+	require.NoError(t, c.Hold("", "launch", "abc123"))
+	require.ErrorIs(t, c.Hold("", "launch", ""), storage.ErrAliasHeld)
+	require.NoError(t, c.Hold("", "free", ""))
+	require.Error(t, c.Hold("", "broken", ""))
+
+	link, err := c.Link(context.Background(), storage.Link{Domain: "brand.example", Alias: "sale", URL: "https://example.com"})
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/", link.URL)
+	require.Equal(t, "https://example.com", link.OriginalURL)
+	_, err = c.Link(context.Background(), storage.Link{Alias: "launch", URL: "https://example.com"})
+	require.ErrorIs(t, err, storage.ErrAliasHeld)
+}
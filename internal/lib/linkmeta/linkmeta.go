@@ -0,0 +1,106 @@
+// Package linkmeta checks the descriptive metadata links are organised by.
+package linkmeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxTitleLength       = 200
+	MaxDescriptionLength = 2000
+	MaxTags              = 20
+	MaxTagLength         = 64
+	// MaxMetadataSize bounds the encoded metadata object in bytes.
+	MaxMetadataSize = 8 << 10
+)
+
+var (
+	ErrTitleTooLong       = errors.New("title is too long")
+	ErrDescriptionTooLong = errors.New("description is too long")
+	ErrTooManyTags        = errors.New("too many tags")
+	ErrInvalidTag         = errors.New("invalid tag")
+	ErrInvalidMetadata    = errors.New("metadata must be a JSON object")
+	ErrMetadataTooLarge   = errors.New("metadata is too large")
+)
+
+// tagPattern keeps tags free of separators, so storage can join them.
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}_.:/-]+$`)
+
+// NormalizeTag returns tag trimmed and lowercased, as tags are matched
+// case-insensitively.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if utf8.RuneCountInString(tag) > MaxTagLength || !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+	}
+
+	return tag, nil
+}
+
+// NormalizeTags normalizes every tag and drops duplicates, keeping the
+// first occurrence.
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) > MaxTags {
+		return nil, ErrTooManyTags
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]struct{}, len(tags))
+	res := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		res = append(res, tag)
+	}
+
+	return res, nil
+}
+
+// ValidateText checks the length of a title and description.
+func ValidateText(title, description string) error {
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return ErrTitleTooLong
+	}
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return ErrDescriptionTooLong
+	}
+
+	return nil
+}
+
+// CompactMetadata checks that raw is a JSON object and returns it without
+// insignificant whitespace. JSON null and empty input clear the metadata
+// and yield nil.
+func CompactMetadata(raw json.RawMessage) (json.RawMessage, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	if raw[0] != '{' {
+		return nil, ErrInvalidMetadata
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return nil, ErrInvalidMetadata
+	}
+	if buf.Len() > MaxMetadataSize {
+		return nil, ErrMetadataTooLarge
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,50 @@
+package linkmeta_test
+
+import (
+	"RestApi/internal/lib/linkmeta"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := linkmeta.NormalizeTags([]string{" Spring-2025 ", "mail", "spring-2025", "utm:source/news", "Größe"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"spring-2025", "mail", "utm:source/news", "größe"}, tags)
+
+	for _, tag := range []string{"", "two words", "a,b", strings.Repeat("x", linkmeta.MaxTagLength+1)} {
+		_, err := linkmeta.NormalizeTags([]string{tag})
+		require.ErrorIs(t, err, linkmeta.ErrInvalidTag, tag)
+	}
+
+	_, err = linkmeta.NormalizeTags(make([]string, linkmeta.MaxTags+1))
+	require.ErrorIs(t, err, linkmeta.ErrTooManyTags)
+}
+
+func TestValidateText(t *testing.T) {
+	require.NoError(t, linkmeta.ValidateText("Spring sale", "Poster in the main station"))
+	require.ErrorIs(t, linkmeta.ValidateText(strings.Repeat("ü", linkmeta.MaxTitleLength+1), ""), linkmeta.ErrTitleTooLong)
+	require.ErrorIs(t, linkmeta.ValidateText("", strings.Repeat("x", linkmeta.MaxDescriptionLength+1)), linkmeta.ErrDescriptionTooLong)
+}
+
+func TestCompactMetadata(t *testing.T) {
+	meta, err := linkmeta.CompactMetadata(json.RawMessage(` { "campaign": "spring", "budget": 100 } `))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"campaign":"spring","budget":100}`, string(meta))
+	require.Equal(t, `{"campaign":"spring","budget":100}`, string(meta))
+
+	for _, raw := range []string{"", "null"} {
+		meta, err := linkmeta.CompactMetadata(json.RawMessage(raw))
+		require.NoError(t, err)
+		require.Nil(t, meta)
+	}
+
+	for _, raw := range []string{`[1, 2]`, `"text"`, `{"open": `} {
+		_, err := linkmeta.CompactMetadata(json.RawMessage(raw))
+		require.ErrorIs(t, err, linkmeta.ErrInvalidMetadata, raw)
+	}
+
+	_, err = linkmeta.CompactMetadata(json.RawMessage(`{"x": "` + strings.Repeat("x", linkmeta.MaxMetadataSize) + `"}`))
+	require.ErrorIs(t, err, linkmeta.ErrMetadataTooLarge)
+}
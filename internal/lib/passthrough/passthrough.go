@@ -0,0 +1,88 @@
+// Package passthrough carries the query string and trailing path of a
+// short URL request over to the target.
+package passthrough
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Query policies for parameters present in both the request and the
+// target.
+const (
+	// QueryKeep keeps the target's value.
+	QueryKeep = "keep"
+	// QueryReplace uses the request's value.
+	QueryReplace = "replace"
+	// QueryAppend sends both.
+	QueryAppend = "append"
+)
+
+var ErrInvalidPath = errors.New("invalid path")
+
+// ValidQueryPolicy reports whether policy is a known query policy.
+func ValidQueryPolicy(policy string) bool {
+	switch policy {
+	case QueryKeep, QueryReplace, QueryAppend:
+		return true
+	default:
+		return false
+	}
+}
+
+// Query merges incoming into the query of target following policy.
+func Query(target string, incoming url.Values, policy string) (string, error) {
+	const op = "lib.passthrough.Query"
+
+	if len(incoming) == 0 {
+		return target, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	q := u.Query()
+	for key, values := range incoming {
+		_, exists := q[key]
+		switch {
+		case !exists, policy == QueryReplace:
+			q[key] = values
+		case policy == QueryAppend:
+			q[key] = append(q[key], values...)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// Path appends rest, an escaped path relative to the alias, to the path
+// of target.
+func Path(target, rest string) (string, error) {
+	const op = "lib.passthrough.Path"
+
+	if rest == "" {
+		return target, nil
+	}
+
+	decoded, err := url.PathUnescape(rest)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidPath)
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	rawPath := strings.TrimSuffix(u.EscapedPath(), "/") + "/" + rest
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + decoded
+	// Keep the request's escaping, e.g. an encoded slash in a segment.
+	u.RawPath = rawPath
+
+	return u.String(), nil
+}
@@ -0,0 +1,61 @@
+package passthrough_test
+
+import (
+	"RestApi/internal/lib/passthrough"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	incoming := url.Values{"ref": {"mail"}, "lang": {"de"}}
+
+	cases := []struct {
+		policy string
+		want   string
+	}{
+		{policy: passthrough.QueryKeep, want: "https://example.com/p?lang=en&ref=mail"},
+		{policy: passthrough.QueryReplace, want: "https://example.com/p?lang=de&ref=mail"},
+		{policy: passthrough.QueryAppend, want: "https://example.com/p?lang=en&lang=de&ref=mail"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.policy, func(t *testing.T) {
+			got, err := passthrough.Query("https://example.com/p?lang=en", incoming, tc.policy)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	got, err := passthrough.Query("https://example.com/p?lang=en", nil, passthrough.QueryAppend)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/p?lang=en", got)
+
+	require.True(t, passthrough.ValidQueryPolicy("keep"))
+	require.False(t, passthrough.ValidQueryPolicy("merge"))
+}
+
+func TestPath(t *testing.T) {
+	cases := []struct {
+		target string
+		rest   string
+		want   string
+	}{
+		{target: "https://docs.example.com/v2", rest: "guide/intro.html", want: "https://docs.example.com/v2/guide/intro.html"},
+		{target: "https://docs.example.com/v2/", rest: "guide", want: "https://docs.example.com/v2/guide"},
+		{target: "https://docs.example.com", rest: "a%20b/c%2Fd", want: "https://docs.example.com/a%20b/c%2Fd"},
+		{target: "https://docs.example.com/v2?x=1#top", rest: "guide", want: "https://docs.example.com/v2/guide?x=1#top"},
+		{target: "https://docs.example.com/v2", rest: "", want: "https://docs.example.com/v2"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.rest, func(t *testing.T) {
+			got, err := passthrough.Path(tc.target, tc.rest)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := passthrough.Path("https://docs.example.com", "bad%zz")
+	require.ErrorIs(t, err, passthrough.ErrInvalidPath)
+}
@@ -0,0 +1,193 @@
+// Package qr renders QR codes as PNG and SVG images.
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/skip2/go-qrcode"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultSize   = 256
+	DefaultMargin = 4
+
+	MinSize   = 64
+	MaxSize   = 2048
+	MaxMargin = 16
+)
+
+var (
+	ErrInvalidSize   = errors.New("invalid size")
+	ErrInvalidLevel  = errors.New("invalid error correction level")
+	ErrInvalidMargin = errors.New("invalid margin")
+	ErrInvalidColor  = errors.New("invalid color")
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options control how a code is drawn. Size is the width and height of
+// the image in pixels, Margin the quiet zone around the code in modules.
+type Options struct {
+	Size       int
+	Level      string
+	Margin     int
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// DefaultOptions returns black on white codes of DefaultSize with
+// medium error correction.
+func DefaultOptions() Options {
+	return Options{
+		Size:       DefaultSize,
+		Level:      "M",
+		Margin:     DefaultMargin,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// Validate reports the first option out of range.
+func (o Options) Validate() error {
+	if o.Size < MinSize || o.Size > MaxSize {
+		return ErrInvalidSize
+	}
+	if _, ok := levels[o.Level]; !ok {
+		return ErrInvalidLevel
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return ErrInvalidMargin
+	}
+
+	return nil
+}
+
+// ParseColor parses a hex colour as "rgb" or "rrggbb", with or without a
+// leading "#".
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// bitmap encodes content and surrounds it with the margin.
+func bitmap(content string, o Options) ([][]bool, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	code, err := qrcode.New(content, levels[o.Level])
+	if err != nil {
+		return nil, err
+	}
+	code.DisableBorder = true
+	modules := code.Bitmap()
+
+	n := len(modules) + 2*o.Margin
+	bits := make([][]bool, n)
+	for y := range bits {
+		bits[y] = make([]bool, n)
+		if y >= o.Margin && y < n-o.Margin {
+			copy(bits[y][o.Margin:], modules[y-o.Margin])
+		}
+	}
+
+	return bits, nil
+}
+
+// PNG renders content as a Size×Size PNG image. Modules are whole pixels,
+// leftover pixels widen the margin.
+func PNG(content string, o Options) ([]byte, error) {
+	const op = "lib.qr.PNG"
+
+	bits, err := bitmap(content, o)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	scale := o.Size / len(bits)
+	if scale < 1 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidSize)
+	}
+	offset := (o.Size - scale*len(bits)) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, o.Size, o.Size), color.Palette{o.Background, o.Foreground})
+	for y, row := range bits {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for dy := range scale {
+				for dx := range scale {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SVG renders content as an SVG image of Size pixels that scales without
+// loss.
+func SVG(content string, o Options) ([]byte, error) {
+	const op = "lib.qr.SVG"
+
+	bits, err := bitmap(content, o)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		o.Size, o.Size, len(bits), len(bits))
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, hex(o.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hex(o.Foreground))
+	for y, row := range bits {
+		// Runs of dark modules are drawn as one rectangle.
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,89 @@
+package qr_test
+
+import (
+	"RestApi/internal/lib/qr"
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	o := qr.DefaultOptions()
+	o.Foreground = color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}
+
+	data, err := qr.PNG("https://sho.rt/abc", o)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, qr.DefaultSize, img.Bounds().Dx())
+	require.Equal(t, qr.DefaultSize, img.Bounds().Dy())
+
+	// The corner lies in the margin, the finder pattern starts right
+	// after it.
+	r, g, b, _ := img.At(0, 0).RGBA()
+	require.Equal(t, [3]uint32{0xffff, 0xffff, 0xffff}, [3]uint32{r, g, b})
+
+	// The URL needs a version 2 code of 25 modules.
+	modules := 25 + 2*qr.DefaultMargin
+	scale := qr.DefaultSize / modules
+	offset := (qr.DefaultSize - scale*modules) / 2
+	at := offset + qr.DefaultMargin*scale
+	r, g, b, _ = img.At(at, at).RGBA()
+	require.Equal(t, [3]uint32{0x1111, 0x2222, 0x3333}, [3]uint32{r, g, b})
+}
+
+func TestSVG(t *testing.T) {
+	o := qr.DefaultOptions()
+	o.Margin = 0
+	o.Background, _ = qr.ParseColor("#ff0")
+
+	data, err := qr.SVG("https://sho.rt/abc", o)
+	require.NoError(t, err)
+
+	svg := string(data)
+	require.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256" viewBox="0 0 25 25"`))
+	require.Contains(t, svg, `fill="#ffff00"`)
+	// The top row opens with a finder pattern.
+	require.Contains(t, svg, `d="M0 0h7v1h-7z`)
+}
+
+func TestOptions(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(o *qr.Options)
+		err    error
+	}{
+		{name: "defaults", modify: func(o *qr.Options) {}},
+		{name: "too small", modify: func(o *qr.Options) { o.Size = qr.MinSize - 1 }, err: qr.ErrInvalidSize},
+		{name: "too large", modify: func(o *qr.Options) { o.Size = qr.MaxSize + 1 }, err: qr.ErrInvalidSize},
+		{name: "level", modify: func(o *qr.Options) { o.Level = "X" }, err: qr.ErrInvalidLevel},
+		{name: "margin", modify: func(o *qr.Options) { o.Margin = -1 }, err: qr.ErrInvalidMargin},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := qr.DefaultOptions()
+			tc.modify(&o)
+			require.ErrorIs(t, o.Validate(), tc.err)
+		})
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := qr.ParseColor("1a2B3c")
+	require.NoError(t, err)
+	require.Equal(t, color.RGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}, c)
+
+	c, err = qr.ParseColor("#abc")
+	require.NoError(t, err)
+	require.Equal(t, color.RGBA{R: 0xaa, G: 0xbb, B: 0xcc, A: 0xff}, c)
+
+	for _, s := range []string{"", "#12345", "zzzzzz", "+12345"} {
+		_, err := qr.ParseColor(s)
+		require.ErrorIs(t, err, qr.ErrInvalidColor, s)
+	}
+}
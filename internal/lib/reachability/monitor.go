@@ -0,0 +1,86 @@
+package reachability
+
+import (
+	"RestApi/internal/storage"
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	DefaultInterval  = time.Hour
+	defaultBatchSize = 100
+)
+
+type LinkStore interface {
+	ListURLsAfter(afterID int64, limit int) ([]storage.Link, error)
+	SetLinkStatus(domain, alias string, status storage.LinkStatus) error
+}
+
+// Monitor periodically re-checks every stored link and marks dead ones.
+type Monitor struct {
+	Checker  *Checker
+	Store    LinkStore
+	Log      *slog.Logger
+	Interval time.Duration
+}
+
+// Run checks all links every Interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	log := m.Log.With(slog.String("component", "reachability/monitor"))
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		checked, dead, err := m.CheckAll(ctx)
+		if err != nil {
+			log.Error("link check failed", "error", err.Error(), slog.Int("checked", checked))
+			continue
+		}
+		log.Info("link check completed", slog.Int("checked", checked), slog.Int("dead", dead))
+	}
+}
+
+// CheckAll checks every link once and stores the outcome.
+func (m *Monitor) CheckAll(ctx context.Context) (checked int, dead int, err error) {
+	var lastID int64
+	for {
+		links, err := m.Store.ListURLsAfter(lastID, defaultBatchSize)
+		if err != nil {
+			return checked, dead, err
+		}
+
+		for _, link := range links {
+			if err := ctx.Err(); err != nil {
+				return checked, dead, err
+			}
+
+			status, _ := m.Checker.Status(ctx, link.URL)
+			if err := m.Store.SetLinkStatus(link.Domain, link.Alias, status); err != nil {
+				return checked, dead, err
+			}
+
+			checked++
+			if status.Dead {
+				dead++
+			}
+			lastID = link.ID
+		}
+
+		if len(links) < defaultBatchSize {
+			return checked, dead, nil
+		}
+	}
+}
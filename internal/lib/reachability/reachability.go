@@ -0,0 +1,153 @@
+// Package reachability verifies that link targets respond.
+package reachability
+
+import (
+	"RestApi/internal/lib/urlpolicy"
+	"RestApi/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultTimeout      = 5 * time.Second
+	DefaultMaxRedirects = 5
+
+	userAgent = "url-shortener-link-checker/1.0"
+)
+
+var (
+	ErrPrivateAddress   = errors.New("target resolves to a private address")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrBadScheme        = errors.New("redirect to an unsupported scheme")
+)
+
+type Config struct {
+	Timeout      time.Duration
+	MaxRedirects int
+	// AllowPrivate disables the SSRF protection. Only meant for tests.
+	AllowPrivate bool
+}
+
+type Result struct {
+	// FinalURL is the URL the last redirect led to.
+	FinalURL   string
+	StatusCode int
+}
+
+// Dead reports whether the target answered with a client or server error.
+func (r Result) Dead() bool {
+	return r.StatusCode >= http.StatusBadRequest
+}
+
+type Checker struct {
+	client *http.Client
+}
+
+func New(cfg Config) *Checker {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxRedirects <= 0 {
+		cfg.MaxRedirects = DefaultMaxRedirects
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivate {
+		// Checked on the resolved address right before connecting, so
+		// DNS rebinding cannot sneak a private address past the check.
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || urlpolicy.IsPrivate(addr) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		// Never go through an environment proxy, it would bypass the
+		// address check above.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.Timeout,
+		ResponseHeaderTimeout: cfg.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &Checker{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > cfg.MaxRedirects {
+					return ErrTooManyRedirects
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return ErrBadScheme
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// Check requests rawURL with HEAD, falling back to GET for servers that
+// do not support HEAD. An error means the target could not be reached at
+// all; HTTP error statuses are reported through Result.
+func (c *Checker) Check(ctx context.Context, rawURL string) (Result, error) {
+	const op = "lib.reachability.Check"
+
+	res, err := c.do(ctx, http.MethodHead, rawURL)
+	if err == nil && (res.StatusCode == http.StatusMethodNotAllowed ||
+		res.StatusCode == http.StatusNotImplemented) {
+		res, err = c.do(ctx, http.MethodGet, rawURL)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+func (c *Checker) do(ctx context.Context, method, rawURL string) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	_ = resp.Body.Close()
+
+	return Result{
+		FinalURL:   resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+	}, nil
+}
+
+// Status checks rawURL and converts the outcome into a storage status.
+// Unreachable targets are reported as dead together with the error.
+func (c *Checker) Status(ctx context.Context, rawURL string) (storage.LinkStatus, error) {
+	res, err := c.Check(ctx, rawURL)
+
+	return storage.LinkStatus{
+		ResolvedURL: res.FinalURL,
+		StatusCode:  res.StatusCode,
+		Dead:        err != nil || res.Dead(),
+		CheckedAt:   time.Now().UTC(),
+	}, err
+}
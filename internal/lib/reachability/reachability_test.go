@@ -0,0 +1,150 @@
+package reachability_test
+
+import (
+	"RestApi/internal/lib/reachability"
+	"RestApi/internal/storage"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		_, _ = fmt.Sscanf(r.URL.Path, "/redirect/%d", &n)
+		if n == 0 {
+			http.Redirect(w, r, "/ok", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/redirect/%d", n-1), http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestChecker(t *testing.T) {
+	srv := newServer(t)
+	c := reachability.New(reachability.Config{
+		Timeout:      100 * time.Millisecond,
+		MaxRedirects: 3,
+		AllowPrivate: true,
+	})
+
+	cases := []struct {
+		path     string
+		status   int
+		finalURL string
+		dead     bool
+		err      error
+	}{
+		{path: "/ok", status: http.StatusOK, finalURL: "/ok"},
+		{path: "/missing", status: http.StatusNotFound, finalURL: "/missing", dead: true},
+		{path: "/no-head", status: http.StatusOK, finalURL: "/no-head"},
+		{path: "/redirect/2", status: http.StatusOK, finalURL: "/ok"},
+		{path: "/redirect/5", err: reachability.ErrTooManyRedirects},
+		{path: "/slow", err: context.DeadlineExceeded},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			res, err := c.Check(context.Background(), srv.URL+tc.path)
+			if tc.err != nil {
+				require.Error(t, err)
+				if tc.err != context.DeadlineExceeded {
+					require.ErrorIs(t, err, tc.err)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.status, res.StatusCode)
+			require.Equal(t, srv.URL+tc.finalURL, res.FinalURL)
+			require.Equal(t, tc.dead, res.Dead())
+		})
+	}
+}
+
+func TestChecker_BlocksPrivateAddresses(t *testing.T) {
+	srv := newServer(t)
+	c := reachability.New(reachability.Config{})
+
+	status, err := c.Status(context.Background(), srv.URL+"/ok")
+	require.ErrorIs(t, err, reachability.ErrPrivateAddress)
+	require.True(t, status.Dead)
+	require.False(t, status.CheckedAt.IsZero())
+}
+
+type memStore struct {
+	links  []storage.Link
+	status map[string]storage.LinkStatus
+}
+
+func (s *memStore) ListURLsAfter(afterID int64, limit int) ([]storage.Link, error) {
+	var out []storage.Link
+	for _, l := range s.links {
+		if l.ID > afterID && len(out) < limit {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) SetLinkStatus(_, alias string, status storage.LinkStatus) error {
+	s.status[alias] = status
+	return nil
+}
+
+func TestMonitor_CheckAll(t *testing.T) {
+	srv := newServer(t)
+	store := &memStore{status: map[string]storage.LinkStatus{}}
+	for i := 1; i <= 150; i++ {
+		path := "/ok"
+		if i%50 == 0 {
+			path = "/missing"
+		}
+		store.links = append(store.links, storage.Link{
+			ID:    int64(i),
+			Alias: fmt.Sprintf("alias%d", i),
+			URL:   srv.URL + path,
+		})
+	}
+
+	m := &reachability.Monitor{
+		Checker: reachability.New(reachability.Config{AllowPrivate: true}),
+		Store:   store,
+		Log:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	checked, dead, err := m.CheckAll(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 150, checked)
+	require.Equal(t, 3, dead)
+	require.Len(t, store.status, 150)
+	require.True(t, store.status["alias50"].Dead)
+	require.Equal(t, http.StatusNotFound, store.status["alias50"].StatusCode)
+	require.False(t, store.status["alias1"].Dead)
+}
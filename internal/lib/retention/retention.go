@@ -0,0 +1,73 @@
+// Package retention purges deleted links once they can no longer be
+// restored.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	DefaultRetention = 30 * 24 * time.Hour
+	DefaultInterval  = time.Hour
+)
+
+type Store interface {
+	PurgeDeleted(before time.Time) (int64, error)
+}
+
+// Purger periodically removes links that were deleted more than Retention
+// ago. Until then they can be restored and their aliases stay taken.
+type Purger struct {
+	Store     Store
+	Log       *slog.Logger
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+// Run purges once right away and then every Interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	log := p.Log.With(slog.String("component", "retention/purger"))
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		purged, err := p.Purge(time.Now())
+		if err != nil {
+			log.Error("purge failed", "error", err.Error())
+		} else if purged > 0 {
+			log.Info("deleted links purged", slog.Int64("purged", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Purge removes the links deleted more than Retention before now.
+func (p *Purger) Purge(now time.Time) (int64, error) {
+	const op = "lib.retention.Purge"
+
+	retention := p.Retention
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+
+	purged, err := p.Store.PurgeDeleted(now.Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,74 @@
+package retention_test
+
+import (
+	"RestApi/internal/lib/retention"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	mu      sync.Mutex
+	cutoffs []time.Time
+	err     error
+}
+
+func (s *fakeStore) PurgeDeleted(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cutoffs = append(s.cutoffs, before)
+
+	return 3, s.err
+}
+
+func (s *fakeStore) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.cutoffs)
+}
+
+func TestPurge(t *testing.T) {
+	store := &fakeStore{}
+	p := &retention.Purger{Store: store, Retention: 7 * 24 * time.Hour}
+
+	now := time.Date(2025, 3, 8, 12, 0, 0, 0, time.UTC)
+	purged, err := p.Purge(now)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), purged)
+	require.Equal(t, time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), store.cutoffs[0])
+
+	p.Retention = 0
+	_, err = p.Purge(now)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(-retention.DefaultRetention), store.cutoffs[1])
+
+	store.err = errors.New("database is locked")
+	_, err = p.Purge(now)
+	require.ErrorIs(t, err, store.err)
+}
+
+func TestRun(t *testing.T) {
+	store := &fakeStore{}
+	p := &retention.Purger{
+		Store:    store,
+		Log:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Interval: 10 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return store.calls() >= 2 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+}
@@ -0,0 +1,152 @@
+// Package shorturl builds the public URLs short links are shared as.
+package shorturl
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrInvalidBaseURL = errors.New("base url must be an absolute http or https url without query")
+	ErrInvalidDomain  = errors.New("invalid domain")
+)
+
+// Builder joins aliases onto the canonical base URL or onto one of the
+// custom domains the shortener is also served from.
+type Builder struct {
+	base    *url.URL
+	domains map[string]struct{}
+}
+
+// New returns a builder for baseURL, e.g. "https://sho.rt". Without a
+// base URL the host a request was sent to is used. domains are further
+// hosts links may be shared on; they use the scheme of the base URL.
+func New(baseURL string, domains []string) (*Builder, error) {
+	const op = "lib.shorturl.New"
+
+	b := &Builder{domains: make(map[string]struct{}, len(domains))}
+
+	if baseURL != "" {
+		u, err := url.Parse(baseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidBaseURL)
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = ""
+		b.base = u
+	}
+
+	for _, d := range domains {
+		host := NormalizeHost(d)
+		if host == "" || strings.ContainsAny(d, "/@?# ") {
+			return nil, fmt.Errorf("%s: %w: %q", op, ErrInvalidDomain, d)
+		}
+		b.domains[host] = struct{}{}
+	}
+
+	return b, nil
+}
+
+// Default returns a builder that answers with the host of the request.
+func Default() *Builder {
+	return &Builder{}
+}
+
+// Known reports whether domain is one of the builder's custom domains.
+func (b *Builder) Known(domain string) bool {
+	_, ok := b.domains[NormalizeHost(domain)]
+
+	return ok
+}
+
+// Domain returns the custom domain r was sent to, empty for any other
+// host.
+func (b *Builder) Domain(r *http.Request) string {
+	if host := NormalizeHost(r.Host); b.Known(host) {
+		return host
+	}
+
+	return ""
+}
+
+// Resolve returns the domain a request managing links acts on: requested
+// when set, otherwise the custom domain r was sent to. ok is false when
+// requested is not one of the builder's custom domains.
+func (b *Builder) Resolve(r *http.Request, requested string) (domain string, ok bool) {
+	if requested == "" {
+		return b.Domain(r), true
+	}
+	if !b.Known(requested) {
+		return "", false
+	}
+
+	return NormalizeHost(requested), true
+}
+
+// Hosts returns the host of the base URL and the custom domains, the
+// hosts targets must not point back to.
+func (b *Builder) Hosts() []string {
+	hosts := make([]string, 0, len(b.domains)+1)
+	if b.base != nil {
+		hosts = append(hosts, b.base.Hostname())
+	}
+	for d := range b.domains {
+		hosts = append(hosts, d)
+	}
+
+	return hosts
+}
+
+// URL returns the short URL of alias for r. Requests sent to a custom
+// domain get a URL on that domain, all others one on the base URL.
+func (b *Builder) URL(r *http.Request, alias string) string {
+	if domain := b.Domain(r); domain != "" {
+		return b.OnDomain(domain, alias)
+	}
+	if b.base != nil {
+		return b.join(*b.base, alias)
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return b.join(url.URL{Scheme: scheme, Host: r.Host}, alias)
+}
+
+// OnDomain returns the short URL of alias on domain, which callers check
+// with Known first. An empty domain means the base URL.
+func (b *Builder) OnDomain(domain, alias string) string {
+	if domain == "" && b.base != nil {
+		return b.join(*b.base, alias)
+	}
+
+	scheme := "https"
+	if b.base != nil {
+		scheme = b.base.Scheme
+	}
+
+	return b.join(url.URL{Scheme: scheme, Host: NormalizeHost(domain)}, alias)
+}
+
+func (b *Builder) join(u url.URL, alias string) string {
+	u.Path += "/" + alias
+
+	return u.String()
+}
+
+// NormalizeHost lowercases host and drops a port and trailing dot, the
+// form domains are stored in.
+func NormalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
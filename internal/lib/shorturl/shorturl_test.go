@@ -0,0 +1,73 @@
+package shorturl_test
+
+import (
+	"RestApi/internal/lib/shorturl"
+	"crypto/tls"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	b, err := shorturl.New("https://sho.rt/s/", []string{"Brand.example", "go.example:443"})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/url/get-url", nil)
+	r.Host = "api.internal:8082"
+	require.Equal(t, "https://sho.rt/s/abc", b.URL(r, "abc"))
+
+	r.Host = "brand.example"
+	require.Equal(t, "https://brand.example/abc", b.URL(r, "abc"))
+
+	require.True(t, b.Known("GO.example"))
+	require.False(t, b.Known("other.example"))
+	require.Equal(t, "https://go.example/a%20b", b.OnDomain("go.example", "a b"))
+	require.Equal(t, "https://sho.rt/s/abc", b.OnDomain("", "abc"))
+	require.ElementsMatch(t, []string{"sho.rt", "brand.example", "go.example"}, b.Hosts())
+}
+
+func TestResolve(t *testing.T) {
+	b, err := shorturl.New("https://sho.rt", []string{"brand.example"})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/url/get-url", nil)
+	r.Host = "api.internal:8082"
+
+	domain, ok := b.Resolve(r, "")
+	require.True(t, ok)
+	require.Empty(t, domain)
+
+	domain, ok = b.Resolve(r, "Brand.example")
+	require.True(t, ok)
+	require.Equal(t, "brand.example", domain)
+
+	_, ok = b.Resolve(r, "other.example")
+	require.False(t, ok)
+
+	r.Host = "brand.example"
+	domain, ok = b.Resolve(r, "")
+	require.True(t, ok)
+	require.Equal(t, "brand.example", domain)
+}
+
+func TestDefault(t *testing.T) {
+	b := shorturl.Default()
+
+	r := httptest.NewRequest("GET", "/abc", nil)
+	r.Host = "localhost:8082"
+	require.Equal(t, "http://localhost:8082/abc", b.URL(r, "abc"))
+
+	r.TLS = &tls.ConnectionState{}
+	require.Equal(t, "https://localhost:8082/abc", b.URL(r, "abc"))
+	require.Empty(t, b.Hosts())
+}
+
+func TestNew(t *testing.T) {
+	for _, base := range []string{"sho.rt", "ftp://sho.rt", "https://sho.rt/?a=b", "https://user@sho.rt"} {
+		_, err := shorturl.New(base, nil)
+		require.ErrorIs(t, err, shorturl.ErrInvalidBaseURL, base)
+	}
+
+	_, err := shorturl.New("", []string{"https://brand.example"})
+	require.ErrorIs(t, err, shorturl.ErrInvalidDomain)
+}
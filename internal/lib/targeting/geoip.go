@@ -0,0 +1,43 @@
+package targeting
+
+import (
+	"fmt"
+	"github.com/oschwald/maxminddb-golang"
+	"net/netip"
+)
+
+// GeoIP looks up countries in a local MaxMind database file such as
+// GeoLite2-Country.mmdb.
+type GeoIP struct {
+	db *maxminddb.Reader
+}
+
+func OpenGeoIP(path string) (*GeoIP, error) {
+	const op = "lib.targeting.OpenGeoIP"
+
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &GeoIP{db: db}, nil
+}
+
+func (g *GeoIP) Country(addr netip.Addr) (string, error) {
+	const op = "lib.targeting.GeoIP.Country"
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := g.db.Lookup(addr.AsSlice(), &record); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return record.Country.ISOCode, nil
+}
+
+func (g *GeoIP) Close() error {
+	return g.db.Close()
+}
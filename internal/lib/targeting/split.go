@@ -0,0 +1,76 @@
+package targeting
+
+import (
+	"RestApi/internal/storage"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	variantCookie = "link_variant"
+	// VariantCookieTTL is how long a sticky visitor keeps their variant.
+	VariantCookieTTL = 30 * 24 * time.Hour
+)
+
+// Pick chooses one of targets with probability proportional to its
+// weight. Targets with zero weight are paused and never chosen. It
+// reports false when no target can be chosen.
+func Pick(targets []storage.Target) (storage.Target, bool) {
+	return pick(targets, rand.IntN)
+}
+
+func pick(targets []storage.Target, roll func(n int) int) (storage.Target, bool) {
+	total := 0
+	for _, t := range targets {
+		total += max(t.Weight, 0)
+	}
+	if total == 0 {
+		return storage.Target{}, false
+	}
+
+	n := roll(total)
+	for _, t := range targets {
+		if n < max(t.Weight, 0) {
+			return t, true
+		}
+		n -= max(t.Weight, 0)
+	}
+
+	// Unreachable, n is below the sum of the weights.
+	return storage.Target{}, false
+}
+
+// PickSticky returns the variant remembered in r's cookie while it is
+// still one of the active targets of the link on domain, and picks a
+// fresh one otherwise.
+func PickSticky(targets []storage.Target, r *http.Request, domain string) (storage.Target, bool) {
+	if c, err := r.Cookie(variantCookie); err == nil {
+		variant, cookieDomain, ok := strings.Cut(c.Value, "@")
+		if ok && cookieDomain == domain {
+			for _, t := range targets {
+				if t.Variant == variant && t.Weight > 0 {
+					return t, true
+				}
+			}
+		}
+	}
+
+	return Pick(targets)
+}
+
+// VariantCookie remembers variant for the visitors of alias on domain.
+// Variants are alphanumeric, so the domain is kept after an "@".
+func VariantCookie(r *http.Request, domain, alias, variant string) *http.Cookie {
+	return &http.Cookie{
+		Name:     variantCookie,
+		Value:    variant + "@" + domain,
+		Path:     "/" + url.PathEscape(alias),
+		MaxAge:   int(VariantCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
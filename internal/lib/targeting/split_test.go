@@ -0,0 +1,76 @@
+package targeting_test
+
+import (
+	"RestApi/internal/lib/targeting"
+	"RestApi/internal/storage"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPick(t *testing.T) {
+	targets := []storage.Target{
+		{Variant: "a", Weight: 3},
+		{Variant: "paused", Weight: 0},
+		{Variant: "b", Weight: 1},
+	}
+
+	counts := map[string]int{}
+	for range 10000 {
+		target, ok := targeting.Pick(targets)
+		require.True(t, ok)
+		counts[target.Variant]++
+	}
+
+	require.Zero(t, counts["paused"])
+	require.InDelta(t, 7500, counts["a"], 400)
+	require.InDelta(t, 2500, counts["b"], 400)
+
+	_, ok := targeting.Pick([]storage.Target{{Variant: "a"}})
+	require.False(t, ok)
+	_, ok = targeting.Pick(nil)
+	require.False(t, ok)
+}
+
+func TestPickSticky(t *testing.T) {
+	targets := []storage.Target{
+		{Variant: "a", Weight: 1},
+		{Variant: "b", Weight: 1000},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/promo", nil)
+	cookie := targeting.VariantCookie(r, "brand.example", "promo", "a")
+	require.Equal(t, "/promo", cookie.Path)
+	r.AddCookie(cookie)
+
+	for range 100 {
+		target, ok := targeting.PickSticky(targets, r, "brand.example")
+		require.True(t, ok)
+		require.Equal(t, "a", target.Variant)
+	}
+
+	// The same alias on another domain is another link.
+	target, ok := targeting.PickSticky(targets, r, "")
+	require.True(t, ok)
+	require.Equal(t, "b", target.Variant)
+
+	// A paused variant is not kept.
+	targets[0].Weight = 0
+	target, ok = targeting.PickSticky(targets, r, "brand.example")
+	require.True(t, ok)
+	require.Equal(t, "b", target.Variant)
+}
+
+func TestPickSticky_UnknownVariant(t *testing.T) {
+	targets := []storage.Target{{Variant: "b", Weight: 1}}
+
+	for _, value := range []string{"a@", "a", "", "@", "c@"} {
+		r := httptest.NewRequest(http.MethodGet, "/promo", nil)
+		r.AddCookie(&http.Cookie{Name: "link_variant", Value: value})
+
+		target, ok := targeting.PickSticky(append(targets, storage.Target{Variant: "c"}), r, "")
+		require.True(t, ok)
+		require.Equal(t, "b", target.Variant, value)
+	}
+}
@@ -0,0 +1,238 @@
+// Package targeting picks the target of a link for a visitor from the
+// link's conditional rules.
+package targeting
+
+import (
+	"RestApi/internal/storage"
+	"errors"
+	"golang.org/x/text/language"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+const (
+	DeviceIOS     = "ios"
+	DeviceAndroid = "android"
+	DeviceDesktop = "desktop"
+)
+
+var (
+	ErrInvalidDevice   = errors.New("device must be ios, android or desktop")
+	ErrInvalidLanguage = errors.New("language is not a valid BCP 47 tag")
+	ErrInvalidCountry  = errors.New("country is not an ISO 3166-1 alpha-2 code")
+	ErrInvalidHours    = errors.New("hours must look like 09:00-17:30")
+)
+
+// CountryLocator resolves the ISO country code of an address. It
+// returns an empty code for unknown addresses.
+type CountryLocator interface {
+	Country(addr netip.Addr) (string, error)
+}
+
+// Visitor holds what rules are matched against.
+type Visitor struct {
+	Device string
+	// Language is the visitor's preferred language, if any.
+	Language string
+	Country  string
+	// Time is the visit time in the evaluator's time zone.
+	Time time.Time
+}
+
+type Config struct {
+	// GeoIP resolves countries. Country rules never match without it.
+	GeoIP CountryLocator
+	// Location is the time zone hours are evaluated in, UTC when nil.
+	Location *time.Location
+}
+
+type Evaluator struct {
+	geo CountryLocator
+	loc *time.Location
+	now func() time.Time
+}
+
+func New(cfg Config) *Evaluator {
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+
+	return &Evaluator{geo: cfg.GeoIP, loc: cfg.Location, now: time.Now}
+}
+
+// Target returns the URL of the first rule of link that matches the
+// visitor of r. It reports false when no rule matches.
+func (e *Evaluator) Target(link storage.Link, r *http.Request) (string, bool) {
+	if len(link.Rules) == 0 {
+		return "", false
+	}
+
+	v := e.Visitor(r, needsCountry(link.Rules))
+	for _, rule := range link.Rules {
+		if Match(rule, v) {
+			return rule.URL, true
+		}
+	}
+
+	return "", false
+}
+
+// Visitor describes the client of r. The GeoIP lookup is skipped unless
+// withCountry is set.
+func (e *Evaluator) Visitor(r *http.Request, withCountry bool) Visitor {
+	v := Visitor{
+		Device: DeviceClass(r.UserAgent()),
+		Time:   e.now().In(e.loc),
+	}
+
+	if tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language")); err == nil && len(tags) > 0 {
+		v.Language = tags[0].String()
+	}
+
+	if withCountry && e.geo != nil {
+		if addr, err := remoteAddr(r); err == nil {
+			v.Country, _ = e.geo.Country(addr)
+		}
+	}
+
+	return v
+}
+
+func needsCountry(rules []storage.Rule) bool {
+	for _, rule := range rules {
+		if rule.Country != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func remoteAddr(r *http.Request) (netip.Addr, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	return addr.Unmap(), nil
+}
+
+// DeviceClass classifies a User-Agent as ios, android or desktop.
+func DeviceClass(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPhone"),
+		strings.Contains(userAgent, "iPad"),
+		strings.Contains(userAgent, "iPod"):
+		return DeviceIOS
+	case strings.Contains(userAgent, "Android"):
+		return DeviceAndroid
+	default:
+		return DeviceDesktop
+	}
+}
+
+// Match reports whether every condition set on rule holds for v.
+func Match(rule storage.Rule, v Visitor) bool {
+	if rule.Device != "" && rule.Device != v.Device {
+		return false
+	}
+	if rule.Language != "" && !matchLanguage(rule.Language, v.Language) {
+		return false
+	}
+	if rule.Country != "" && !strings.EqualFold(rule.Country, v.Country) {
+		return false
+	}
+	if rule.Hours != "" {
+		from, to, err := parseHours(rule.Hours)
+		if err != nil || !inHours(from, to, v.Time) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchLanguage matches "de" against "de" and "de-AT", but "de-AT" only
+// against "de-AT".
+func matchLanguage(want, got string) bool {
+	want, got = strings.ToLower(want), strings.ToLower(got)
+
+	return got == want || strings.HasPrefix(got, want+"-")
+}
+
+// Validate checks the conditions and target of rule.
+func Validate(rule storage.Rule) error {
+	switch rule.Device {
+	case "", DeviceIOS, DeviceAndroid, DeviceDesktop:
+	default:
+		return ErrInvalidDevice
+	}
+
+	if rule.Language != "" {
+		if _, err := language.Parse(rule.Language); err != nil {
+			return ErrInvalidLanguage
+		}
+	}
+
+	if rule.Country != "" {
+		region, err := language.ParseRegion(rule.Country)
+		if err != nil || len(rule.Country) != 2 || !region.IsCountry() {
+			return ErrInvalidCountry
+		}
+	}
+
+	if rule.Hours != "" {
+		if _, _, err := parseHours(rule.Hours); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseHours parses "15:04-15:04" into minutes since midnight.
+func parseHours(hours string) (from, to int, err error) {
+	start, end, ok := strings.Cut(hours, "-")
+	if !ok {
+		return 0, 0, ErrInvalidHours
+	}
+
+	if from, err = parseClock(start); err != nil {
+		return 0, 0, err
+	}
+	if to, err = parseClock(end); err != nil {
+		return 0, 0, err
+	}
+	if from == to {
+		return 0, 0, ErrInvalidHours
+	}
+
+	return from, to, nil
+}
+
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, ErrInvalidHours
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func inHours(from, to int, t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if from < to {
+		return m >= from && m < to
+	}
+
+	// The range wraps past midnight.
+	return m >= from || m < to
+}
@@ -0,0 +1,114 @@
+package targeting_test
+
+import (
+	"RestApi/internal/lib/targeting"
+	"RestApi/internal/storage"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile"
+	desktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0"
+)
+
+type countries map[string]string
+
+func (c countries) Country(addr netip.Addr) (string, error) {
+	return c[addr.String()], nil
+}
+
+func TestDeviceClass(t *testing.T) {
+	require.Equal(t, targeting.DeviceIOS, targeting.DeviceClass(iPhoneUA))
+	require.Equal(t, targeting.DeviceAndroid, targeting.DeviceClass(androidUA))
+	require.Equal(t, targeting.DeviceDesktop, targeting.DeviceClass(desktopUA))
+	require.Equal(t, targeting.DeviceDesktop, targeting.DeviceClass(""))
+}
+
+func TestMatch(t *testing.T) {
+	noon := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	v := targeting.Visitor{Device: targeting.DeviceIOS, Language: "de-AT", Country: "AT", Time: noon}
+
+	cases := []struct {
+		name  string
+		rule  storage.Rule
+		match bool
+	}{
+		{name: "no conditions", rule: storage.Rule{}, match: true},
+		{name: "device", rule: storage.Rule{Device: "ios"}, match: true},
+		{name: "other device", rule: storage.Rule{Device: "android"}, match: false},
+		{name: "base language", rule: storage.Rule{Language: "de"}, match: true},
+		{name: "exact language", rule: storage.Rule{Language: "de-AT"}, match: true},
+		{name: "other region", rule: storage.Rule{Language: "de-CH"}, match: false},
+		{name: "country", rule: storage.Rule{Country: "at"}, match: true},
+		{name: "other country", rule: storage.Rule{Country: "DE"}, match: false},
+		{name: "hours", rule: storage.Rule{Hours: "09:00-17:00"}, match: true},
+		{name: "outside hours", rule: storage.Rule{Hours: "13:00-17:00"}, match: false},
+		{name: "hours past midnight", rule: storage.Rule{Hours: "22:00-06:00"}, match: false},
+		{name: "all conditions", rule: storage.Rule{Device: "ios", Language: "de", Country: "AT"}, match: true},
+		{name: "one failing condition", rule: storage.Rule{Device: "ios", Country: "DE"}, match: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.match, targeting.Match(tc.rule, v))
+		})
+	}
+
+	night := v
+	night.Time = time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC)
+	require.True(t, targeting.Match(storage.Rule{Hours: "22:00-06:00"}, night))
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, targeting.Validate(storage.Rule{
+		Device: "android", Language: "pt-BR", Country: "BR", Hours: "08:00-20:00",
+	}))
+	require.ErrorIs(t, targeting.Validate(storage.Rule{Device: "tv"}), targeting.ErrInvalidDevice)
+	require.ErrorIs(t, targeting.Validate(storage.Rule{Language: "not a tag"}), targeting.ErrInvalidLanguage)
+	require.ErrorIs(t, targeting.Validate(storage.Rule{Country: "XYZ"}), targeting.ErrInvalidCountry)
+	require.ErrorIs(t, targeting.Validate(storage.Rule{Country: "EU"}), targeting.ErrInvalidCountry)
+	require.ErrorIs(t, targeting.Validate(storage.Rule{Hours: "9-17"}), targeting.ErrInvalidHours)
+	require.ErrorIs(t, targeting.Validate(storage.Rule{Hours: "10:00-10:00"}), targeting.ErrInvalidHours)
+}
+
+func TestTarget(t *testing.T) {
+	e := targeting.New(targeting.Config{GeoIP: countries{"203.0.113.7": "FR"}})
+
+	link := storage.Link{
+		URL: "https://example.com/app",
+		Rules: []storage.Rule{
+			{Device: "ios", URL: "https://apps.apple.com/app/id1"},
+			{Device: "android", URL: "https://play.google.com/store/apps/details?id=app"},
+			{Country: "FR", URL: "https://example.fr/app"},
+			{Language: "de", URL: "https://example.de/app"},
+		},
+	}
+
+	request := func(ua, lang, addr string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/app", nil)
+		r.Header.Set("User-Agent", ua)
+		r.Header.Set("Accept-Language", lang)
+		r.RemoteAddr = addr
+		return r
+	}
+
+	target := func(r *http.Request) string {
+		url, ok := e.Target(link, r)
+		if !ok {
+			return link.URL
+		}
+		return url
+	}
+
+	require.Equal(t, "https://apps.apple.com/app/id1", target(request(iPhoneUA, "", "198.51.100.1:1234")))
+	require.Equal(t, "https://play.google.com/store/apps/details?id=app", target(request(androidUA, "fr", "203.0.113.7:1234")))
+	require.Equal(t, "https://example.fr/app", target(request(desktopUA, "de", "203.0.113.7:1234")))
+	require.Equal(t, "https://example.de/app", target(request(desktopUA, "en;q=0.5, de-DE", "198.51.100.1:1234")))
+	require.Equal(t, "https://example.com/app", target(request(desktopUA, "en", "198.51.100.1:1234")))
+}
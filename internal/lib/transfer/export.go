@@ -0,0 +1,43 @@
+package transfer
+
+import (
+	"RestApi/internal/storage"
+)
+
+const exportPageSize = 500
+
+// URLLister pages through whole links, timestamps and owner included,
+// in id order.
+type URLLister interface {
+	ListURLsAfter(afterID int64, limit int) ([]storage.Link, error)
+}
+
+// Export writes every stored link to enc page by page, so the whole table
+// is never held in memory. It returns the number of exported links.
+func Export(enc Encoder, lister URLLister) (int, error) {
+	var (
+		count   int
+		afterID int64
+	)
+
+	for {
+		links, err := lister.ListURLsAfter(afterID, exportPageSize)
+		if err != nil {
+			return count, err
+		}
+
+		for _, link := range links {
+			if err := enc.Encode(fromLink(link)); err != nil {
+				return count, err
+			}
+			count++
+			afterID = link.ID
+		}
+
+		if len(links) < exportPageSize {
+			break
+		}
+	}
+
+	return count, enc.Flush()
+}
@@ -0,0 +1,167 @@
+package transfer
+
+import (
+	"RestApi/internal/lib/linkcheck"
+	"RestApi/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ConflictPolicy decides what happens when an imported alias already
+// exists.
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictFail      ConflictPolicy = "fail"
+	ConflictRename    ConflictPolicy = "rename"
+)
+
+// maxRenames bounds the alias-2, alias-3, ... probing of ConflictRename.
+const maxRenames = 100
+
+var (
+	ErrUnknownPolicy = errors.New("unknown conflict policy")
+	ErrConflict      = errors.New("alias already exists")
+)
+
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictSkip, ConflictOverwrite, ConflictFail, ConflictRename:
+		return p, nil
+	case "":
+		return ConflictFail, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownPolicy, s)
+	}
+}
+
+type URLImporter interface {
+	SaveLink(link storage.Link) (int64, error)
+	UpdateURL(domain, alias string, urlToSave, originalURL string) error
+}
+
+type Result struct {
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+	Renamed     int `json:"renamed"`
+	// Failed counts the records that were rejected, see Errors.
+	Failed int        `json:"failed"`
+	Errors []RowError `json:"errors,omitempty"`
+}
+
+// RowError tells why the record-th record, counting from 1, was not
+// imported.
+type RowError struct {
+	Record int    `json:"record"`
+	Alias  string `json:"alias,omitempty"`
+	Error  string `json:"error"`
+}
+
+// Import reads every record from dec and stores it. Records are put
+// through check as links created through the API are; those it rejects
+// are reported in the result and the import goes on. A nil check stores
+// records as they are. Records stored before an error are kept: the
+// import is not transactional.
+func Import(ctx context.Context, dec Decoder, importer URLImporter, policy ConflictPolicy, check *linkcheck.Checker) (Result, error) {
+	var res Result
+
+	for line := 1; ; line++ {
+		rec, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return res, fmt.Errorf("record %d: %w", line, err)
+		}
+
+		link := rec.Link()
+		if check != nil {
+			if link, err = check.Link(ctx, link); err != nil {
+				res.reject(line, rec.Alias, err)
+				continue
+			}
+		}
+
+		var rej rejection
+		if err := importLink(link, importer, policy, check, &res); errors.As(err, &rej) {
+			res.reject(line, rec.Alias, rej.err)
+		} else if err != nil {
+			return res, fmt.Errorf("record %d: %w", line, err)
+		}
+	}
+}
+
+// rejection wraps errors that reject a single record rather than abort
+// the import.
+type rejection struct {
+	err error
+}
+
+func (r rejection) Error() string {
+	return r.err.Error()
+}
+
+func (res *Result) reject(line int, alias string, err error) {
+	res.Failed++
+	res.Errors = append(res.Errors, RowError{Record: line, Alias: alias, Error: err.Error()})
+}
+
+func importLink(link storage.Link, importer URLImporter, policy ConflictPolicy, check *linkcheck.Checker, res *Result) error {
+	_, err := importer.SaveLink(link)
+	if err == nil {
+		res.Created++
+		return nil
+	}
+	if !errors.Is(err, storage.ErrURLExists) && !errors.Is(err, storage.ErrAliasQuarantined) {
+		return err
+	}
+
+	switch policy {
+	case ConflictSkip:
+		res.Skipped++
+		return nil
+	case ConflictOverwrite:
+		// Deleted links keep their alias until purged and are not
+		// brought back by overwriting them.
+		if errors.Is(err, storage.ErrAliasQuarantined) {
+			return rejection{err}
+		}
+		if err := importer.UpdateURL(link.Domain, link.Alias, link.URL, link.OriginalURL); err != nil {
+			return err
+		}
+		res.Overwritten++
+		return nil
+	case ConflictRename:
+		base := link.Alias
+		for i := 2; i <= maxRenames; i++ {
+			link.Alias = fmt.Sprintf("%s-%d", base, i)
+			if check != nil {
+				if _, err := check.Alias(link.Alias); err != nil {
+					return rejection{err}
+				}
+				if err := check.Hold(link.Domain, link.Alias, ""); errors.Is(err, storage.ErrAliasHeld) {
+					continue
+				} else if err != nil {
+					return err
+				}
+			}
+
+			_, err := importer.SaveLink(link)
+			if err == nil {
+				res.Renamed++
+				return nil
+			}
+			if !errors.Is(err, storage.ErrURLExists) && !errors.Is(err, storage.ErrAliasQuarantined) {
+				return err
+			}
+		}
+		return fmt.Errorf("%w: %q: no free alias to rename to", ErrConflict, base)
+	default:
+		return fmt.Errorf("%w: %q", ErrConflict, link.Alias)
+	}
+}
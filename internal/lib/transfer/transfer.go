@@ -0,0 +1,283 @@
+// Package transfer exports and imports links as JSON Lines or CSV.
+package transfer
+
+import (
+	"RestApi/internal/storage"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrInvalidRecord = errors.New("invalid record")
+)
+
+// Record is a single exported link. Domain is empty for the default
+// domain; CreatedAt, CreatedBy and NotAfter are carried over so an export
+// can be imported again without losing them.
+type Record struct {
+	Alias     string     `json:"alias"`
+	URL       string     `json:"url"`
+	Domain    string     `json:"domain,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+}
+
+// csvHeader lists the CSV columns in the order they are written. Files
+// may leave out or reorder any but the leading alias and url, so ones
+// exported before the other columns existed still import.
+var csvHeader = []string{"alias", "url", "domain", "created_at", "created_by", "not_after"}
+
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv"
+	}
+
+	return "application/x-ndjson"
+}
+
+type Encoder interface {
+	Encode(rec Record) error
+	Flush() error
+}
+
+type Decoder interface {
+	// Decode returns io.EOF once the input is exhausted.
+	Decode() (Record, error)
+}
+
+func NewEncoder(w io.Writer, format string) (Encoder, error) {
+	switch format {
+	case FormatJSONL:
+		bw := bufio.NewWriter(w)
+		return &jsonlEncoder{w: bw, enc: json.NewEncoder(bw)}, nil
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func NewDecoder(r io.Reader, format string) (Decoder, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonlDecoder{dec: json.NewDecoder(r)}, nil
+	case FormatCSV:
+		return &csvDecoder{r: csv.NewReader(r)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+type jsonlEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (e *jsonlEncoder) Encode(rec Record) error {
+	return e.enc.Encode(rec)
+}
+
+func (e *jsonlEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+type csvEncoder struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (e *csvEncoder) Encode(rec Record) error {
+	if !e.wroteHeader {
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+		e.wroteHeader = true
+	}
+
+	return e.w.Write([]string{
+		rec.Alias,
+		rec.URL,
+		rec.Domain,
+		formatTime(rec.CreatedAt),
+		rec.CreatedBy,
+		formatTime(rec.NotAfter),
+	})
+}
+
+func (e *csvEncoder) Flush() error {
+	if !e.wroteHeader {
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+		e.wroteHeader = true
+	}
+	e.w.Flush()
+
+	return e.w.Error()
+}
+
+type jsonlDecoder struct {
+	dec *json.Decoder
+}
+
+func (d *jsonlDecoder) Decode() (Record, error) {
+	var rec Record
+	if err := d.dec.Decode(&rec); err != nil {
+		if errors.Is(err, io.EOF) {
+			return rec, io.EOF
+		}
+		return rec, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+	}
+
+	return rec, validate(rec)
+}
+
+type csvDecoder struct {
+	r *csv.Reader
+	// columns maps the names of csvHeader to their index in the file.
+	columns map[string]int
+}
+
+func (d *csvDecoder) Decode() (Record, error) {
+	if d.columns == nil {
+		header, err := d.r.Read()
+		if err != nil {
+			return Record{}, csvErr(err)
+		}
+		if d.columns, err = csvColumns(header); err != nil {
+			return Record{}, err
+		}
+	}
+
+	row, err := d.r.Read()
+	if err != nil {
+		return Record{}, csvErr(err)
+	}
+
+	col := func(name string) string {
+		if i, ok := d.columns[name]; ok {
+			return row[i]
+		}
+		return ""
+	}
+
+	rec := Record{
+		Alias:     col("alias"),
+		URL:       col("url"),
+		Domain:    col("domain"),
+		CreatedBy: col("created_by"),
+	}
+	if rec.CreatedAt, err = parseTime(col("created_at")); err != nil {
+		return rec, fmt.Errorf("%w: created_at: %v", ErrInvalidRecord, err)
+	}
+	if rec.NotAfter, err = parseTime(col("not_after")); err != nil {
+		return rec, fmt.Errorf("%w: not_after: %v", ErrInvalidRecord, err)
+	}
+
+	return rec, validate(rec)
+}
+
+// csvColumns checks header, which starts with alias and url, and maps
+// its column names to their index.
+func csvColumns(header []string) (map[string]int, error) {
+	if len(header) < 2 || header[0] != "alias" || header[1] != "url" {
+		return nil, fmt.Errorf("%w: unexpected csv header %v", ErrInvalidRecord, header)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if _, dup := columns[name]; dup || !slices.Contains(csvHeader, name) {
+			return nil, fmt.Errorf("%w: unexpected csv header %v", ErrInvalidRecord, header)
+		}
+		columns[name] = i
+	}
+
+	return columns, nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func csvErr(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+
+	return fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+}
+
+func validate(rec Record) error {
+	if rec.Alias == "" || rec.URL == "" {
+		return fmt.Errorf("%w: alias and url are required", ErrInvalidRecord)
+	}
+
+	return nil
+}
+
+func fromLink(link storage.Link) Record {
+	return Record{
+		Alias:     link.Alias,
+		URL:       link.URL,
+		Domain:    link.Domain,
+		CreatedAt: timeOrNil(link.CreatedAt),
+		CreatedBy: link.CreatedBy,
+		NotAfter:  timeOrNil(link.NotAfter),
+	}
+}
+
+// Link returns the link rec describes.
+func (rec Record) Link() storage.Link {
+	link := storage.Link{
+		Alias:     rec.Alias,
+		URL:       rec.URL,
+		Domain:    rec.Domain,
+		CreatedBy: rec.CreatedBy,
+	}
+	if rec.CreatedAt != nil {
+		link.CreatedAt = rec.CreatedAt.UTC()
+	}
+	if rec.NotAfter != nil {
+		link.NotAfter = rec.NotAfter.UTC()
+	}
+
+	return link
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,375 @@
+package transfer_test
+
+import (
+	"RestApi/internal/lib/alias"
+	"RestApi/internal/lib/linkcheck"
+	"RestApi/internal/lib/transfer"
+	"RestApi/internal/lib/urlnorm"
+	"RestApi/internal/storage"
+	"RestApi/internal/storage/sqllite"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memStore is an in-memory stand-in for the storage backends.
+type memStore struct {
+	links []storage.Link
+	held  []string
+}
+
+func (s *memStore) SaveURL(urlToSave string, alias string) (int64, error) {
+	return s.SaveLink(storage.Link{Alias: alias, URL: urlToSave})
+}
+
+func (s *memStore) SaveLink(link storage.Link) (int64, error) {
+	for _, l := range s.links {
+		if l.Domain == link.Domain && l.Alias == link.Alias && !l.DeletedAt.IsZero() {
+			return 0, storage.ErrAliasQuarantined
+		}
+		if l.Domain == link.Domain && l.Alias == link.Alias {
+			return 0, storage.ErrURLExists
+		}
+	}
+	link.ID = int64(len(s.links) + 1)
+	s.links = append(s.links, link)
+
+	return link.ID, nil
+}
+
+func (s *memStore) UpdateURL(domain, alias string, urlToSave, _ string) error {
+	for i := range s.links {
+		if s.links[i].Domain == domain && s.links[i].Alias == alias && s.links[i].DeletedAt.IsZero() {
+			s.links[i].URL = urlToSave
+			return nil
+		}
+	}
+
+	return storage.ErrURLNotFound
+}
+
+// GetReservation holds every alias in s.held.
+func (s *memStore) GetReservation(_, alias string) (storage.Reservation, error) {
+	if slices.Contains(s.held, alias) {
+		return storage.Reservation{Alias: alias, Token: "token"}, nil
+	}
+
+	return storage.Reservation{}, storage.ErrReservationNotFound
+}
+
+// ListURLsAfter relies on ids being positions in s.links plus one.
+func (s *memStore) ListURLsAfter(afterID int64, limit int) ([]storage.Link, error) {
+	if afterID >= int64(len(s.links)) {
+		return nil, nil
+	}
+
+	return s.links[afterID:min(int(afterID)+limit, len(s.links))], nil
+}
+
+func (s *memStore) urls() map[string]string {
+	res := make(map[string]string, len(s.links))
+	for _, link := range s.links {
+		key := link.Alias
+		if link.Domain != "" {
+			key = link.Domain + "/" + key
+		}
+		res[key] = link.URL
+	}
+
+	return res
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{transfer.FormatJSONL, transfer.FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			src := &memStore{}
+			for i := 0; i < 1200; i++ {
+				_, err := src.SaveURL("https://example.com/?q=a,\"b\"&n="+strings.Repeat("x", i%7), fmt.Sprintf("alias%d", i))
+				require.NoError(t, err)
+			}
+
+			var buf bytes.Buffer
+			enc, err := transfer.NewEncoder(&buf, format)
+			require.NoError(t, err)
+
+			count, err := transfer.Export(enc, src)
+			require.NoError(t, err)
+			require.Equal(t, 1200, count)
+
+			dec, err := transfer.NewDecoder(&buf, format)
+			require.NoError(t, err)
+
+			dst := &memStore{}
+			res, err := transfer.Import(context.Background(), dec, dst, transfer.ConflictFail, nil)
+			require.NoError(t, err)
+			require.Equal(t, transfer.Result{Created: 1200}, res)
+			require.Equal(t, src.urls(), dst.urls())
+		})
+	}
+}
+
+func TestRoundTrip_Fields(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	links := []storage.Link{
+		{Alias: "sale", URL: "https://example.com/sale", CreatedAt: created, CreatedBy: "alice", NotAfter: expires},
+		{Alias: "sale", URL: "https://brand.example/sale", Domain: "brand.example", CreatedBy: "bob"},
+		{Alias: "plain", URL: "https://example.com"},
+	}
+
+	for _, format := range []string{transfer.FormatJSONL, transfer.FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			src := &memStore{}
+			for _, link := range links {
+				_, err := src.SaveLink(link)
+				require.NoError(t, err)
+			}
+
+			var buf bytes.Buffer
+			enc, err := transfer.NewEncoder(&buf, format)
+			require.NoError(t, err)
+			_, err = transfer.Export(enc, src)
+			require.NoError(t, err)
+
+			dec, err := transfer.NewDecoder(&buf, format)
+			require.NoError(t, err)
+
+			dst := &memStore{}
+			res, err := transfer.Import(context.Background(), dec, dst, transfer.ConflictFail, nil)
+			require.NoError(t, err)
+			require.Equal(t, transfer.Result{Created: 3}, res)
+			require.Equal(t, src.links, dst.links)
+		})
+	}
+}
+
+// TestRoundTrip_SQLite exports from and imports into the real backend,
+// whose listing queries decide which fields reach the export.
+func TestRoundTrip_SQLite(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	links := []storage.Link{
+		{Alias: "sale", URL: "https://example.com/sale", CreatedAt: created, CreatedBy: "alice", NotAfter: expires},
+		{Alias: "sale", URL: "https://brand.example/sale", Domain: "brand.example", CreatedAt: created, CreatedBy: "bob"},
+	}
+
+	for _, format := range []string{transfer.FormatJSONL, transfer.FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			src, err := sqllite.New(filepath.Join(t.TempDir(), "src.db"))
+			require.NoError(t, err)
+			defer src.Close()
+			for _, link := range links {
+				_, err := src.SaveLink(link)
+				require.NoError(t, err)
+			}
+
+			var buf bytes.Buffer
+			enc, err := transfer.NewEncoder(&buf, format)
+			require.NoError(t, err)
+			count, err := transfer.Export(enc, src)
+			require.NoError(t, err)
+			require.Equal(t, len(links), count)
+
+			dst, err := sqllite.New(filepath.Join(t.TempDir(), "dst.db"))
+			require.NoError(t, err)
+			defer dst.Close()
+
+			dec, err := transfer.NewDecoder(&buf, format)
+			require.NoError(t, err)
+			res, err := transfer.Import(context.Background(), dec, dst, transfer.ConflictFail, nil)
+			require.NoError(t, err)
+			require.Equal(t, transfer.Result{Created: len(links)}, res)
+
+			for _, want := range links {
+				got, err := dst.GetLink(want.Domain, want.Alias)
+				require.NoError(t, err)
+				require.Equal(t, want.URL, got.URL)
+				require.Equal(t, want.CreatedBy, got.CreatedBy)
+				require.True(t, want.CreatedAt.Equal(got.CreatedAt), got.CreatedAt)
+				require.True(t, want.NotAfter.Equal(got.NotAfter), got.NotAfter)
+			}
+		})
+	}
+}
+
+func TestDecodeCSV_OldHeader(t *testing.T) {
+	dec, err := transfer.NewDecoder(strings.NewReader("alias,url\na,https://example.com/a\n"), transfer.FormatCSV)
+	require.NoError(t, err)
+
+	rec, err := dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, transfer.Record{Alias: "a", URL: "https://example.com/a"}, rec)
+}
+
+func TestImportConflicts(t *testing.T) {
+	const input = `{"alias":"a","url":"https://new.example/a"}
+{"alias":"b","url":"https://new.example/b"}
+`
+
+	cases := []struct {
+		policy  transfer.ConflictPolicy
+		result  transfer.Result
+		urls    map[string]string
+		wantErr error
+	}{
+		{
+			policy: transfer.ConflictSkip,
+			result: transfer.Result{Created: 1, Skipped: 1},
+			urls:   map[string]string{"a": "https://old.example/a", "b": "https://new.example/b"},
+		},
+		{
+			policy: transfer.ConflictOverwrite,
+			result: transfer.Result{Created: 1, Overwritten: 1},
+			urls:   map[string]string{"a": "https://new.example/a", "b": "https://new.example/b"},
+		},
+		{
+			policy: transfer.ConflictRename,
+			result: transfer.Result{Created: 1, Renamed: 1},
+			urls: map[string]string{
+				"a":   "https://old.example/a",
+				"a-2": "https://new.example/a",
+				"b":   "https://new.example/b",
+			},
+		},
+		{
+			policy:  transfer.ConflictFail,
+			urls:    map[string]string{"a": "https://old.example/a"},
+			wantErr: transfer.ErrConflict,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			store := &memStore{}
+			_, err := store.SaveURL("https://old.example/a", "a")
+			require.NoError(t, err)
+
+			dec, err := transfer.NewDecoder(strings.NewReader(input), transfer.FormatJSONL)
+			require.NoError(t, err)
+
+			res, err := transfer.Import(context.Background(), dec, store, tc.policy, nil)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.result, res)
+			require.Equal(t, tc.urls, store.urls())
+		})
+	}
+}
+
+func TestImportChecks(t *testing.T) {
+	const input = `{"alias":"ok","url":"HTTPS://Example.com/a/../b"}
+{"alias":"bad alias","url":"https://example.com"}
+{"alias":"nourl","url":"not a url"}
+{"alias":"held","url":"https://example.com"}
+{"alias":"gone","url":"https://example.com"}
+{"alias":"x","url":"https://example.com","domain":"other.example"}
+{"alias":"taken","url":"https://example.com/new"}
+`
+
+	cases := []struct {
+		policy transfer.ConflictPolicy
+		result transfer.Result
+		urls   map[string]string
+	}{
+		{
+			policy: transfer.ConflictOverwrite,
+			result: transfer.Result{Created: 1, Overwritten: 1},
+			urls: map[string]string{
+				"ok":    "https://example.com/b",
+				"gone":  "https://old.example",
+				"taken": "https://example.com/new",
+			},
+		},
+		{
+			policy: transfer.ConflictRename,
+			result: transfer.Result{Created: 1, Renamed: 2},
+			urls: map[string]string{
+				"ok":      "https://example.com/b",
+				"gone":    "https://old.example",
+				"gone-2":  "https://example.com/",
+				"taken":   "https://old.example",
+				"taken-3": "https://example.com/new",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			store := &memStore{held: []string{"held", "taken-2"}}
+			_, err := store.SaveLink(storage.Link{Alias: "gone", URL: "https://old.example", DeletedAt: time.Now()})
+			require.NoError(t, err)
+			_, err = store.SaveLink(storage.Link{Alias: "taken", URL: "https://old.example"})
+			require.NoError(t, err)
+
+			check := linkcheck.New(linkcheck.Config{
+				Normalizer: urlnorm.New(urlnorm.Options{}),
+				Holds:      store,
+			})
+
+			dec, err := transfer.NewDecoder(strings.NewReader(input), transfer.FormatJSONL)
+			require.NoError(t, err)
+
+			res, err := transfer.Import(context.Background(), dec, store, tc.policy, check)
+			require.NoError(t, err)
+
+			errs := map[string]string{
+				"bad alias": alias.ErrCharset.Error(),
+				"nourl":     linkcheck.ErrInvalidURL.Error(),
+				"held":      storage.ErrAliasHeld.Error(),
+				"x":         linkcheck.ErrUnknownDomain.Error(),
+			}
+			if tc.policy == transfer.ConflictOverwrite {
+				errs["gone"] = storage.ErrAliasQuarantined.Error()
+			}
+			require.Len(t, res.Errors, len(errs))
+			for _, e := range res.Errors {
+				require.Equal(t, errs[e.Alias], e.Error, e.Alias)
+			}
+			res.Errors = nil
+			tc.result.Failed = len(errs)
+			require.Equal(t, tc.result, res)
+			require.Equal(t, tc.urls, store.urls())
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{name: "jsonl garbage", format: transfer.FormatJSONL, input: "{not json"},
+		{name: "jsonl missing url", format: transfer.FormatJSONL, input: `{"alias":"a"}`},
+		{name: "csv wrong header", format: transfer.FormatCSV, input: "url,alias\nx,y\n"},
+		{name: "csv wrong width", format: transfer.FormatCSV, input: "alias,url\nx\n"},
+		{name: "csv unknown column", format: transfer.FormatCSV, input: "alias,url,owner\nx,y,z\n"},
+		{name: "csv bad time", format: transfer.FormatCSV, input: "alias,url,created_at\nx,y,yesterday\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dec, err := transfer.NewDecoder(strings.NewReader(tc.input), tc.format)
+			require.NoError(t, err)
+
+			_, err = dec.Decode()
+			require.ErrorIs(t, err, transfer.ErrInvalidRecord)
+		})
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	p, err := transfer.ParseConflictPolicy("")
+	require.NoError(t, err)
+	require.Equal(t, transfer.ConflictFail, p)
+
+	_, err = transfer.ParseConflictPolicy("merge")
+	require.ErrorIs(t, err, transfer.ErrUnknownPolicy)
+}
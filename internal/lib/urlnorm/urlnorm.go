@@ -0,0 +1,151 @@
+// Package urlnorm canonicalizes URLs so that semantically identical links
+// are stored the same way.
+package urlnorm
+
+import (
+	"errors"
+	"golang.org/x/net/idna"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var ErrInvalidURL = errors.New("url is not valid")
+
+// DefaultTrackingParams are stripped when tracking stripping is enabled
+// and no list is configured. Entries ending in "*" match by prefix.
+var DefaultTrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "yclid", "msclkid", "mc_cid", "mc_eid", "_ga", "_hsenc", "_hsmi",
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+type Options struct {
+	StripTracking  bool
+	TrackingParams []string
+}
+
+type Normalizer struct {
+	stripTracking  bool
+	trackingParams []string
+}
+
+func New(opts Options) *Normalizer {
+	params := opts.TrackingParams
+	if len(params) == 0 {
+		params = DefaultTrackingParams
+	}
+
+	return &Normalizer{
+		stripTracking:  opts.StripTracking,
+		trackingParams: params,
+	}
+}
+
+// Normalize lowercases the scheme and host, converts internationalized
+// host names to punycode, drops the default port, resolves dot segments
+// in the path, sorts query parameters and optionally strips tracking
+// parameters. The fragment is kept as is.
+func (n *Normalizer) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return "", ErrInvalidURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host, port := strings.TrimSuffix(strings.ToLower(u.Hostname()), "."), u.Port()
+	if net.ParseIP(host) == nil {
+		if host, err = idna.Lookup.ToASCII(host); err != nil {
+			return "", ErrInvalidURL
+		}
+	}
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	u.Path = removeDotSegments(u.Path)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawPath = ""
+
+	u.RawQuery = n.normalizeQuery(u.Query())
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+func (n *Normalizer) normalizeQuery(q url.Values) string {
+	if n.stripTracking {
+		for key := range q {
+			if n.isTracking(key) {
+				q.Del(key)
+			}
+		}
+	}
+
+	// Encode sorts by key and keeps the order of repeated values.
+	return q.Encode()
+}
+
+func (n *Normalizer) isTracking(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range n.trackingParams {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeDotSegments implements RFC 3986, section 5.2.4.
+func removeDotSegments(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	var out []string
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		switch seg {
+		case ".":
+			if i == len(segments)-1 {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if i == len(segments)-1 {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, seg)
+		}
+	}
+
+	res := strings.Join(out, "/")
+	if strings.HasPrefix(path, "/") && !strings.HasPrefix(res, "/") {
+		res = "/" + res
+	}
+
+	return res
+}
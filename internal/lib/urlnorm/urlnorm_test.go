@@ -0,0 +1,61 @@
+package urlnorm_test
+
+import (
+	"RestApi/internal/lib/urlnorm"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		in    string
+		out   string
+		strip bool
+	}{
+		{in: "HTTP://Example.com:80/a/../b?utm_source=x", out: "http://example.com/b?utm_source=x"},
+		{in: "HTTP://Example.com:80/a/../b?utm_source=x", out: "http://example.com/b", strip: true},
+		{in: "https://example.com:443", out: "https://example.com/"},
+		{in: "https://example.com:8443/x", out: "https://example.com:8443/x"},
+		{in: "http://example.com./a/./b/../../c/", out: "http://example.com/c/"},
+		{in: "http://example.com/a/b/..", out: "http://example.com/a/"},
+		{in: "http://example.com/../../a", out: "http://example.com/a"},
+		{in: "https://example.com/?b=2&a=1&b=1", out: "https://example.com/?a=1&b=2&b=1"},
+		{in: "https://example.com/?", out: "https://example.com/"},
+		{in: "https://bücher.example/straße", out: "https://xn--bcher-kva.example/stra%C3%9Fe"},
+		{in: "https://example.com/path#Section", out: "https://example.com/path#Section"},
+		{in: "https://example.com/?fbclid=1&q=go&UTM_Medium=m", out: "https://example.com/?q=go", strip: true},
+		{in: "http://[::1]:80/x", out: "http://[::1]/x"},
+		{in: "http://[::1]:8080/x", out: "http://[::1]:8080/x"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			n := urlnorm.New(urlnorm.Options{StripTracking: tc.strip})
+
+			out, err := n.Normalize(tc.in)
+			require.NoError(t, err)
+			require.Equal(t, tc.out, out)
+
+			again, err := n.Normalize(out)
+			require.NoError(t, err)
+			require.Equal(t, out, again, "normalization must be idempotent")
+		})
+	}
+}
+
+func TestNormalizeCustomTrackingParams(t *testing.T) {
+	n := urlnorm.New(urlnorm.Options{StripTracking: true, TrackingParams: []string{"ref", "pk_*"}})
+
+	out, err := n.Normalize("https://example.com/?ref=x&pk_campaign=y&utm_source=z")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/?utm_source=z", out)
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	n := urlnorm.New(urlnorm.Options{})
+
+	for _, in := range []string{"", "not a url", "/relative/path", "http://%zz"} {
+		_, err := n.Normalize(in)
+		require.ErrorIs(t, err, urlnorm.ErrInvalidURL, in)
+	}
+}
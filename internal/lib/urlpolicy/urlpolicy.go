@@ -0,0 +1,310 @@
+// Package urlpolicy decides which target URLs may be shortened.
+package urlpolicy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultMaxLength      = 2048
+	DefaultReloadInterval = 30 * time.Second
+
+	resolveTimeout = 2 * time.Second
+)
+
+var DefaultSchemes = []string{"http", "https"}
+
+var (
+	ErrInvalidURL       = errors.New("url is not valid")
+	ErrTooLong          = errors.New("url is too long")
+	ErrSchemeNotAllowed = errors.New("url scheme is not allowed")
+	ErrDomainBlocked    = errors.New("url domain is blocked")
+	ErrDomainNotAllowed = errors.New("url domain is not allowed")
+	ErrPrivateAddress   = errors.New("url points to a private address")
+	ErrRedirectLoop     = errors.New("url points back to the shortener")
+)
+
+type Config struct {
+	AllowedSchemes []string
+	MaxLength      int
+	// DomainsFile holds one rule per line: "allow <pattern>" or
+	// "block <pattern>", where a pattern is a host name optionally
+	// starting with "*." to match its subdomains.
+	DomainsFile  string
+	BlockPrivate bool
+	// ResolveHosts also checks the addresses a host name resolves to
+	// when BlockPrivate is set.
+	ResolveHosts bool
+	// OwnDomains are the hosts the shortener is served from.
+	OwnDomains []string
+}
+
+type rules struct {
+	allow []string
+	block []string
+}
+
+type Policy struct {
+	schemes      map[string]struct{}
+	maxLength    int
+	domainsFile  string
+	blockPrivate bool
+	resolve      bool
+	ownDomains   []string
+	resolver     *net.Resolver
+
+	rules   atomic.Pointer[rules]
+	modTime atomic.Int64
+}
+
+func New(cfg Config) (*Policy, error) {
+	const op = "lib.urlpolicy.New"
+
+	if len(cfg.AllowedSchemes) == 0 {
+		cfg.AllowedSchemes = DefaultSchemes
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = DefaultMaxLength
+	}
+
+	p := &Policy{
+		schemes:      make(map[string]struct{}, len(cfg.AllowedSchemes)),
+		maxLength:    cfg.MaxLength,
+		domainsFile:  cfg.DomainsFile,
+		blockPrivate: cfg.BlockPrivate,
+		resolve:      cfg.ResolveHosts,
+		resolver:     net.DefaultResolver,
+	}
+	for _, s := range cfg.AllowedSchemes {
+		p.schemes[strings.ToLower(s)] = struct{}{}
+	}
+	p.AddOwnDomains(cfg.OwnDomains...)
+	p.rules.Store(&rules{})
+
+	if p.domainsFile != "" {
+		if err := p.Reload(); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return p, nil
+}
+
+// AddOwnDomains registers more hosts the shortener is served from. It
+// must not be called once the policy is in use.
+func (p *Policy) AddOwnDomains(hosts ...string) {
+	for _, h := range hosts {
+		if h = normalizeHost(h); h != "" {
+			p.ownDomains = append(p.ownDomains, h)
+		}
+	}
+}
+
+// Check returns nil if rawURL may be stored, or the reason it may not.
+func (p *Policy) Check(ctx context.Context, rawURL string) error {
+	if len(rawURL) > p.maxLength {
+		return ErrTooLong
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ErrInvalidURL
+	}
+
+	if _, ok := p.schemes[strings.ToLower(u.Scheme)]; !ok {
+		return ErrSchemeNotAllowed
+	}
+
+	host := normalizeHost(u.Hostname())
+	for _, own := range p.ownDomains {
+		if host == own {
+			return ErrRedirectLoop
+		}
+	}
+
+	r := p.rules.Load()
+	if matchAny(r.block, host) {
+		return ErrDomainBlocked
+	}
+	if len(r.allow) > 0 && !matchAny(r.allow, host) {
+		return ErrDomainNotAllowed
+	}
+
+	if p.blockPrivate {
+		return p.checkAddress(ctx, host)
+	}
+
+	return nil
+}
+
+func (p *Policy) checkAddress(ctx context.Context, host string) error {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if IsPrivate(addr) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+
+	if !p.resolve {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	addrs, err := p.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		// Unresolvable hosts cannot reach anything private either.
+		return nil
+	}
+	for _, addr := range addrs {
+		if IsPrivate(addr) {
+			return ErrPrivateAddress
+		}
+	}
+
+	return nil
+}
+
+// IsPrivate reports whether addr is loopback, private, link-local or
+// otherwise not publicly routable.
+func IsPrivate(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified()
+}
+
+// Reload re-reads the domains file.
+func (p *Policy) Reload() error {
+	st, err := os.Stat(p.domainsFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat domains file: %w", err)
+	}
+
+	r, err := readRules(p.domainsFile)
+	if err != nil {
+		return err
+	}
+
+	p.rules.Store(r)
+	p.modTime.Store(st.ModTime().UnixNano())
+
+	return nil
+}
+
+// Watch reloads the domains file whenever its modification time changes,
+// until ctx is done. A broken file keeps the previous rules in effect.
+func (p *Policy) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	if p.domainsFile == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+
+	log = log.With(slog.String("component", "urlpolicy"))
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		st, err := os.Stat(p.domainsFile)
+		if err != nil {
+			log.Error("failed to stat domains file", "error", err.Error())
+			continue
+		}
+		if st.ModTime().UnixNano() == p.modTime.Load() {
+			continue
+		}
+
+		if err := p.Reload(); err != nil {
+			log.Error("failed to reload domains file", "error", err.Error())
+			continue
+		}
+		log.Info("domains file reloaded")
+	}
+}
+
+func readRules(file string) (*rules, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open domains file: %w", err)
+	}
+	defer f.Close()
+
+	r := &rules{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		action, pattern, ok := strings.Cut(line, " ")
+		pattern = normalizeHost(pattern)
+		if _, err := path.Match(pattern, ""); !ok || pattern == "" || err != nil {
+			return nil, fmt.Errorf("domains file line %d: invalid rule %q", n, line)
+		}
+
+		switch action {
+		case "allow":
+			r.allow = append(r.allow, pattern)
+		case "block":
+			r.block = append(r.block, pattern)
+		default:
+			return nil, fmt.Errorf("domains file line %d: unknown action %q", n, action)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read domains file: %w", err)
+	}
+
+	return r, nil
+}
+
+func matchAny(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeHost(host string) string {
+	host = strings.TrimSpace(strings.ToLower(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+}
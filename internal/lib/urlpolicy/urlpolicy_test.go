@@ -0,0 +1,103 @@
+package urlpolicy_test
+
+import (
+	"RestApi/internal/lib/urlpolicy"
+	"context"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	domains := filepath.Join(t.TempDir(), "domains.txt")
+	require.NoError(t, os.WriteFile(domains, []byte(`# rules
+block evil.com
+block *.evil.com
+`), 0o644))
+
+	p, err := urlpolicy.New(urlpolicy.Config{
+		MaxLength:    100,
+		DomainsFile:  domains,
+		BlockPrivate: true,
+		OwnDomains:   []string{"sho.rt:8082"},
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		url string
+		err error
+	}{
+		{url: "https://google.com/search?q=go"},
+		{url: "HTTP://Example.COM:80/"},
+		{url: "javascript:alert(1)", err: urlpolicy.ErrInvalidURL},
+		{url: "file:///etc/passwd", err: urlpolicy.ErrInvalidURL},
+		{url: "ftp://files.example.com/a", err: urlpolicy.ErrSchemeNotAllowed},
+		{url: "data://text/plain,hi", err: urlpolicy.ErrSchemeNotAllowed},
+		{url: "https://example.com/" + strings.Repeat("a", 100), err: urlpolicy.ErrTooLong},
+		{url: "https://evil.com/x", err: urlpolicy.ErrDomainBlocked},
+		{url: "https://a.b.EVIL.com/x", err: urlpolicy.ErrDomainBlocked},
+		{url: "https://notevil.com/x"},
+		{url: "http://localhost/admin", err: urlpolicy.ErrPrivateAddress},
+		{url: "http://127.0.0.1:8080/", err: urlpolicy.ErrPrivateAddress},
+		{url: "http://10.1.2.3/", err: urlpolicy.ErrPrivateAddress},
+		{url: "http://169.254.169.254/latest/meta-data", err: urlpolicy.ErrPrivateAddress},
+		{url: "http://[::1]/", err: urlpolicy.ErrPrivateAddress},
+		{url: "http://[::ffff:192.168.0.1]/", err: urlpolicy.ErrPrivateAddress},
+		{url: "https://sho.rt/abc", err: urlpolicy.ErrRedirectLoop},
+		{url: "https://SHO.RT./abc", err: urlpolicy.ErrRedirectLoop},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.url, func(t *testing.T) {
+			require.ErrorIs(t, p.Check(context.Background(), tc.url), tc.err)
+		})
+	}
+}
+
+func TestPolicyAllowList(t *testing.T) {
+	domains := filepath.Join(t.TempDir(), "domains.txt")
+	require.NoError(t, os.WriteFile(domains, []byte("allow example.com\nallow *.example.com\nblock bad.example.com\n"), 0o644))
+
+	p, err := urlpolicy.New(urlpolicy.Config{DomainsFile: domains})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, p.Check(ctx, "https://example.com"))
+	require.NoError(t, p.Check(ctx, "https://docs.example.com"))
+	require.ErrorIs(t, p.Check(ctx, "https://bad.example.com"), urlpolicy.ErrDomainBlocked)
+	require.ErrorIs(t, p.Check(ctx, "https://google.com"), urlpolicy.ErrDomainNotAllowed)
+}
+
+func TestPolicyInvalidDomainsFile(t *testing.T) {
+	domains := filepath.Join(t.TempDir(), "domains.txt")
+	require.NoError(t, os.WriteFile(domains, []byte("deny evil.com\n"), 0o644))
+
+	_, err := urlpolicy.New(urlpolicy.Config{DomainsFile: domains})
+	require.Error(t, err)
+}
+
+func TestPolicyWatch(t *testing.T) {
+	domains := filepath.Join(t.TempDir(), "domains.txt")
+	require.NoError(t, os.WriteFile(domains, nil, 0o644))
+
+	p, err := urlpolicy.New(urlpolicy.Config{DomainsFile: domains})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Watch(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)), 10*time.Millisecond)
+
+	require.NoError(t, p.Check(ctx, "https://evil.com"))
+
+	require.NoError(t, os.WriteFile(domains, []byte("block evil.com\n"), 0o644))
+	require.NoError(t, os.Chtimes(domains, time.Now(), time.Now().Add(time.Second)))
+
+	require.Eventually(t, func() bool {
+		return p.Check(ctx, "https://evil.com") != nil
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,224 @@
+// Package copier moves links between storage backends, for example from
+// sqllite.Storage to postgres.Storage.
+package copier
+
+import (
+	"RestApi/internal/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const DefaultBatchSize = 500
+
+var ErrMismatch = errors.New("alias exists in destination with a different link")
+
+type Source interface {
+	ListLinksAfter(afterID int64, limit int) ([]storage.Link, error)
+}
+
+type Destination interface {
+	Source
+	SaveLink(link storage.Link) (int64, error)
+	GetLink(domain, alias string) (storage.Link, error)
+}
+
+type Copier struct {
+	Src       Source
+	Dst       Destination
+	BatchSize int
+	// OnBatch is called after every copied batch with the id of the last
+	// source row, so the caller can persist it and resume from there.
+	OnBatch func(lastID int64) error
+}
+
+type Result struct {
+	Copied int
+	// Existing counts links already present in the destination with the
+	// same contents, which happens when a copy is resumed.
+	Existing int
+	LastID   int64
+}
+
+// Copy copies every source link with an id greater than afterID, deleted
+// links and split targets included.
+func (c *Copier) Copy(afterID int64) (Result, error) {
+	const op = "storage.copier.Copy"
+
+	batch := c.BatchSize
+	if batch <= 0 {
+		batch = DefaultBatchSize
+	}
+
+	res := Result{LastID: afterID}
+	for {
+		links, err := c.Src.ListLinksAfter(res.LastID, batch)
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, link := range links {
+			if err := c.copyLink(link, &res); err != nil {
+				return res, fmt.Errorf("%s: alias %q on domain %q: %w", op, link.Alias, link.Domain, err)
+			}
+			res.LastID = link.ID
+		}
+
+		if len(links) > 0 && c.OnBatch != nil {
+			if err := c.OnBatch(res.LastID); err != nil {
+				return res, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if len(links) < batch {
+			return res, nil
+		}
+	}
+}
+
+func (c *Copier) copyLink(link storage.Link, res *Result) error {
+	_, err := c.Dst.SaveLink(link)
+	if err == nil {
+		res.Copied++
+		return nil
+	}
+	if !errors.Is(err, storage.ErrURLExists) && !errors.Is(err, storage.ErrAliasQuarantined) {
+		return err
+	}
+
+	existing, err := c.Dst.GetLink(link.Domain, link.Alias)
+	if err != nil {
+		return err
+	}
+	same, err := sameLink(link, existing)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return ErrMismatch
+	}
+	res.Existing++
+
+	return nil
+}
+
+type Verification struct {
+	SourceCount         int64
+	DestinationCount    int64
+	SourceChecksum      string
+	DestinationChecksum string
+}
+
+// OK reports whether both sides hold exactly the same links.
+func (v Verification) OK() bool {
+	return v.SourceCount == v.DestinationCount &&
+		v.SourceChecksum == v.DestinationChecksum
+}
+
+// Verify counts and checksums both sides. The checksum does not depend on
+// row order or ids, which differ between backends after a copy.
+func Verify(src, dst Source) (Verification, error) {
+	const op = "storage.copier.Verify"
+
+	var v Verification
+	var err error
+
+	if v.SourceCount, v.SourceChecksum, err = checksum(src); err != nil {
+		return v, fmt.Errorf("%s: %w", op, err)
+	}
+	if v.DestinationCount, v.DestinationChecksum, err = checksum(dst); err != nil {
+		return v, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return v, nil
+}
+
+// checksum counts every link, deleted ones included, and XORs their
+// fingerprints. (domain, alias) is unique, so no two links can cancel each
+// other out.
+func checksum(s Source) (int64, string, error) {
+	var (
+		count  int64
+		sum    [sha256.Size]byte
+		lastID int64
+	)
+	for {
+		links, err := s.ListLinksAfter(lastID, DefaultBatchSize)
+		if err != nil {
+			return 0, "", err
+		}
+
+		for _, link := range links {
+			h, err := fingerprint(link)
+			if err != nil {
+				return 0, "", err
+			}
+			for i := range sum {
+				sum[i] ^= h[i]
+			}
+			count++
+			lastID = link.ID
+		}
+
+		if len(links) < DefaultBatchSize {
+			return count, hex.EncodeToString(sum[:]), nil
+		}
+	}
+}
+
+func sameLink(a, b storage.Link) (bool, error) {
+	ha, err := fingerprint(a)
+	if err != nil {
+		return false, err
+	}
+	hb, err := fingerprint(b)
+	if err != nil {
+		return false, err
+	}
+
+	return ha == hb, nil
+}
+
+// fingerprint hashes every copied field of link. Ids are left out and
+// values the backends store differently, timestamps, tag order and
+// metadata formatting, are brought into one form first.
+func fingerprint(link storage.Link) ([sha256.Size]byte, error) {
+	link.ID = 0
+	for _, t := range []*time.Time{
+		&link.CreatedAt, &link.UpdatedAt, &link.DeletedAt,
+		&link.NotBefore, &link.NotAfter, &link.Status.CheckedAt,
+	} {
+		*t = t.UTC().Truncate(time.Microsecond)
+	}
+
+	link.Meta.Tags = slices.Sorted(slices.Values(link.Meta.Tags))
+	if len(link.Meta.Metadata) > 0 {
+		var v any
+		if err := json.Unmarshal(link.Meta.Metadata, &v); err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		link.Meta.Metadata = b
+	}
+
+	targets := make([]storage.Target, len(link.Targets))
+	for i, t := range link.Targets {
+		t.ID = 0
+		targets[i] = t
+	}
+	link.Targets = targets
+
+	b, err := json.Marshal(link)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	return sha256.Sum256(b), nil
+}
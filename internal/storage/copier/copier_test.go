@@ -0,0 +1,178 @@
+package copier_test
+
+import (
+	"RestApi/internal/storage"
+	"RestApi/internal/storage/copier"
+	"RestApi/internal/storage/sqllite"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newStorage(t *testing.T, name string) *sqllite.Storage {
+	t.Helper()
+
+	s, err := sqllite.New(filepath.Join(t.TempDir(), name))
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestCopyResumeVerify(t *testing.T) {
+	src := newStorage(t, "src.db")
+	dst := newStorage(t, "dst.db")
+
+	for i := 0; i < 25; i++ {
+		_, err := src.SaveURL(fmt.Sprintf("https://example.com/%d", i), fmt.Sprintf("alias%d", i))
+		require.NoError(t, err)
+	}
+
+	errCrash := errors.New("crash")
+	var checkpoint int64
+
+	c := &copier.Copier{
+		Src:       src,
+		Dst:       dst,
+		BatchSize: 10,
+		OnBatch: func(lastID int64) error {
+			checkpoint = lastID
+			return errCrash
+		},
+	}
+
+	res, err := c.Copy(0)
+	require.ErrorIs(t, err, errCrash)
+	require.Equal(t, 10, res.Copied)
+
+	v, err := copier.Verify(src, dst)
+	require.NoError(t, err)
+	require.False(t, v.OK())
+	require.Equal(t, int64(10), v.DestinationCount)
+
+	// A resumed copy that restarts a little earlier than the checkpoint
+	// must tolerate links it already copied.
+	c.OnBatch = func(lastID int64) error {
+		checkpoint = lastID
+		return nil
+	}
+
+	res, err = c.Copy(checkpoint - 3)
+	require.NoError(t, err)
+	require.Equal(t, copier.Result{Copied: 15, Existing: 3, LastID: 25}, res)
+	require.Equal(t, int64(25), checkpoint)
+
+	v, err = copier.Verify(src, dst)
+	require.NoError(t, err)
+	require.True(t, v.OK())
+	require.Equal(t, int64(25), v.SourceCount)
+}
+
+func TestCopyMismatch(t *testing.T) {
+	src := newStorage(t, "src.db")
+	dst := newStorage(t, "dst.db")
+
+	_, err := src.SaveURL("https://example.com/a", "a")
+	require.NoError(t, err)
+	_, err = dst.SaveURL("https://example.com/other", "a")
+	require.NoError(t, err)
+
+	_, err = (&copier.Copier{Src: src, Dst: dst}).Copy(0)
+	require.ErrorIs(t, err, copier.ErrMismatch)
+
+	v, err := copier.Verify(src, dst)
+	require.NoError(t, err)
+	require.Equal(t, v.SourceCount, v.DestinationCount)
+	require.False(t, v.OK())
+}
+
+func TestCopyWholeLinks(t *testing.T) {
+	src := newStorage(t, "src.db")
+	dst := newStorage(t, "dst.db")
+
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	_, err := src.SaveLink(storage.Link{
+		Alias:         "promo",
+		URL:           "https://example.com/a",
+		CreatedAt:     at,
+		CreatedBy:     "alice",
+		OriginalURL:   "HTTPS://Example.com/a",
+		PasswordHash:  "hash",
+		MaxClicks:     3,
+		NotBefore:     at,
+		NotAfter:      at.Add(24 * time.Hour),
+		Rules:         []storage.Rule{{Device: "ios", URL: "https://example.com/ios"}},
+		StickyTargets: true,
+		RedirectType:  "307",
+		PassQuery:     "keep",
+		PassPath:      true,
+		Meta: storage.Meta{
+			Title:    "Promo",
+			Tags:     []string{"spring", "ads"},
+			Metadata: json.RawMessage(`{"team": "growth"}`),
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, src.SetTargets("", "promo", true, []storage.Target{
+		{Variant: "a", URL: "https://example.com/a", Weight: 1},
+		{Variant: "b", URL: "https://example.com/b", Weight: 3},
+	}))
+	targets, err := src.GetTargets("", "promo")
+	require.NoError(t, err)
+	require.NoError(t, src.CountTargetHit(targets[1].ID))
+	_, err = src.ConsumeClick("", "promo")
+	require.NoError(t, err)
+
+	// The same alias on another domain, and a deleted link.
+	_, err = src.SaveLink(storage.Link{Domain: "brand.example", Alias: "promo", URL: "https://brand.example/a"})
+	require.NoError(t, err)
+	_, err = src.SaveURL("https://example.com/old", "old")
+	require.NoError(t, err)
+	require.NoError(t, src.DeleteURL("", "old"))
+
+	res, err := (&copier.Copier{Src: src, Dst: dst}).Copy(0)
+	require.NoError(t, err)
+	require.Equal(t, 3, res.Copied)
+
+	for _, key := range [][2]string{{"", "promo"}, {"brand.example", "promo"}, {"", "old"}} {
+		want, err := src.GetLink(key[0], key[1])
+		require.NoError(t, err)
+		got, err := dst.GetLink(key[0], key[1])
+		require.NoError(t, err)
+
+		want.ID, got.ID = 0, 0
+		for i := range want.Targets {
+			want.Targets[i].ID, got.Targets[i].ID = 0, 0
+		}
+		require.Equal(t, want, got)
+	}
+
+	v, err := copier.Verify(src, dst)
+	require.NoError(t, err)
+	require.True(t, v.OK())
+	require.Equal(t, int64(3), v.SourceCount)
+
+	// Copying again finds every link, deleted ones included, unchanged.
+	res, err = (&copier.Copier{Src: src, Dst: dst}).Copy(0)
+	require.NoError(t, err)
+	require.Equal(t, copier.Result{Existing: 3, LastID: 3}, res)
+}
+
+func TestVerifyComparesFields(t *testing.T) {
+	src := newStorage(t, "src.db")
+	dst := newStorage(t, "dst.db")
+
+	_, err := src.SaveLink(storage.Link{Domain: "brand.example", Alias: "a", URL: "https://example.com/a", MaxClicks: 5})
+	require.NoError(t, err)
+	// Same alias and url, but on the default domain without a limit.
+	_, err = dst.SaveURL("https://example.com/a", "a")
+	require.NoError(t, err)
+
+	v, err := copier.Verify(src, dst)
+	require.NoError(t, err)
+	require.Equal(t, v.SourceCount, v.DestinationCount)
+	require.False(t, v.OK())
+}
@@ -3,9 +3,11 @@ package postgres
 import (
 	"RestApi/internal/storage"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -32,29 +34,142 @@ func New(connString string, timeout time.Duration) (*Storage, error) {
 	return &Storage{db: pool}, nil
 }
 
+// Close closes every connection in the pool.
+func (s *Storage) Close() error {
+	s.db.Close()
+
+	return nil
+}
+
 func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
-	const op = "storage.postgres.SaveURL"
+	return s.SaveLink(storage.Link{URL: urlToSave, Alias: alias})
+}
+
+func (s *Storage) SaveLink(link storage.Link) (int64, error) {
+	const op = "storage.postgres.SaveLink"
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	rules, err := marshalRules(link.Rules)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
 	var id int64
-	err := s.db.QueryRow(ctx,
-		"INSERT INTO url(url, alias) VALUES ($1, $2) RETURNING id",
-		urlToSave, alias).Scan(&id)
+	err = tx.QueryRow(ctx, `
+		INSERT INTO url(url, domain, alias, original_url, password_hash, max_clicks, clicks_left,
+			not_before, not_after, rules, redirect_type, pass_query, pass_path, created_at, updated_at, created_by,
+			title, description, metadata,
+			resolved_url, last_status, checked_at, dead, sticky_targets, deleted_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, 0), $7, $8, $9, $10, NULLIF($11, ''),
+			NULLIF($12, ''), $13, $14, $15, NULLIF($16, ''), NULLIF($17, ''), NULLIF($18, ''), $19,
+			NULLIF($20, ''), NULLIF($21, 0), $22, $23, $24, $25)
+		RETURNING id`,
+		link.URL, link.Domain, link.Alias, link.OriginalURL, link.PasswordHash, link.MaxClicks, clicksLeft(link),
+		nullTime(link.NotBefore), nullTime(link.NotAfter), rules, link.RedirectType, link.PassQuery, link.PassPath,
+		creationTime(link), nullTime(link.UpdatedAt), link.CreatedBy,
+		link.Meta.Title, link.Meta.Description, nullJSON(link.Meta.Metadata),
+		link.Status.ResolvedURL, link.Status.StatusCode, nullTime(link.Status.CheckedAt), link.Status.Dead,
+		link.StickyTargets, nullTime(link.DeletedAt),
+	).Scan(&id)
 
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+			return 0, fmt.Errorf("%s: %w", op, s.conflict(ctx, link.Domain, link.Alias))
 		}
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := setTags(ctx, tx, id, link.Meta.Tags); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := insertTargets(ctx, tx, id, link.Targets); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return id, nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
+// SetMeta replaces the title, description, tags and metadata of alias on
+// domain.
+func (s *Storage) SetMeta(domain, alias string, meta storage.Meta) error {
+	const op = "storage.postgres.SetMeta"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+		UPDATE url SET title = NULLIF($1, ''), description = NULLIF($2, ''), metadata = $3, updated_at = $4
+		WHERE domain = $5 AND alias = $6 AND deleted_at IS NULL
+		RETURNING id`,
+		meta.Title, meta.Description, nullJSON(meta.Metadata), time.Now().UTC(), domain, alias,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrURLNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM link_tags WHERE url_id = $1", id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := setTags(ctx, tx, id, meta.Tags); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// conflict tells why alias on domain cannot be saved: it either belongs to
+// a live link or to a deleted one that is still quarantined.
+func (s *Storage) conflict(ctx context.Context, domain, alias string) error {
+	var deleted bool
+	err := s.db.QueryRow(ctx, "SELECT deleted_at IS NOT NULL FROM url WHERE domain = $1 AND alias = $2",
+		domain, alias).Scan(&deleted)
+	if err == nil && deleted {
+		return storage.ErrAliasQuarantined
+	}
+
+	return storage.ErrURLExists
+}
+
+func setTags(ctx context.Context, tx pgx.Tx, urlID int64, tags []string) error {
+	for _, tag := range tags {
+		_, err := tx.Exec(ctx, "INSERT INTO link_tags(url_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING", urlID, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetURL returns the target of alias on domain.
+func (s *Storage) GetURL(domain, alias string) (string, error) {
 	const op = "storage.postgres.GetURL"
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -62,8 +177,8 @@ func (s *Storage) GetURL(alias string) (string, error) {
 
 	var resURL string
 	err := s.db.QueryRow(ctx,
-		"SELECT url FROM url WHERE alias = $1",
-		alias).Scan(&resURL)
+		"SELECT url FROM url WHERE domain = $1 AND alias = $2 AND deleted_at IS NULL",
+		domain, alias).Scan(&resURL)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return "", storage.ErrURLNotFound
@@ -75,15 +190,196 @@ func (s *Storage) GetURL(alias string) (string, error) {
 	return resURL, nil
 }
 
-func (s *Storage) DeleteURL(alias string) error {
+// GetLink returns alias on domain, empty for the default domain. Deleted
+// links are returned as well, with DeletedAt set.
+func (s *Storage) GetLink(domain, alias string) (storage.Link, error) {
+	const op = "storage.postgres.GetLink"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	link, err := scanLink(s.db.QueryRow(ctx,
+		"SELECT "+linkColumns+" FROM url WHERE domain = $1 AND alias = $2", domain, alias))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.Link{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	link.Targets, err = s.targets(ctx, link.ID)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return link, nil
+}
+
+// ConsumeClick uses up one click of a limited link and returns how many
+// are left. Links without a limit are left untouched and report -1.
+func (s *Storage) ConsumeClick(domain, alias string) (int, error) {
+	const op = "storage.postgres.ConsumeClick"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var left int
+	err := s.db.QueryRow(ctx, `
+		UPDATE url SET clicks_left = clicks_left - 1
+		WHERE domain = $1 AND alias = $2 AND clicks_left > 0
+		RETURNING clicks_left`, domain, alias,
+	).Scan(&left)
+	if err == nil {
+		return left, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Nothing was decremented: the link is unlimited, used up or gone.
+	var limited bool
+	err = s.db.QueryRow(ctx,
+		"SELECT clicks_left IS NOT NULL FROM url WHERE domain = $1 AND alias = $2",
+		domain, alias).Scan(&limited)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if limited {
+		return 0, storage.ErrLinkExhausted
+	}
+
+	return -1, nil
+}
+
+// SetTargets replaces the split targets of alias on domain. Hit counts
+// start over.
+func (s *Storage) SetTargets(domain, alias string, sticky bool, targets []storage.Target) error {
+	const op = "storage.postgres.SetTargets"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+		UPDATE url SET sticky_targets = $1, updated_at = $2
+		WHERE domain = $3 AND alias = $4 AND deleted_at IS NULL
+		RETURNING id`,
+		sticky, time.Now().UTC(), domain, alias).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrURLNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM link_targets WHERE url_id = $1", id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := insertTargets(ctx, tx, id, targets); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func insertTargets(ctx context.Context, tx pgx.Tx, urlID int64, targets []storage.Target) error {
+	for _, t := range targets {
+		_, err := tx.Exec(ctx,
+			"INSERT INTO link_targets(url_id, variant, url, weight, hits) VALUES ($1, $2, $3, $4, $5)",
+			urlID, t.Variant, t.URL, t.Weight, t.Hits)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTargets returns the split targets of alias on domain with their hit
+// counts.
+func (s *Storage) GetTargets(domain, alias string) ([]storage.Target, error) {
+	const op = "storage.postgres.GetTargets"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRow(ctx, "SELECT id FROM url WHERE domain = $1 AND alias = $2 AND deleted_at IS NULL",
+		domain, alias).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	targets, err := s.targets(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return targets, nil
+}
+
+func (s *Storage) targets(ctx context.Context, urlID int64) ([]storage.Target, error) {
+	rows, err := s.db.Query(ctx,
+		"SELECT id, variant, url, weight, hits FROM link_targets WHERE url_id = $1 ORDER BY id",
+		urlID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []storage.Target
+	for rows.Next() {
+		var t storage.Target
+		if err := rows.Scan(&t.ID, &t.Variant, &t.URL, &t.Weight, &t.Hits); err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, rows.Err()
+}
+
+// CountTargetHit records that the target was served.
+func (s *Storage) CountTargetHit(targetID int64) error {
+	const op = "storage.postgres.CountTargetHit"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.db.Exec(ctx, "UPDATE link_targets SET hits = hits + 1 WHERE id = $1", targetID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteURL marks alias on domain as deleted. The link keeps its alias
+// until PurgeDeleted removes it.
+func (s *Storage) DeleteURL(domain, alias string) error {
 	const op = "storage.postgres.DeleteURL"
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	res, err := s.db.Exec(ctx,
-		"DELETE FROM url WHERE alias = $1",
-		alias)
+		"UPDATE url SET deleted_at = $1 WHERE domain = $2 AND alias = $3 AND deleted_at IS NULL",
+		time.Now().UTC(), domain, alias)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -93,3 +389,500 @@ func (s *Storage) DeleteURL(alias string) error {
 
 	return nil
 }
+
+// RestoreURL brings back the deleted alias on domain.
+func (s *Storage) RestoreURL(domain, alias string) error {
+	const op = "storage.postgres.RestoreURL"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx,
+		"UPDATE url SET deleted_at = NULL WHERE domain = $1 AND alias = $2 AND deleted_at IS NOT NULL",
+		domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// PurgeDeleted permanently removes links deleted before t, together with
+// their targets and tags, and returns how many there were.
+func (s *Storage) PurgeDeleted(t time.Time) (int64, error) {
+	const op = "storage.postgres.PurgeDeleted"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx, "DELETE FROM url WHERE deleted_at IS NOT NULL AND deleted_at < $1", t)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.RowsAffected(), nil
+}
+
+// ReserveAlias holds res.Alias on res.Domain until res.ExpiresAt. It fails
+// with ErrURLExists or ErrAliasQuarantined when a link has the alias and
+// with ErrAliasHeld while another reservation holds it.
+func (s *Storage) ReserveAlias(res storage.Reservation) error {
+	const op = "storage.postgres.ReserveAlias"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM alias_reservations WHERE expires_at <= $1", time.Now().UTC()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var deleted bool
+	err = tx.QueryRow(ctx, "SELECT deleted_at IS NOT NULL FROM url WHERE domain = $1 AND alias = $2",
+		res.Domain, res.Alias).Scan(&deleted)
+	switch {
+	case err == nil && deleted:
+		return fmt.Errorf("%s: %w", op, storage.ErrAliasQuarantined)
+	case err == nil:
+		return fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO alias_reservations(domain, alias, token, reserved_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		res.Domain, res.Alias, res.Token, res.ReservedBy, res.ExpiresAt.UTC())
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%s: %w", op, storage.ErrAliasHeld)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetReservation returns the reservation holding alias on domain. Expired
+// reservations are not returned.
+func (s *Storage) GetReservation(domain, alias string) (storage.Reservation, error) {
+	const op = "storage.postgres.GetReservation"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res := storage.Reservation{Domain: domain, Alias: alias}
+	err := s.db.QueryRow(ctx, `
+		SELECT token, reserved_by, expires_at FROM alias_reservations
+		WHERE domain = $1 AND alias = $2 AND expires_at > $3`,
+		domain, alias, time.Now().UTC(),
+	).Scan(&res.Token, &res.ReservedBy, &res.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.Reservation{}, storage.ErrReservationNotFound
+	}
+	if err != nil {
+		return storage.Reservation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// ReleaseReservation drops the reservation of alias on domain, if any.
+func (s *Storage) ReleaseReservation(domain, alias string) error {
+	const op = "storage.postgres.ReleaseReservation"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, "DELETE FROM alias_reservations WHERE domain = $1 AND alias = $2", domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ListURLs(limit, offset int) ([]storage.Link, error) {
+	const op = "storage.postgres.ListURLs"
+
+	links, err := s.listLinks(
+		"SELECT "+listColumns+" FROM url WHERE deleted_at IS NULL ORDER BY id LIMIT $1 OFFSET $2",
+		limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+// ListURLsByTag lists the links tagged with tag.
+func (s *Storage) ListURLsByTag(tag string, limit, offset int) ([]storage.Link, error) {
+	const op = "storage.postgres.ListURLsByTag"
+
+	links, err := s.listLinks(`
+		SELECT `+listColumns+` FROM url
+		WHERE deleted_at IS NULL AND id IN (SELECT url_id FROM link_tags WHERE tag = $1)
+		ORDER BY id LIMIT $2 OFFSET $3`,
+		tag, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+// listColumns lists the columns listLinks reads, in order.
+const listColumns = `id, domain, alias, url, COALESCE(title, ''), ` + tagsColumn
+
+func (s *Storage) listLinks(query string, args ...any) ([]storage.Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []storage.Link
+	for rows.Next() {
+		var (
+			link storage.Link
+			tags string
+		)
+		if err := rows.Scan(&link.ID, &link.Domain, &link.Alias, &link.URL, &link.Meta.Title, &tags); err != nil {
+			return nil, err
+		}
+		link.Meta.Tags = splitTags(tags)
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+func (s *Storage) CountURLs() (int64, error) {
+	const op = "storage.postgres.CountURLs"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var count int64
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM url WHERE deleted_at IS NULL").Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// UpdateURL changes the target of alias on domain. originalURL is the
+// target as submitted when it was normalized, empty otherwise. The
+// reachability status of the old target is cleared.
+func (s *Storage) UpdateURL(domain, alias string, urlToSave, originalURL string) error {
+	const op = "storage.postgres.UpdateURL"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx,
+		`UPDATE url SET url = $1, original_url = NULLIF($2, ''), updated_at = $3,
+			resolved_url = NULL, last_status = NULL, checked_at = NULL, dead = FALSE
+		WHERE domain = $4 AND alias = $5 AND deleted_at IS NULL`,
+		urlToSave, originalURL, time.Now().UTC(), domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) ListURLsAfter(afterID int64, limit int) ([]storage.Link, error) {
+	const op = "storage.postgres.ListURLsAfter"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx,
+		"SELECT "+linkColumns+" FROM url WHERE id > $1 AND deleted_at IS NULL ORDER BY id LIMIT $2",
+		afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var links []storage.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+// ListLinksAfter pages through every row in id order, deleted links and
+// split targets included, so whole tables can be copied.
+func (s *Storage) ListLinksAfter(afterID int64, limit int) ([]storage.Link, error) {
+	const op = "storage.postgres.ListLinksAfter"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, "SELECT "+linkColumns+" FROM url WHERE id > $1 ORDER BY id LIMIT $2",
+		afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var links []storage.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range links {
+		if links[i].Targets, err = s.targets(ctx, links[i].ID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return links, nil
+}
+
+func (s *Storage) SetLinkStatus(domain, alias string, status storage.LinkStatus) error {
+	const op = "storage.postgres.SetLinkStatus"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx, `
+		UPDATE url
+		SET resolved_url = NULLIF($1, ''), last_status = NULLIF($2, 0), checked_at = $3, dead = $4
+		WHERE domain = $5 AND alias = $6`,
+		status.ResolvedURL, status.StatusCode, nullTime(status.CheckedAt), status.Dead, domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// AppendAudit adds entry to the audit log. Entries cannot be changed
+// afterwards.
+func (s *Storage) AppendAudit(entry storage.AuditEntry) error {
+	const op = "storage.postgres.AppendAudit"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO audit_log(at, action, domain, alias, actor, request_id, old_value, new_value)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		entry.At.UTC(), entry.Action, entry.Domain, entry.Alias, entry.Actor, entry.RequestID,
+		nullJSON(entry.Old), nullJSON(entry.New))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListAudit returns the audit entries matching filter, newest first.
+func (s *Storage) ListAudit(filter storage.AuditFilter, limit, offset int) ([]storage.AuditEntry, error) {
+	const op = "storage.postgres.ListAudit"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.Alias != "" {
+		add("alias = $%d", filter.Alias)
+	}
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if !filter.From.IsZero() {
+		add("at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("at <= $%d", filter.To)
+	}
+
+	query := "SELECT id, at, action, domain, alias, actor, request_id, old_value, new_value FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+
+	rows, err := s.db.Query(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var entries []storage.AuditEntry
+	for rows.Next() {
+		var e storage.AuditEntry
+		err := rows.Scan(&e.ID, &e.At, &e.Action, &e.Domain, &e.Alias, &e.Actor, &e.RequestID, &e.Old, &e.New)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+func nullJSON(raw json.RawMessage) *string {
+	if len(raw) == 0 {
+		return nil
+	}
+	str := string(raw)
+
+	return &str
+}
+
+// tagsColumn aggregates the tags of a url row, joined by commas which tags
+// never contain.
+const tagsColumn = `COALESCE((SELECT string_agg(tag, ',' ORDER BY tag) FROM link_tags WHERE url_id = url.id), '')`
+
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+
+	return strings.Split(tags, ",")
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return *t
+}
+
+// linkColumns lists the columns scanLink reads, in order.
+const linkColumns = `id, domain, alias, url, COALESCE(original_url, ''), COALESCE(password_hash, ''),
+	COALESCE(max_clicks, 0), not_before, not_after, rules, sticky_targets, COALESCE(redirect_type, ''),
+	COALESCE(pass_query, ''), pass_path, created_at, updated_at, COALESCE(created_by, ''), deleted_at,
+	COALESCE(title, ''), COALESCE(description, ''), metadata, ` + tagsColumn + `,
+	COALESCE(resolved_url, ''), COALESCE(last_status, 0), checked_at, dead,
+	COALESCE(max_clicks - clicks_left, 0)`
+
+func scanLink(row pgx.Row) (storage.Link, error) {
+	var (
+		link                 storage.Link
+		notBefore, notAfter  *time.Time
+		createdAt, updatedAt *time.Time
+		deletedAt, checkedAt *time.Time
+		rules, metadata      []byte
+		tags                 string
+	)
+	err := row.Scan(&link.ID, &link.Domain, &link.Alias, &link.URL, &link.OriginalURL, &link.PasswordHash,
+		&link.MaxClicks, &notBefore, &notAfter, &rules, &link.StickyTargets, &link.RedirectType,
+		&link.PassQuery, &link.PassPath, &createdAt, &updatedAt, &link.CreatedBy, &deletedAt,
+		&link.Meta.Title, &link.Meta.Description, &metadata, &tags,
+		&link.Status.ResolvedURL, &link.Status.StatusCode, &checkedAt, &link.Status.Dead,
+		&link.ClicksUsed)
+	if err != nil {
+		return storage.Link{}, err
+	}
+	link.NotBefore, link.NotAfter = timeOrZero(notBefore), timeOrZero(notAfter)
+	link.CreatedAt, link.UpdatedAt = timeOrZero(createdAt), timeOrZero(updatedAt)
+	link.DeletedAt, link.Status.CheckedAt = timeOrZero(deletedAt), timeOrZero(checkedAt)
+	link.Meta.Metadata, link.Meta.Tags = metadata, splitTags(tags)
+
+	if rules != nil {
+		if err := json.Unmarshal(rules, &link.Rules); err != nil {
+			return storage.Link{}, err
+		}
+	}
+
+	return link, nil
+}
+
+// marshalRules encodes rules for the rules column, NULL when there are
+// none.
+func marshalRules(rules []storage.Rule) (*string, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	str := string(b)
+
+	return &str, nil
+}
+
+// creationTime returns when link was created, now for new links.
+func creationTime(link storage.Link) time.Time {
+	if link.CreatedAt.IsZero() {
+		return time.Now().UTC()
+	}
+
+	return link.CreatedAt
+}
+
+// clicksLeft returns the clicks link has left after ClicksUsed, NULL for
+// unlimited links.
+func clicksLeft(link storage.Link) *int {
+	if link.MaxClicks == 0 {
+		return nil
+	}
+	left := max(link.MaxClicks-link.ClicksUsed, 0)
+
+	return &left
+}
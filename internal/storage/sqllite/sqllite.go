@@ -2,9 +2,13 @@ package sqllite
 
 import (
 	"RestApi/internal/storage"
+	"RestApi/storage/scripts"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 )
@@ -16,46 +20,80 @@ type Storage struct {
 func New(storagePath string) (*Storage, error) {
 	const op = "storage.sqlite.New"
 
-	db, err := sql.Open("sqlite3", storagePath)
-	if err != nil {
+	if err := migrateSchema(storagePath); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmt, err := db.Prepare(`
-	CREATE TABLE IF NOT EXISTS url(
-	    id INTEGER PRIMARY KEY,
-	    alias TEXT NOT NULL UNIQUE,
-	    url TEXT NOT NULL);
-	CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
-	`)
+	db, err := sql.Open("sqlite3", withForeignKeys(storagePath))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec()
+	return &Storage{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+func migrateSchema(storagePath string) error {
+	m, err := scripts.NewMigrator(scripts.SQLiteDSN(storagePath), "")
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return err
 	}
+	defer m.Close()
 
-	return &Storage{db: db}, nil
+	return m.Up(0)
+}
+
+// withForeignKeys turns on foreign key enforcement for every connection
+// of the pool, so deleting a link also deletes its split targets.
+func withForeignKeys(storagePath string) string {
+	if strings.Contains(storagePath, "?") {
+		return storagePath + "&_foreign_keys=on"
+	}
+
+	return storagePath + "?_foreign_keys=on"
 }
 
 func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
-	const op = "storage.sqlite.SaveURL"
+	return s.SaveLink(storage.Link{URL: urlToSave, Alias: alias})
+}
 
-	stmt, err := s.db.Prepare("INSERT INTO url(url, alias) VALUES (?, ?)")
+func (s *Storage) SaveLink(link storage.Link) (int64, error) {
+	const op = "storage.sqlite.SaveLink"
+
+	rules, err := marshalRules(link.Rules)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
 
-	res, err := stmt.Exec(urlToSave, alias)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO url(url, domain, alias, original_url, password_hash, max_clicks, clicks_left,
+			not_before, not_after, rules, redirect_type, pass_query, pass_path, created_at, updated_at, created_by,
+			title, description, metadata,
+			resolved_url, last_status, checked_at, dead, sticky_targets, deleted_at)
+		VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?,
+			?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?,
+			NULLIF(?, ''), NULLIF(?, 0), ?, ?, ?, ?)`,
+		link.URL, link.Domain, link.Alias, link.OriginalURL, link.PasswordHash, link.MaxClicks, clicksLeft(link),
+		nullTime(link.NotBefore), nullTime(link.NotAfter), rules, link.RedirectType, link.PassQuery, link.PassPath,
+		creationTime(link), nullTime(link.UpdatedAt), link.CreatedBy,
+		link.Meta.Title, link.Meta.Description, nullJSON(link.Meta.Metadata),
+		link.Status.ResolvedURL, link.Status.StatusCode, nullTime(link.Status.CheckedAt), link.Status.Dead,
+		link.StickyTargets, nullTime(link.DeletedAt))
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) &&
 			errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
-			return 0, fmt.Errorf("%s: %w", op, err)
+			return 0, fmt.Errorf("%s: %w", op, s.conflict(link.Domain, link.Alias))
 		}
 
 		return 0, fmt.Errorf("%s: %w", op, err)
@@ -66,20 +104,94 @@ func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := setTags(tx, id, link.Meta.Tags); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := insertTargets(tx, id, link.Targets); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return id, nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
+// SetMeta replaces the title, description, tags and metadata of alias on
+// domain.
+func (s *Storage) SetMeta(domain, alias string, meta storage.Meta) error {
+	const op = "storage.sqlite.SetMeta"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(`
+		UPDATE url SET title = NULLIF(?, ''), description = NULLIF(?, ''), metadata = ?, updated_at = ?
+		WHERE domain = ? AND alias = ? AND deleted_at IS NULL
+		RETURNING id`,
+		meta.Title, meta.Description, nullJSON(meta.Metadata), time.Now().UTC(), domain, alias,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrURLNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM link_tags WHERE url_id = ?", id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := setTags(tx, id, meta.Tags); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// conflict tells why alias on domain cannot be saved: it either belongs to
+// a live link or to a deleted one that is still quarantined.
+func (s *Storage) conflict(domain, alias string) error {
+	var deleted bool
+	err := s.db.QueryRow("SELECT deleted_at IS NOT NULL FROM url WHERE domain = ? AND alias = ?",
+		domain, alias).Scan(&deleted)
+	if err == nil && deleted {
+		return storage.ErrAliasQuarantined
+	}
+
+	return storage.ErrURLExists
+}
+
+func setTags(tx *sql.Tx, urlID int64, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO link_tags(url_id, tag) VALUES (?, ?)", urlID, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetURL returns the target of alias on domain.
+func (s *Storage) GetURL(domain, alias string) (string, error) {
 	const op = "storage.sqlite.GetURL"
 
-	stmt, err := s.db.Prepare("SELECT url FROM  url WHERE alias = ?")
+	stmt, err := s.db.Prepare("SELECT url FROM url WHERE domain = ? AND alias = ? AND deleted_at IS NULL")
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
 	var resURL string
-	err = stmt.QueryRow(alias).Scan(&resURL)
+	err = stmt.QueryRow(domain, alias).Scan(&resURL)
 	if errors.Is(err, sql.ErrNoRows) {
 		return "", storage.ErrURLNotFound
 	}
@@ -90,16 +202,189 @@ func (s *Storage) GetURL(alias string) (string, error) {
 	return resURL, nil
 }
 
-func (s *Storage) DeleteURL(alias string) error {
+// GetLink returns alias on domain, empty for the default domain. Deleted
+// links are returned as well, with DeletedAt set.
+func (s *Storage) GetLink(domain, alias string) (storage.Link, error) {
+	const op = "storage.sqlite.GetLink"
+
+	link, err := scanLink(s.db.QueryRow(
+		"SELECT "+linkColumns+" FROM url WHERE domain = ? AND alias = ?", domain, alias))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Link{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	link.Targets, err = s.targets(link.ID)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return link, nil
+}
+
+// ConsumeClick uses up one click of a limited link and returns how many
+// are left. Links without a limit are left untouched and report -1.
+func (s *Storage) ConsumeClick(domain, alias string) (int, error) {
+	const op = "storage.sqlite.ConsumeClick"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// The guarded decrement keeps concurrent visitors from both taking
+	// the last click.
+	res, err := tx.Exec(
+		"UPDATE url SET clicks_left = clicks_left - 1 WHERE domain = ? AND alias = ? AND clicks_left > 0",
+		domain, alias)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	consumed, _ := res.RowsAffected()
+
+	var left sql.NullInt64
+	err = tx.QueryRow("SELECT clicks_left FROM url WHERE domain = ? AND alias = ?", domain, alias).Scan(&left)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case !left.Valid:
+		return -1, nil
+	case consumed == 0:
+		return 0, storage.ErrLinkExhausted
+	}
+
+	return int(left.Int64), nil
+}
+
+// SetTargets replaces the split targets of alias on domain. Hit counts
+// start over.
+func (s *Storage) SetTargets(domain, alias string, sticky bool, targets []storage.Target) error {
+	const op = "storage.sqlite.SetTargets"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(`
+		UPDATE url SET sticky_targets = ?, updated_at = ?
+		WHERE domain = ? AND alias = ? AND deleted_at IS NULL
+		RETURNING id`,
+		sticky, time.Now().UTC(), domain, alias).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrURLNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM link_targets WHERE url_id = ?", id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := insertTargets(tx, id, targets); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func insertTargets(tx *sql.Tx, urlID int64, targets []storage.Target) error {
+	for _, t := range targets {
+		_, err := tx.Exec("INSERT INTO link_targets(url_id, variant, url, weight, hits) VALUES (?, ?, ?, ?, ?)",
+			urlID, t.Variant, t.URL, t.Weight, t.Hits)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTargets returns the split targets of alias on domain with their hit
+// counts.
+func (s *Storage) GetTargets(domain, alias string) ([]storage.Target, error) {
+	const op = "storage.sqlite.GetTargets"
+
+	var id int64
+	err := s.db.QueryRow("SELECT id FROM url WHERE domain = ? AND alias = ? AND deleted_at IS NULL",
+		domain, alias).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	targets, err := s.targets(id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return targets, nil
+}
+
+func (s *Storage) targets(urlID int64) ([]storage.Target, error) {
+	rows, err := s.db.Query(
+		"SELECT id, variant, url, weight, hits FROM link_targets WHERE url_id = ? ORDER BY id",
+		urlID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []storage.Target
+	for rows.Next() {
+		var t storage.Target
+		if err := rows.Scan(&t.ID, &t.Variant, &t.URL, &t.Weight, &t.Hits); err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, rows.Err()
+}
+
+// CountTargetHit records that the target was served.
+func (s *Storage) CountTargetHit(targetID int64) error {
+	const op = "storage.sqlite.CountTargetHit"
+
+	if _, err := s.db.Exec("UPDATE link_targets SET hits = hits + 1 WHERE id = ?", targetID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteURL marks alias on domain as deleted. The link keeps its alias
+// until PurgeDeleted removes it.
+func (s *Storage) DeleteURL(domain, alias string) error {
 	const op = "storage.sqlite.DeleteURL"
 
-	stmt, err := s.db.Prepare("DELETE FROM url WHERE alias = ?")
+	stmt, err := s.db.Prepare("UPDATE url SET deleted_at = ? WHERE domain = ? AND alias = ? AND deleted_at IS NULL")
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(alias)
+	res, err := stmt.Exec(time.Now().UTC(), domain, alias)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -110,3 +395,468 @@ func (s *Storage) DeleteURL(alias string) error {
 
 	return err
 }
+
+// RestoreURL brings back the deleted alias on domain.
+func (s *Storage) RestoreURL(domain, alias string) error {
+	const op = "storage.sqlite.RestoreURL"
+
+	res, err := s.db.Exec(
+		"UPDATE url SET deleted_at = NULL WHERE domain = ? AND alias = ? AND deleted_at IS NOT NULL",
+		domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// PurgeDeleted permanently removes links deleted before t, together with
+// their targets and tags, and returns how many there were.
+func (s *Storage) PurgeDeleted(t time.Time) (int64, error) {
+	const op = "storage.sqlite.PurgeDeleted"
+
+	res, err := s.db.Exec("DELETE FROM url WHERE deleted_at IS NOT NULL AND deleted_at < ?", t.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	purged, _ := res.RowsAffected()
+
+	return purged, nil
+}
+
+// ReserveAlias holds res.Alias on res.Domain until res.ExpiresAt. It fails
+// with ErrURLExists or ErrAliasQuarantined when a link has the alias and
+// with ErrAliasHeld while another reservation holds it.
+func (s *Storage) ReserveAlias(res storage.Reservation) error {
+	const op = "storage.sqlite.ReserveAlias"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM alias_reservations WHERE expires_at <= ?", time.Now().UTC()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var deleted bool
+	err = tx.QueryRow("SELECT deleted_at IS NOT NULL FROM url WHERE domain = ? AND alias = ?",
+		res.Domain, res.Alias).Scan(&deleted)
+	switch {
+	case err == nil && deleted:
+		return fmt.Errorf("%s: %w", op, storage.ErrAliasQuarantined)
+	case err == nil:
+		return fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO alias_reservations(domain, alias, token, reserved_by, expires_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		res.Domain, res.Alias, res.Token, res.ReservedBy, res.ExpiresAt.UTC())
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) &&
+			(errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintPrimaryKey) ||
+				errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique)) {
+			return fmt.Errorf("%s: %w", op, storage.ErrAliasHeld)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetReservation returns the reservation holding alias on domain. Expired
+// reservations are not returned.
+func (s *Storage) GetReservation(domain, alias string) (storage.Reservation, error) {
+	const op = "storage.sqlite.GetReservation"
+
+	res := storage.Reservation{Domain: domain, Alias: alias}
+	err := s.db.QueryRow(`
+		SELECT token, reserved_by, expires_at FROM alias_reservations
+		WHERE domain = ? AND alias = ? AND expires_at > ?`,
+		domain, alias, time.Now().UTC(),
+	).Scan(&res.Token, &res.ReservedBy, &res.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Reservation{}, storage.ErrReservationNotFound
+	}
+	if err != nil {
+		return storage.Reservation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// ReleaseReservation drops the reservation of alias on domain, if any.
+func (s *Storage) ReleaseReservation(domain, alias string) error {
+	const op = "storage.sqlite.ReleaseReservation"
+
+	if _, err := s.db.Exec("DELETE FROM alias_reservations WHERE domain = ? AND alias = ?", domain, alias); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ListURLs(limit, offset int) ([]storage.Link, error) {
+	const op = "storage.sqlite.ListURLs"
+
+	links, err := s.listLinks(
+		"SELECT "+listColumns+" FROM url WHERE deleted_at IS NULL ORDER BY id LIMIT ? OFFSET ?",
+		limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+// ListURLsByTag lists the links tagged with tag.
+func (s *Storage) ListURLsByTag(tag string, limit, offset int) ([]storage.Link, error) {
+	const op = "storage.sqlite.ListURLsByTag"
+
+	links, err := s.listLinks(`
+		SELECT `+listColumns+` FROM url
+		WHERE deleted_at IS NULL AND id IN (SELECT url_id FROM link_tags WHERE tag = ?)
+		ORDER BY id LIMIT ? OFFSET ?`,
+		tag, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+// listColumns lists the columns listLinks reads, in order.
+const listColumns = `id, domain, alias, url, COALESCE(title, ''), ` + tagsColumn
+
+func (s *Storage) listLinks(query string, args ...any) ([]storage.Link, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []storage.Link
+	for rows.Next() {
+		var (
+			link storage.Link
+			tags string
+		)
+		if err := rows.Scan(&link.ID, &link.Domain, &link.Alias, &link.URL, &link.Meta.Title, &tags); err != nil {
+			return nil, err
+		}
+		link.Meta.Tags = splitTags(tags)
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+func (s *Storage) CountURLs() (int64, error) {
+	const op = "storage.sqlite.CountURLs"
+
+	var count int64
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM url WHERE deleted_at IS NULL").Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// UpdateURL changes the target of alias on domain. originalURL is the
+// target as submitted when it was normalized, empty otherwise. The
+// reachability status of the old target is cleared.
+func (s *Storage) UpdateURL(domain, alias string, urlToSave, originalURL string) error {
+	const op = "storage.sqlite.UpdateURL"
+
+	res, err := s.db.Exec(`
+		UPDATE url SET url = ?, original_url = NULLIF(?, ''), updated_at = ?,
+			resolved_url = NULL, last_status = NULL, checked_at = NULL, dead = FALSE
+		WHERE domain = ? AND alias = ? AND deleted_at IS NULL`,
+		urlToSave, originalURL, time.Now().UTC(), domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) ListURLsAfter(afterID int64, limit int) ([]storage.Link, error) {
+	const op = "storage.sqlite.ListURLsAfter"
+
+	rows, err := s.db.Query(
+		"SELECT "+linkColumns+" FROM url WHERE id > ? AND deleted_at IS NULL ORDER BY id LIMIT ?",
+		afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var links []storage.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+// ListLinksAfter pages through every row in id order, deleted links and
+// split targets included, so whole tables can be copied.
+func (s *Storage) ListLinksAfter(afterID int64, limit int) ([]storage.Link, error) {
+	const op = "storage.sqlite.ListLinksAfter"
+
+	rows, err := s.db.Query("SELECT "+linkColumns+" FROM url WHERE id > ? ORDER BY id LIMIT ?",
+		afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var links []storage.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range links {
+		if links[i].Targets, err = s.targets(links[i].ID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return links, nil
+}
+
+func (s *Storage) SetLinkStatus(domain, alias string, status storage.LinkStatus) error {
+	const op = "storage.sqlite.SetLinkStatus"
+
+	res, err := s.db.Exec(`
+		UPDATE url
+		SET resolved_url = NULLIF(?, ''), last_status = NULLIF(?, 0), checked_at = ?, dead = ?
+		WHERE domain = ? AND alias = ?`,
+		status.ResolvedURL, status.StatusCode, nullTime(status.CheckedAt), status.Dead, domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// AppendAudit adds entry to the audit log. Entries cannot be changed
+// afterwards.
+func (s *Storage) AppendAudit(entry storage.AuditEntry) error {
+	const op = "storage.sqlite.AppendAudit"
+
+	_, err := s.db.Exec(`
+		INSERT INTO audit_log(at, action, domain, alias, actor, request_id, old_value, new_value)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.At.UTC(), entry.Action, entry.Domain, entry.Alias, entry.Actor, entry.RequestID,
+		nullJSON(entry.Old), nullJSON(entry.New))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListAudit returns the audit entries matching filter, newest first.
+func (s *Storage) ListAudit(filter storage.AuditFilter, limit, offset int) ([]storage.AuditEntry, error) {
+	const op = "storage.sqlite.ListAudit"
+
+	var (
+		where []string
+		args  []any
+	)
+	if filter.Alias != "" {
+		where, args = append(where, "alias = ?"), append(args, filter.Alias)
+	}
+	if filter.Actor != "" {
+		where, args = append(where, "actor = ?"), append(args, filter.Actor)
+	}
+	// Times are stored in UTC, so their text compares in order.
+	if !filter.From.IsZero() {
+		where, args = append(where, "at >= ?"), append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		where, args = append(where, "at <= ?"), append(args, filter.To.UTC())
+	}
+
+	query := "SELECT id, at, action, domain, alias, actor, request_id, old_value, new_value FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	rows, err := s.db.Query(query+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var entries []storage.AuditEntry
+	for rows.Next() {
+		var (
+			e                  storage.AuditEntry
+			oldValue, newValue sql.NullString
+		)
+		err := rows.Scan(&e.ID, &e.At, &e.Action, &e.Domain, &e.Alias, &e.Actor, &e.RequestID, &oldValue, &newValue)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if oldValue.Valid {
+			e.Old = json.RawMessage(oldValue.String)
+		}
+		if newValue.Valid {
+			e.New = json.RawMessage(newValue.String)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+func nullJSON(raw json.RawMessage) *string {
+	if len(raw) == 0 {
+		return nil
+	}
+	str := string(raw)
+
+	return &str
+}
+
+// tagsColumn aggregates the tags of a url row, joined by commas which tags
+// never contain.
+const tagsColumn = `COALESCE((SELECT group_concat(tag, ',')
+	FROM (SELECT tag FROM link_tags WHERE url_id = url.id ORDER BY tag)), '')`
+
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+
+	return strings.Split(tags, ",")
+}
+
+// linkColumns lists the columns scanLink reads, in order.
+const linkColumns = `id, domain, alias, url, COALESCE(original_url, ''), COALESCE(password_hash, ''),
+	COALESCE(max_clicks, 0), not_before, not_after, rules, sticky_targets, COALESCE(redirect_type, ''),
+	COALESCE(pass_query, ''), pass_path, created_at, updated_at, COALESCE(created_by, ''), deleted_at,
+	COALESCE(title, ''), COALESCE(description, ''), metadata, ` + tagsColumn + `,
+	COALESCE(resolved_url, ''), COALESCE(last_status, 0), checked_at, dead,
+	COALESCE(max_clicks - clicks_left, 0)`
+
+func scanLink(row interface{ Scan(dest ...any) error }) (storage.Link, error) {
+	var (
+		link                 storage.Link
+		notBefore, notAfter  sql.NullTime
+		createdAt, updatedAt sql.NullTime
+		deletedAt, checkedAt sql.NullTime
+		rules, metadata      sql.NullString
+		tags                 string
+	)
+	err := row.Scan(&link.ID, &link.Domain, &link.Alias, &link.URL, &link.OriginalURL, &link.PasswordHash,
+		&link.MaxClicks, &notBefore, &notAfter, &rules, &link.StickyTargets, &link.RedirectType,
+		&link.PassQuery, &link.PassPath, &createdAt, &updatedAt, &link.CreatedBy, &deletedAt,
+		&link.Meta.Title, &link.Meta.Description, &metadata, &tags,
+		&link.Status.ResolvedURL, &link.Status.StatusCode, &checkedAt, &link.Status.Dead,
+		&link.ClicksUsed)
+	if err != nil {
+		return storage.Link{}, err
+	}
+	link.NotBefore, link.NotAfter = notBefore.Time, notAfter.Time
+	link.CreatedAt, link.UpdatedAt = createdAt.Time, updatedAt.Time
+	link.DeletedAt, link.Status.CheckedAt = deletedAt.Time, checkedAt.Time
+	link.Meta.Tags = splitTags(tags)
+	if metadata.Valid {
+		link.Meta.Metadata = json.RawMessage(metadata.String)
+	}
+
+	if rules.Valid {
+		if err := json.Unmarshal([]byte(rules.String), &link.Rules); err != nil {
+			return storage.Link{}, err
+		}
+	}
+
+	return link, nil
+}
+
+// marshalRules encodes rules for the rules column, NULL when there are
+// none.
+func marshalRules(rules []storage.Rule) (*string, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	str := string(b)
+
+	return &str, nil
+}
+
+// creationTime returns when link was created, now for new links.
+func creationTime(link storage.Link) time.Time {
+	if link.CreatedAt.IsZero() {
+		return time.Now().UTC()
+	}
+
+	return link.CreatedAt
+}
+
+// clicksLeft returns the clicks link has left after ClicksUsed, NULL for
+// unlimited links.
+func clicksLeft(link storage.Link) *int {
+	if link.MaxClicks == 0 {
+		return nil
+	}
+	left := max(link.MaxClicks-link.ClicksUsed, 0)
+
+	return &left
+}
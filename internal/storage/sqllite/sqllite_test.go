@@ -0,0 +1,457 @@
+package sqllite_test
+
+import (
+	"RestApi/internal/storage"
+	"RestApi/internal/storage/sqllite"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConsumeClick(t *testing.T) {
+	s, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Alias: "once", URL: "https://example.com", MaxClicks: 3})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "open", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("", "once")
+	require.NoError(t, err)
+	require.Equal(t, 3, link.MaxClicks)
+
+	// Concurrent visitors never get more clicks than the limit.
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		ok, spent int
+	)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.ConsumeClick("", "once")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				ok++
+			case errors.Is(err, storage.ErrLinkExhausted):
+				spent++
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 3, ok)
+	require.Equal(t, 7, spent)
+
+	left, err := s.ConsumeClick("", "open")
+	require.NoError(t, err)
+	require.Equal(t, -1, left)
+
+	_, err = s.ConsumeClick("", "missing")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestActivationWindow(t *testing.T) {
+	s, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	launch := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	_, err = s.SaveLink(storage.Link{
+		Alias:     "launch",
+		URL:       "https://example.com",
+		NotBefore: launch,
+		NotAfter:  launch.Add(24 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("", "launch")
+	require.NoError(t, err)
+	require.True(t, launch.Equal(link.NotBefore))
+	require.True(t, launch.Add(24*time.Hour).Equal(link.NotAfter))
+	require.False(t, link.Active(launch.Add(-time.Second)))
+	require.True(t, link.Active(launch))
+	require.False(t, link.Active(link.NotAfter))
+
+	_, err = s.SaveLink(storage.Link{Alias: "open", URL: "https://example.com"})
+	require.NoError(t, err)
+	link, err = s.GetLink("", "open")
+	require.NoError(t, err)
+	require.True(t, link.NotBefore.IsZero())
+	require.True(t, link.NotAfter.IsZero())
+}
+
+func TestRules(t *testing.T) {
+	s, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	rules := []storage.Rule{
+		{Device: "ios", URL: "https://apps.apple.com/app/id1"},
+		{Language: "de", Country: "AT", Hours: "08:00-20:00", URL: "https://example.at"},
+	}
+	_, err = s.SaveLink(storage.Link{Alias: "app", URL: "https://example.com", Rules: rules})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("", "app")
+	require.NoError(t, err)
+	require.Equal(t, rules, link.Rules)
+
+	links, err := s.ListURLsAfter(0, 10)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.Equal(t, rules, links[0].Rules)
+}
+
+func TestTargets(t *testing.T) {
+	s, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, s.SetTargets("", "missing", false, nil), storage.ErrURLNotFound)
+
+	require.NoError(t, s.SetTargets("", "promo", true, []storage.Target{
+		{Variant: "a", URL: "https://example.com/a", Weight: 70},
+		{Variant: "b", URL: "https://example.com/b", Weight: 30},
+	}))
+
+	link, err := s.GetLink("", "promo")
+	require.NoError(t, err)
+	require.True(t, link.StickyTargets)
+	require.Len(t, link.Targets, 2)
+
+	require.NoError(t, s.CountTargetHit(link.Targets[1].ID))
+	require.NoError(t, s.CountTargetHit(link.Targets[1].ID))
+
+	targets, err := s.GetTargets("", "promo")
+	require.NoError(t, err)
+	require.Equal(t, "b", targets[1].Variant)
+	require.EqualValues(t, 2, targets[1].Hits)
+
+	// Purging the link takes its targets along.
+	require.NoError(t, s.DeleteURL("", "promo"))
+	_, err = s.PurgeDeleted(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com"})
+	require.NoError(t, err)
+	targets, err = s.GetTargets("", "promo")
+	require.NoError(t, err)
+	require.Empty(t, targets)
+}
+
+func TestDomains(t *testing.T) {
+	s, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Alias: "sale", URL: "https://example.com"})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Domain: "brand.example", Alias: "sale", URL: "https://brand.example/sale", MaxClicks: 1})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Domain: "brand.example", Alias: "sale", URL: "https://other.example"})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	link, err := s.GetLink("brand.example", "sale")
+	require.NoError(t, err)
+	require.Equal(t, "brand.example", link.Domain)
+	require.Equal(t, "https://brand.example/sale", link.URL)
+
+	link, err = s.GetLink("", "sale")
+	require.NoError(t, err)
+	require.Empty(t, link.Domain)
+	require.Equal(t, "https://example.com", link.URL)
+
+	_, err = s.GetLink("other.example", "sale")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// Clicks are counted on the link of the domain only.
+	left, err := s.ConsumeClick("brand.example", "sale")
+	require.NoError(t, err)
+	require.Equal(t, 0, left)
+	left, err = s.ConsumeClick("", "sale")
+	require.NoError(t, err)
+	require.Equal(t, -1, left)
+
+	// Management acts on the link of the given domain only.
+	require.NoError(t, s.UpdateURL("brand.example", "sale", "https://brand.example/summer", ""))
+	require.NoError(t, s.SetMeta("brand.example", "sale", storage.Meta{Title: "Summer"}))
+	require.NoError(t, s.SetTargets("brand.example", "sale", false, []storage.Target{
+		{Variant: "a", URL: "https://brand.example/a", Weight: 1},
+	}))
+	targets, err := s.GetTargets("brand.example", "sale")
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	targets, err = s.GetTargets("", "sale")
+	require.NoError(t, err)
+	require.Empty(t, targets)
+
+	target, err := s.GetURL("", "sale")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", target)
+	target, err = s.GetURL("brand.example", "sale")
+	require.NoError(t, err)
+	require.Equal(t, "https://brand.example/summer", target)
+
+	require.NoError(t, s.DeleteURL("brand.example", "sale"))
+	_, err = s.GetURL("brand.example", "sale")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.GetURL("", "sale")
+	require.NoError(t, err)
+	require.ErrorIs(t, s.RestoreURL("", "sale"), storage.ErrURLNotFound)
+	require.NoError(t, s.RestoreURL("brand.example", "sale"))
+
+	link, err = s.GetLink("brand.example", "sale")
+	require.NoError(t, err)
+	require.Equal(t, "Summer", link.Meta.Title)
+	link, err = s.GetLink("", "sale")
+	require.NoError(t, err)
+	require.Empty(t, link.Meta.Title)
+}
+
+func TestMeta(t *testing.T) {
+	s, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Alias: "spring", URL: "https://example.com/spring", Meta: storage.Meta{
+		Title:    "Spring sale",
+		Tags:     []string{"sale", "poster"},
+		Metadata: json.RawMessage(`{"campaign":"spring"}`),
+	}})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "autumn", URL: "https://example.com/autumn", Meta: storage.Meta{
+		Tags: []string{"sale"},
+	}})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "plain", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("", "spring")
+	require.NoError(t, err)
+	require.Equal(t, "Spring sale", link.Meta.Title)
+	require.Equal(t, []string{"poster", "sale"}, link.Meta.Tags)
+	require.JSONEq(t, `{"campaign":"spring"}`, string(link.Meta.Metadata))
+
+	links, err := s.ListURLsByTag("sale", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	require.Equal(t, "spring", links[0].Alias)
+	require.Equal(t, "Spring sale", links[0].Meta.Title)
+	require.Equal(t, []string{"sale"}, links[1].Meta.Tags)
+
+	links, err = s.ListURLs(10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 3)
+	require.Empty(t, links[2].Meta.Tags)
+
+	require.NoError(t, s.SetMeta("", "spring", storage.Meta{Description: "Posters in the main station", Tags: []string{"poster"}}))
+	link, err = s.GetLink("", "spring")
+	require.NoError(t, err)
+	require.Empty(t, link.Meta.Title)
+	require.Equal(t, "Posters in the main station", link.Meta.Description)
+	require.Equal(t, []string{"poster"}, link.Meta.Tags)
+	require.Nil(t, link.Meta.Metadata)
+
+	links, err = s.ListURLsByTag("sale", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+
+	require.ErrorIs(t, s.SetMeta("", "missing", storage.Meta{}), storage.ErrURLNotFound)
+}
+
+func TestTimestamps(t *testing.T) {
+	s, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com", CreatedBy: "alice"})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("", "promo")
+	require.NoError(t, err)
+	require.Equal(t, "alice", link.CreatedBy)
+	require.False(t, link.CreatedAt.IsZero())
+	require.True(t, link.UpdatedAt.IsZero())
+
+	require.NoError(t, s.UpdateURL("", "promo", "https://example.com/new", ""))
+	link, err = s.GetLink("", "promo")
+	require.NoError(t, err)
+	require.False(t, link.UpdatedAt.Before(link.CreatedAt))
+}
+
+func TestUpdateURL(t *testing.T) {
+	s, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com/", OriginalURL: "https://Example.com"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetLinkStatus("", "promo", storage.LinkStatus{
+		ResolvedURL: "https://example.com/moved", StatusCode: 404, CheckedAt: time.Now().UTC(), Dead: true,
+	}))
+
+	require.NoError(t, s.UpdateURL("", "promo", "https://example.com/new", "https://Example.com/new?utm_source=x"))
+	link, err := s.GetLink("", "promo")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/new", link.URL)
+	require.Equal(t, "https://Example.com/new?utm_source=x", link.OriginalURL)
+	require.Equal(t, storage.LinkStatus{}, link.Status)
+
+	require.NoError(t, s.UpdateURL("", "promo", "https://example.com/other", ""))
+	link, err = s.GetLink("", "promo")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/other", link.URL)
+	require.Empty(t, link.OriginalURL)
+}
+
+func TestAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	s, err := sqllite.New(path)
+	require.NoError(t, err)
+
+	march := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, e := range []storage.AuditEntry{
+		{At: march, Action: "create", Alias: "promo", Actor: "alice", RequestID: "r1", New: json.RawMessage(`{"url":"https://example.com"}`)},
+		{At: march.Add(time.Hour), Action: "update", Alias: "promo", Actor: "bob",
+			Old: json.RawMessage(`{"url":"https://example.com"}`), New: json.RawMessage(`{"url":"https://example.com/new"}`)},
+		{At: march.AddDate(0, 1, 0), Action: "delete", Alias: "promo", Actor: "alice"},
+		{At: march.AddDate(0, 1, 0), Action: "create", Alias: "other", Actor: "alice"},
+	} {
+		require.NoError(t, s.AppendAudit(e))
+	}
+
+	entries, err := s.ListAudit(storage.AuditFilter{}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	require.Equal(t, "other", entries[0].Alias, "newest first")
+	require.Equal(t, "r1", entries[3].RequestID)
+	require.True(t, march.Equal(entries[3].At))
+	require.Nil(t, entries[3].Old)
+	require.JSONEq(t, `{"url":"https://example.com"}`, string(entries[3].New))
+
+	entries, err = s.ListAudit(storage.AuditFilter{Alias: "promo", Actor: "alice"}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	entries, err = s.ListAudit(storage.AuditFilter{From: march.Add(time.Hour), To: march.AddDate(0, 0, 7)}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "bob", entries[0].Actor)
+
+	entries, err = s.ListAudit(storage.AuditFilter{}, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "update", entries[0].Action)
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("UPDATE audit_log SET actor = 'mallory'")
+	require.ErrorContains(t, err, "append-only")
+	_, err = db.Exec("DELETE FROM audit_log")
+	require.ErrorContains(t, err, "append-only")
+}
+
+func TestSoftDelete(t *testing.T) {
+	s, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com", Meta: storage.Meta{Tags: []string{"sale"}}})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "kept", URL: "https://example.com/kept"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteURL("", "promo"))
+	require.ErrorIs(t, s.DeleteURL("", "promo"), storage.ErrURLNotFound)
+
+	link, err := s.GetLink("", "promo")
+	require.NoError(t, err)
+	require.False(t, link.DeletedAt.IsZero())
+
+	_, err = s.GetURL("", "promo")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	require.ErrorIs(t, s.UpdateURL("", "promo", "https://example.com/new", ""), storage.ErrURLNotFound)
+	links, err := s.ListURLs(10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	links, err = s.ListURLsByTag("sale", 10, 0)
+	require.NoError(t, err)
+	require.Empty(t, links)
+	count, err := s.CountURLs()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	_, err = s.SaveURL("https://example.com/other", "promo")
+	require.ErrorIs(t, err, storage.ErrAliasQuarantined)
+	_, err = s.SaveURL("https://example.com/other", "kept")
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	require.NoError(t, s.RestoreURL("", "promo"))
+	require.ErrorIs(t, s.RestoreURL("", "promo"), storage.ErrURLNotFound)
+	link, err = s.GetLink("", "promo")
+	require.NoError(t, err)
+	require.True(t, link.DeletedAt.IsZero())
+	require.Equal(t, []string{"sale"}, link.Meta.Tags)
+
+	require.NoError(t, s.DeleteURL("", "promo"))
+	purged, err := s.PurgeDeleted(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, purged, "deleted within the retention")
+
+	purged, err = s.PurgeDeleted(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), purged)
+	_, err = s.GetLink("", "promo")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	_, err = s.SaveURL("https://example.com/other", "promo")
+	require.NoError(t, err, "alias is free once purged")
+	link, err = s.GetLink("", "promo")
+	require.NoError(t, err)
+	require.Empty(t, link.Meta.Tags, "tags are purged with the link")
+}
+
+func TestReservations(t *testing.T) {
+	s, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://example.com", "taken")
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com", "gone")
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURL("", "gone"))
+
+	hold := storage.Reservation{Alias: "launch", Token: "t1", ReservedBy: "alice", ExpiresAt: time.Now().Add(time.Minute)}
+	require.NoError(t, s.ReserveAlias(hold))
+
+	res, err := s.GetReservation("", "launch")
+	require.NoError(t, err)
+	require.Equal(t, "t1", res.Token)
+	require.Equal(t, "alice", res.ReservedBy)
+
+	_, err = s.GetReservation("brand.example", "launch")
+	require.ErrorIs(t, err, storage.ErrReservationNotFound, "reservations are per domain")
+
+	require.ErrorIs(t, s.ReserveAlias(storage.Reservation{Alias: "launch", Token: "t2", ExpiresAt: time.Now().Add(time.Minute)}),
+		storage.ErrAliasHeld)
+	require.ErrorIs(t, s.ReserveAlias(storage.Reservation{Alias: "taken", Token: "t2", ExpiresAt: time.Now().Add(time.Minute)}),
+		storage.ErrURLExists)
+	require.ErrorIs(t, s.ReserveAlias(storage.Reservation{Alias: "gone", Token: "t2", ExpiresAt: time.Now().Add(time.Minute)}),
+		storage.ErrAliasQuarantined)
+
+	require.NoError(t, s.ReleaseReservation("", "launch"))
+	_, err = s.GetReservation("", "launch")
+	require.ErrorIs(t, err, storage.ErrReservationNotFound)
+
+	// Expired reservations neither show up nor block the alias.
+	require.NoError(t, s.ReserveAlias(storage.Reservation{Alias: "brief", Token: "t3", ExpiresAt: time.Now().Add(-time.Second)}))
+	_, err = s.GetReservation("", "brief")
+	require.ErrorIs(t, err, storage.ErrReservationNotFound)
+	require.NoError(t, s.ReserveAlias(storage.Reservation{Alias: "brief", Token: "t4", ExpiresAt: time.Now().Add(time.Minute)}))
+}
@@ -1,10 +1,159 @@
 package storage
 
 import (
+	"encoding/json"
 	"errors"
+	"time"
 )
 
 var (
 	ErrURLNotFound = errors.New("URL not found")
 	ErrURLExists   = errors.New("URL exists")
+	// ErrLinkExhausted is returned once a link has used up its clicks.
+	ErrLinkExhausted = errors.New("link exhausted")
+	// ErrAliasQuarantined is returned when saving an alias that belongs to
+	// a deleted link which has not been purged yet.
+	ErrAliasQuarantined = errors.New("alias quarantined")
+	// ErrAliasHeld is returned when reserving an alias another
+	// reservation holds.
+	ErrAliasHeld           = errors.New("alias held")
+	ErrReservationNotFound = errors.New("reservation not found")
 )
+
+type Link struct {
+	ID int64
+	// Domain is the custom domain the alias belongs to, empty for the
+	// default one. Aliases are unique per domain.
+	Domain string
+	Alias  string
+	URL    string
+	// CreatedAt is zero for links saved before it was recorded.
+	CreatedAt time.Time
+	// UpdatedAt is when the target, metadata or split targets last
+	// changed, zero if they never did.
+	UpdatedAt time.Time
+	// CreatedBy is the user who created the link, empty when unknown.
+	CreatedBy string
+	// DeletedAt is when the link was deleted, zero for live links.
+	// Deleted links can be restored until they are purged.
+	DeletedAt time.Time
+	// OriginalURL is the target as submitted, before normalization.
+	OriginalURL string
+	// PasswordHash is the bcrypt hash visitors must match before being
+	// redirected. Empty for public links.
+	PasswordHash string
+	// MaxClicks limits how often the link can be followed. Zero means
+	// unlimited.
+	MaxClicks int
+	// ClicksUsed counts the clicks already taken from MaxClicks.
+	ClicksUsed int
+	// NotBefore and NotAfter bound when the link redirects. Zero values
+	// leave the window open on that side.
+	NotBefore time.Time
+	NotAfter  time.Time
+	// Rules send matching visitors to other targets. They are evaluated
+	// in order and URL is used when none matches.
+	Rules []Rule
+	// Targets split visitors between weighted variants instead of URL.
+	// StickyTargets keeps returning visitors on their first variant.
+	Targets       []Target
+	StickyTargets bool
+	// RedirectType is how visitors are sent on: "301", "302", "307",
+	// "308", "meta" or "frame". Empty uses the server default.
+	RedirectType string
+	// PassQuery appends the request's query to the target, resolving
+	// conflicts by "keep", "replace" or "append". Empty drops it.
+	PassQuery string
+	// PassPath appends whatever follows the alias in the request path to
+	// the target, making the alias a prefix.
+	PassPath bool
+	Meta     Meta
+	Status   LinkStatus
+}
+
+// Meta describes a link to the people managing it. Visitors never see it.
+type Meta struct {
+	Title       string
+	Description string
+	// Tags are lowercase labels links can be listed by.
+	Tags []string
+	// Metadata is a free-form JSON object, nil when unset.
+	Metadata json.RawMessage
+}
+
+// Target is a weighted variant of a link for A/B splits. Hits counts how
+// often the variant was served.
+type Target struct {
+	ID      int64
+	Variant string
+	URL     string
+	Weight  int
+	Hits    int64
+}
+
+// Rule is a conditional target of a link. Empty conditions match any
+// visitor; all set conditions have to match.
+type Rule struct {
+	// Device is "ios", "android" or "desktop".
+	Device string `json:"device,omitempty"`
+	// Language is a BCP 47 tag matched against the visitor's preferred
+	// language, e.g. "de" also matches "de-AT".
+	Language string `json:"language,omitempty"`
+	// Country is an ISO 3166-1 alpha-2 code resolved from the visitor's
+	// address.
+	Country string `json:"country,omitempty"`
+	// Hours is a "15:04-15:04" time of day range, wrapping past midnight
+	// when the end is before the start.
+	Hours string `json:"hours,omitempty"`
+	URL   string `json:"url"`
+}
+
+// Active reports whether the link's activation window contains t.
+func (l Link) Active(t time.Time) bool {
+	return (l.NotBefore.IsZero() || !t.Before(l.NotBefore)) &&
+		(l.NotAfter.IsZero() || t.Before(l.NotAfter))
+}
+
+// LinkStatus is the outcome of the last reachability check of a link.
+// CheckedAt is zero for links that were never checked.
+type LinkStatus struct {
+	ResolvedURL string
+	StatusCode  int
+	Dead        bool
+	CheckedAt   time.Time
+}
+
+// AuditEntry records a change to a link. Old and New hold the link's state
+// before and after as JSON, nil where there is none, e.g. Old of a create.
+type AuditEntry struct {
+	ID int64
+	At time.Time
+	// Action is "create", "update", "delete", "restore" or "import".
+	Action    string
+	Domain    string
+	Alias     string
+	Actor     string
+	RequestID string
+	Old       json.RawMessage
+	New       json.RawMessage
+}
+
+// AuditFilter selects audit entries. Empty fields match every entry, From
+// and To bound At inclusively.
+type AuditFilter struct {
+	Alias string
+	Actor string
+	From  time.Time
+	To    time.Time
+}
+
+// Reservation holds an alias on a domain until ExpiresAt, so it can be
+// picked before the link is created. Only saves presenting Token may use
+// the alias meanwhile.
+type Reservation struct {
+	Domain     string
+	Alias      string
+	Token      string
+	ReservedBy string
+	ExpiresAt  time.Time
+}
@@ -0,0 +1,412 @@
+// Package client is a typed Go client for the url-shortener HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	defaultBackoff = 100 * time.Millisecond
+
+	apiKeyHeader = "X-API-Key"
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	auth       func(r *http.Request)
+	retries    int
+	backoff    time.Duration
+}
+
+type Option func(c *Client)
+
+// WithBasicAuth authenticates every request with HTTP Basic credentials.
+func WithBasicAuth(user, password string) Option {
+	return func(c *Client) {
+		c.auth = func(r *http.Request) {
+			r.SetBasicAuth(user, password)
+		}
+	}
+}
+
+// WithAPIKey authenticates every request with the X-API-Key header,
+// using one of the keys configured in http_server.api_keys.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.auth = func(r *http.Request) {
+			r.Header.Set(apiKeyHeader, key)
+		}
+	}
+}
+
+// WithHTTPClient replaces the default http.Client.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries retries idempotent calls up to n times on transport errors
+// and 5xx responses, doubling the wait after every attempt.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		backoff:    defaultBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Link is a short link as the server reports it. List fills in the
+// domain, alias, URLs, title and tags; GetLink fills in everything but the
+// alias and domain, which it copies from its arguments.
+type Link struct {
+	Domain   string `json:"domain,omitempty"`
+	Alias    string `json:"alias"`
+	URL      string `json:"url"`
+	ShortURL string `json:"short_url,omitempty"`
+	// Protected tells whether visitors need a password.
+	Protected    bool            `json:"protected,omitempty"`
+	MaxClicks    int             `json:"max_clicks,omitempty"`
+	NotBefore    *time.Time      `json:"not_before,omitempty"`
+	NotAfter     *time.Time      `json:"not_after,omitempty"`
+	RedirectType string          `json:"redirect_type,omitempty"`
+	PassQuery    string          `json:"pass_query,omitempty"`
+	PassPath     bool            `json:"pass_path,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	Description  string          `json:"description,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	Metadata     json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt    *time.Time      `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time      `json:"updated_at,omitempty"`
+	CreatedBy    string          `json:"created_by,omitempty"`
+}
+
+// SaveRequest describes a link to create. Only URL is required; the
+// server generates an alias when it is empty and applies its defaults to
+// everything else left zero.
+type SaveRequest struct {
+	URL    string `json:"url"`
+	Alias  string `json:"alias,omitempty"`
+	Domain string `json:"domain,omitempty"`
+	// Password protects the link, at most 72 bytes.
+	Password string `json:"password,omitempty"`
+	// MaxClicks limits how often the link can be followed.
+	MaxClicks int `json:"max_clicks,omitempty"`
+	// NotBefore and NotAfter limit when the link redirects.
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+	Rules     []Rule     `json:"rules,omitempty"`
+	// RedirectType is 301, 302, 307, 308, meta or frame.
+	RedirectType string `json:"redirect_type,omitempty"`
+	// PassQuery is keep, replace or append, PassPath forwards what
+	// follows the alias in the path.
+	PassQuery   string          `json:"pass_query,omitempty"`
+	PassPath    bool            `json:"pass_path,omitempty"`
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	Metadata    json.RawMessage `json:"metadata,omitempty"`
+	// Reservation is the token of a reservation held on Alias.
+	Reservation string `json:"reservation,omitempty"`
+}
+
+// Rule sends visitors matching every set condition to URL.
+type Rule struct {
+	// Device is "ios", "android" or "desktop".
+	Device   string `json:"device,omitempty"`
+	Language string `json:"language,omitempty"`
+	Country  string `json:"country,omitempty"`
+	// Hours is a "15:04-15:04" time of day range.
+	Hours string `json:"hours,omitempty"`
+	URL   string `json:"url"`
+}
+
+// Saved is what the server answers to a created link.
+type Saved struct {
+	Alias    string `json:"alias"`
+	ShortURL string `json:"short_url"`
+}
+
+type Stats struct {
+	Total int64 `json:"total"`
+}
+
+type response struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Save creates a short link. An empty alias lets the server generate one.
+// Save is never retried because it is not idempotent.
+func (c *Client) Save(ctx context.Context, urlToSave, alias string) (string, error) {
+	saved, err := c.SaveLink(ctx, SaveRequest{URL: urlToSave, Alias: alias})
+	if err != nil {
+		return "", err
+	}
+
+	return saved.Alias, nil
+}
+
+// SaveLink creates a short link with every option the server supports.
+// Like Save it is never retried.
+func (c *Client) SaveLink(ctx context.Context, req SaveRequest) (Saved, error) {
+	const op = "client.SaveLink"
+
+	var res struct {
+		response
+		Saved
+	}
+
+	if err := c.call(ctx, http.MethodPost, "/url", nil, req, false, &res); err != nil {
+		return Saved{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.Saved, nil
+}
+
+// Get returns the target URL stored for alias.
+func (c *Client) Get(ctx context.Context, alias string) (string, error) {
+	const op = "client.Get"
+
+	var res struct {
+		response
+		URL string `json:"url"`
+	}
+
+	body := map[string]string{"alias": alias}
+	if err := c.call(ctx, http.MethodPost, "/url/get-url", nil, body, true, &res); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.URL, nil
+}
+
+// GetLink returns the link stored for alias on domain, empty for the
+// default one.
+func (c *Client) GetLink(ctx context.Context, domain, alias string) (Link, error) {
+	const op = "client.GetLink"
+
+	var res struct {
+		response
+		Link
+	}
+
+	body := map[string]string{"alias": alias, "domain": domain}
+	if err := c.call(ctx, http.MethodPost, "/url/get-url", nil, body, true, &res); err != nil {
+		return Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+	res.Link.Domain, res.Link.Alias = domain, alias
+
+	return res.Link, nil
+}
+
+// Update points an existing alias at a new target URL.
+func (c *Client) Update(ctx context.Context, alias, urlToSave string) error {
+	const op = "client.Update"
+
+	var res response
+
+	body := map[string]string{"alias": alias, "url": urlToSave}
+	if err := c.call(ctx, http.MethodPut, "/url/update-url", nil, body, true, &res); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *Client) Delete(ctx context.Context, alias string) error {
+	const op = "client.Delete"
+
+	var res response
+
+	body := map[string]string{"alias": alias}
+	if err := c.call(ctx, http.MethodDelete, "/url/delete-url", nil, body, true, &res); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *Client) List(ctx context.Context, limit, offset int) ([]Link, error) {
+	const op = "client.List"
+
+	var res struct {
+		response
+		Links []Link `json:"links"`
+	}
+
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+
+	if err := c.call(ctx, http.MethodGet, "/url/list", query, nil, true, &res); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.Links, nil
+}
+
+func (c *Client) Stats(ctx context.Context) (Stats, error) {
+	const op = "client.Stats"
+
+	var res struct {
+		response
+		Stats
+	}
+
+	if err := c.call(ctx, http.MethodGet, "/url/stats", nil, nil, true, &res); err != nil {
+		return Stats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.Stats, nil
+}
+
+// call performs a request and decodes the JSON envelope into out, which
+// must embed response.
+func (c *Client) call(
+	ctx context.Context,
+	method, path string,
+	query url.Values,
+	body any,
+	idempotent bool,
+	out interface{ status() (string, string) },
+) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	attempts := 1
+	if idempotent {
+		attempts += c.retries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff<<(attempt-1)); err != nil {
+				return err
+			}
+		}
+
+		retry, err := c.do(ctx, method, target, payload, out)
+		if err == nil || !retry {
+			return err
+		}
+		lastErr = err
+	}
+
+	return lastErr
+}
+
+func (c *Client) do(
+	ctx context.Context,
+	method, target string,
+	payload []byte,
+	out interface{ status() (string, string) },
+) (bool, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return false, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.auth != nil {
+		c.auth(req)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		retry := resp.StatusCode >= http.StatusInternalServerError
+		return retry, newAPIError(resp.StatusCode, errorMessage(resp))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if status, msg := out.status(); status != "OK" {
+		return false, newAPIError(resp.StatusCode, msg)
+	}
+
+	return false, nil
+}
+
+func (r response) status() (string, string) {
+	return r.Status, r.Error
+}
+
+// errorMessage returns the error reported in the body of a failed
+// response, falling back to the body as text and then to the status text.
+func errorMessage(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	var res response
+	if json.Unmarshal(body, &res) == nil && res.Error != "" {
+		return res.Error
+	}
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return msg
+	}
+
+	return http.StatusText(resp.StatusCode)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,293 @@
+package client_test
+
+import (
+	"RestApi/internal/http-server/handlers/url/delete"
+	"RestApi/internal/http-server/handlers/url/get"
+	"RestApi/internal/http-server/handlers/url/list"
+	"RestApi/internal/http-server/handlers/url/save"
+	"RestApi/internal/http-server/handlers/url/stats"
+	"RestApi/internal/http-server/handlers/url/update"
+	"RestApi/internal/http-server/middleware/auth"
+	"RestApi/internal/lib/urlpolicy"
+	"RestApi/internal/storage/sqllite"
+	"RestApi/pkg/client"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	storage, err := sqllite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	urlPolicy, err := urlpolicy.New(urlpolicy.Config{BlockPrivate: true})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Route("/url", func(r chi.Router) {
+		r.Use(auth.New("url-shortener", map[string]string{"user": "pass"}, map[string]string{"secret-key": "ci"}))
+
+		r.Post("/", save.New(log, storage, save.WithURLPolicy(urlPolicy)))
+		r.Post("/get-url", get.New(log, storage))
+		r.Put("/update-url", update.New(log, storage, update.WithURLPolicy(urlPolicy)))
+		r.Delete("/delete-url", delete.New(log, storage))
+		r.Get("/list", list.New(log, storage))
+		r.Get("/stats", stats.New(log, storage))
+	})
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func TestClient_Lifecycle(t *testing.T) {
+	ts := newTestServer(t)
+	c := client.New(ts.URL, client.WithBasicAuth("user", "pass"))
+	ctx := context.Background()
+
+	alias, err := c.Save(ctx, "https://google.com", "google")
+	require.NoError(t, err)
+	require.Equal(t, "google", alias)
+
+	generated, err := c.Save(ctx, "https://ya.ru", "")
+	require.NoError(t, err)
+	require.NotEmpty(t, generated)
+
+	_, err = c.Save(ctx, "https://bing.com", "google")
+	require.ErrorIs(t, err, client.ErrAliasExists)
+
+	_, err = c.Save(ctx, "not a url", "bad")
+	require.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	_, err = c.Save(ctx, "javascript://alert(1)", "js")
+	require.ErrorIs(t, err, client.ErrURLRejected)
+
+	require.NoError(t, c.Update(ctx, "google", "https://www.google.com"))
+	require.ErrorIs(t, c.Update(ctx, "missing", "https://google.com"), client.ErrNotFound)
+	require.ErrorIs(t, c.Update(ctx, "google", "http://127.0.0.1/admin"), client.ErrURLRejected)
+
+	target, err := c.Get(ctx, "google")
+	require.NoError(t, err)
+	require.Equal(t, "https://www.google.com", target)
+
+	links, err := c.List(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, []client.Link{
+		{Alias: "google", URL: "https://www.google.com", ShortURL: ts.URL + "/google"},
+		{Alias: generated, URL: "https://ya.ru", ShortURL: ts.URL + "/" + generated},
+	}, links)
+
+	st, err := c.Stats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), st.Total)
+
+	require.NoError(t, c.Delete(ctx, "google"))
+
+	_, err = c.Get(ctx, "google")
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	var apiErr *client.APIError
+	require.True(t, errors.As(c.Delete(ctx, "google"), &apiErr))
+	require.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	require.Equal(t, "url not found", apiErr.Message)
+}
+
+func TestClient_Unauthorized(t *testing.T) {
+	ts := newTestServer(t)
+	c := client.New(ts.URL, client.WithBasicAuth("user", "wrong"))
+
+	_, err := c.Stats(context.Background())
+	require.ErrorIs(t, err, client.ErrUnauthorized)
+}
+
+func TestClient_SaveLink(t *testing.T) {
+	ts := newTestServer(t)
+	c := client.New(ts.URL, client.WithBasicAuth("user", "pass"))
+	ctx := context.Background()
+
+	notAfter := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	saved, err := c.SaveLink(ctx, client.SaveRequest{
+		URL:          "https://google.com",
+		Alias:        "promo",
+		Password:     "secret",
+		MaxClicks:    5,
+		NotAfter:     &notAfter,
+		RedirectType: "307",
+		PassQuery:    "keep",
+		Title:        "Promo",
+		Tags:         []string{"spring"},
+		Metadata:     json.RawMessage(`{"team":"growth"}`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, client.Saved{Alias: "promo", ShortURL: ts.URL + "/promo"}, saved)
+
+	link, err := c.GetLink(ctx, "", "promo")
+	require.NoError(t, err)
+	require.NotNil(t, link.CreatedAt)
+	require.True(t, notAfter.Equal(*link.NotAfter))
+	link.CreatedAt, link.NotAfter = nil, nil
+	require.Equal(t, client.Link{
+		Alias:        "promo",
+		URL:          "https://google.com",
+		ShortURL:     ts.URL + "/promo",
+		Protected:    true,
+		MaxClicks:    5,
+		RedirectType: "307",
+		PassQuery:    "keep",
+		Title:        "Promo",
+		Tags:         []string{"spring"},
+		Metadata:     json.RawMessage(`{"team":"growth"}`),
+		CreatedBy:    "user",
+	}, link)
+
+	_, err = c.GetLink(ctx, "", "missing")
+	require.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestClient_APIKey(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+
+	c := client.New(ts.URL, client.WithAPIKey("secret-key"))
+	alias, err := c.Save(ctx, "https://google.com", "google")
+	require.NoError(t, err)
+	require.Equal(t, "google", alias)
+
+	_, err = client.New(ts.URL, client.WithAPIKey("wrong-key")).Stats(ctx)
+	require.ErrorIs(t, err, client.ErrUnauthorized)
+}
+
+func TestClient_ErrorKinds(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		body    string
+		kind    error
+		message string
+	}{
+		{
+			name:    "Bad request",
+			status:  http.StatusBadRequest,
+			body:    `{"status":"Error","error":"field URL is a required field"}`,
+			kind:    client.ErrInvalidRequest,
+			message: "field URL is a required field",
+		},
+		{
+			name:    "Not found",
+			status:  http.StatusNotFound,
+			body:    `{"status":"Error","error":"gone fishing"}`,
+			kind:    client.ErrNotFound,
+			message: "gone fishing",
+		},
+		{
+			name:    "Conflict",
+			status:  http.StatusConflict,
+			body:    `{"status":"Error","error":"alias is held by a reservation"}`,
+			kind:    client.ErrAliasExists,
+			message: "alias is held by a reservation",
+		},
+		{
+			name:    "Rejected",
+			status:  http.StatusUnprocessableEntity,
+			body:    `{"status":"Error","error":"url domain is blocked"}`,
+			kind:    client.ErrURLRejected,
+			message: "url domain is blocked",
+		},
+		{
+			name:    "Plain text body",
+			status:  http.StatusUnauthorized,
+			body:    "Unauthorized\n",
+			kind:    client.ErrUnauthorized,
+			message: "Unauthorized",
+		},
+		{
+			name:    "Unknown status",
+			status:  http.StatusTeapot,
+			message: http.StatusText(http.StatusTeapot),
+		},
+		{
+			name:    "Error with status OK",
+			status:  http.StatusOK,
+			body:    `{"status":"Error","error":"url not found"}`,
+			message: "url not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer ts.Close()
+
+			_, err := client.New(ts.URL).Stats(context.Background())
+
+			var apiErr *client.APIError
+			require.True(t, errors.As(err, &apiErr))
+			require.Equal(t, tc.status, apiErr.StatusCode)
+			require.Equal(t, tc.message, apiErr.Message)
+			if tc.kind != nil {
+				require.ErrorIs(t, err, tc.kind)
+			} else {
+				require.Nil(t, apiErr.Unwrap())
+			}
+		})
+	}
+}
+
+func TestClient_Retries(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"OK","url":"https://google.com","alias":"google"}`))
+	}))
+	defer ts.Close()
+
+	c := client.New(ts.URL, client.WithRetries(3, time.Millisecond))
+
+	target, err := c.Get(context.Background(), "google")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", target)
+	require.Equal(t, int32(3), calls.Load())
+
+	calls.Store(0)
+	_, err = c.Save(context.Background(), "https://google.com", "google")
+	require.Error(t, err)
+	require.Equal(t, int32(1), calls.Load(), "save must not be retried")
+}
+
+func TestClient_RetriesRespectContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := client.New(ts.URL, client.WithRetries(10, time.Second))
+
+	_, err := c.Get(ctx, "google")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
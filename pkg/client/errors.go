@@ -0,0 +1,57 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrNotFound       = errors.New("url not found")
+	ErrAliasExists    = errors.New("url already exists")
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrURLRejected    = errors.New("url rejected by policy")
+)
+
+// APIError is returned for every failed call. It carries the HTTP status
+// and the message reported by the server, and unwraps to one of the
+// sentinel errors above according to the status.
+type APIError struct {
+	StatusCode int
+	Message    string
+	kind       error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("client: status %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+func newAPIError(statusCode int, message string) *APIError {
+	return &APIError{
+		StatusCode: statusCode,
+		Message:    message,
+		kind:       errorKind(statusCode),
+	}
+}
+
+func errorKind(statusCode int) error {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return ErrInvalidRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrAliasExists
+	case http.StatusUnprocessableEntity:
+		return ErrURLRejected
+	}
+
+	return nil
+}
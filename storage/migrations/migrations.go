@@ -0,0 +1,22 @@
+// Package migrations embeds the SQL migrations into the binary. Every
+// storage backend has its own directory and both must carry the same
+// versions so the schemas stay equivalent.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// FS returns the migrations for dialect.
+func FS(dialect string) (fs.FS, error) {
+	return fs.Sub(files, dialect)
+}
@@ -0,0 +1,2 @@
+ALTER TABLE url DROP COLUMN pass_path;
+ALTER TABLE url DROP COLUMN pass_query;
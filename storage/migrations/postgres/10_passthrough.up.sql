@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN pass_query TEXT;
+ALTER TABLE url ADD COLUMN pass_path BOOLEAN NOT NULL DEFAULT FALSE;
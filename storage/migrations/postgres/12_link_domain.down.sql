@@ -0,0 +1,8 @@
+-- Links on custom domains may share aliases with default ones and do not
+-- fit the single namespace.
+DELETE FROM url WHERE domain <> '';
+
+ALTER TABLE url DROP CONSTRAINT url_domain_alias_key;
+ALTER TABLE url ADD CONSTRAINT url_alias_key UNIQUE (alias);
+
+ALTER TABLE url DROP COLUMN domain;
@@ -0,0 +1,4 @@
+ALTER TABLE url ADD COLUMN domain TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE url DROP CONSTRAINT url_alias_key;
+ALTER TABLE url ADD CONSTRAINT url_domain_alias_key UNIQUE (domain, alias);
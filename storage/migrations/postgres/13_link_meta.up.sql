@@ -0,0 +1,11 @@
+ALTER TABLE url ADD COLUMN title TEXT;
+ALTER TABLE url ADD COLUMN description TEXT;
+ALTER TABLE url ADD COLUMN metadata JSONB;
+
+CREATE TABLE IF NOT EXISTS link_tags (
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (url_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_tags_tag ON link_tags(tag);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+
+ALTER TABLE url DROP COLUMN created_by;
+ALTER TABLE url DROP COLUMN updated_at;
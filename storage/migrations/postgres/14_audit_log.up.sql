@@ -0,0 +1,30 @@
+ALTER TABLE url ADD COLUMN updated_at TIMESTAMPTZ;
+ALTER TABLE url ADD COLUMN created_by TEXT;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    at TIMESTAMPTZ NOT NULL,
+    action TEXT NOT NULL,
+    domain TEXT NOT NULL DEFAULT '',
+    alias TEXT NOT NULL DEFAULT '',
+    actor TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    old_value JSONB,
+    new_value JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_alias ON audit_log(alias);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor);
+CREATE INDEX IF NOT EXISTS idx_audit_log_at ON audit_log(at);
+
+-- Entries are never changed or removed once written.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,5 @@
+-- Deleted links would come back to life otherwise.
+DELETE FROM url WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_url_deleted_at;
+ALTER TABLE url DROP COLUMN deleted_at;
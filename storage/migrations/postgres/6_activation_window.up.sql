@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN not_before TIMESTAMPTZ;
+ALTER TABLE url ADD COLUMN not_after TIMESTAMPTZ;
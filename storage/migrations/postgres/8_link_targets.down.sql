@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS link_targets;
+
+ALTER TABLE url DROP COLUMN sticky_targets;
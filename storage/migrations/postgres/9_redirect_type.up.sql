@@ -0,0 +1 @@
+ALTER TABLE url ADD COLUMN redirect_type TEXT;
@@ -0,0 +1,39 @@
+-- Links on custom domains may share aliases with default ones and do not
+-- fit the single namespace.
+CREATE TABLE url_old (
+    id INTEGER PRIMARY KEY,
+    alias TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    original_url TEXT,
+    resolved_url TEXT,
+    last_status INTEGER,
+    checked_at DATETIME,
+    dead BOOLEAN NOT NULL DEFAULT FALSE,
+    password_hash TEXT,
+    max_clicks INTEGER,
+    clicks_left INTEGER,
+    not_before DATETIME,
+    not_after DATETIME,
+    rules TEXT,
+    sticky_targets BOOLEAN NOT NULL DEFAULT FALSE,
+    redirect_type TEXT,
+    pass_query TEXT,
+    pass_path BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME
+);
+
+INSERT INTO url_old (id, alias, url, original_url, resolved_url, last_status, checked_at, dead,
+    password_hash, max_clicks, clicks_left, not_before, not_after, rules, sticky_targets,
+    redirect_type, pass_query, pass_path, created_at)
+SELECT id, alias, url, original_url, resolved_url, last_status, checked_at, dead,
+    password_hash, max_clicks, clicks_left, not_before, not_after, rules, sticky_targets,
+    redirect_type, pass_query, pass_path, created_at
+FROM url
+WHERE domain = '';
+
+DELETE FROM link_targets WHERE url_id NOT IN (SELECT id FROM url_old);
+
+DROP TABLE url;
+ALTER TABLE url_old RENAME TO url;
+
+CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
@@ -0,0 +1,39 @@
+-- SQLite cannot drop the inline UNIQUE constraint on alias, so the table
+-- is rebuilt. link_targets keeps referring to url by name; foreign keys
+-- are not enforced while migrating, so dropping url keeps the targets.
+CREATE TABLE url_new (
+    id INTEGER PRIMARY KEY,
+    domain TEXT NOT NULL DEFAULT '',
+    alias TEXT NOT NULL,
+    url TEXT NOT NULL,
+    original_url TEXT,
+    resolved_url TEXT,
+    last_status INTEGER,
+    checked_at DATETIME,
+    dead BOOLEAN NOT NULL DEFAULT FALSE,
+    password_hash TEXT,
+    max_clicks INTEGER,
+    clicks_left INTEGER,
+    not_before DATETIME,
+    not_after DATETIME,
+    rules TEXT,
+    sticky_targets BOOLEAN NOT NULL DEFAULT FALSE,
+    redirect_type TEXT,
+    pass_query TEXT,
+    pass_path BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME,
+    UNIQUE (domain, alias)
+);
+
+INSERT INTO url_new (id, alias, url, original_url, resolved_url, last_status, checked_at, dead,
+    password_hash, max_clicks, clicks_left, not_before, not_after, rules, sticky_targets,
+    redirect_type, pass_query, pass_path, created_at)
+SELECT id, alias, url, original_url, resolved_url, last_status, checked_at, dead,
+    password_hash, max_clicks, clicks_left, not_before, not_after, rules, sticky_targets,
+    redirect_type, pass_query, pass_path, created_at
+FROM url;
+
+DROP TABLE url;
+ALTER TABLE url_new RENAME TO url;
+
+CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS link_tags;
+
+ALTER TABLE url DROP COLUMN metadata;
+ALTER TABLE url DROP COLUMN description;
+ALTER TABLE url DROP COLUMN title;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS audit_log;
+
+ALTER TABLE url DROP COLUMN created_by;
+ALTER TABLE url DROP COLUMN updated_at;
@@ -0,0 +1,29 @@
+ALTER TABLE url ADD COLUMN updated_at DATETIME;
+ALTER TABLE url ADD COLUMN created_by TEXT;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    at DATETIME NOT NULL,
+    action TEXT NOT NULL,
+    domain TEXT NOT NULL DEFAULT '',
+    alias TEXT NOT NULL DEFAULT '',
+    actor TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    old_value TEXT,
+    new_value TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_alias ON audit_log(alias);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor);
+CREATE INDEX IF NOT EXISTS idx_audit_log_at ON audit_log(at);
+
+-- Entries are never changed or removed once written.
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
@@ -0,0 +1,8 @@
+-- Deleted links would come back to life otherwise. Migrations run without
+-- foreign keys, so their targets and tags are removed explicitly.
+DELETE FROM link_targets WHERE url_id IN (SELECT id FROM url WHERE deleted_at IS NOT NULL);
+DELETE FROM link_tags WHERE url_id IN (SELECT id FROM url WHERE deleted_at IS NOT NULL);
+DELETE FROM url WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_url_deleted_at;
+ALTER TABLE url DROP COLUMN deleted_at;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS alias_reservations (
+    domain TEXT NOT NULL DEFAULT '',
+    alias TEXT NOT NULL,
+    token TEXT NOT NULL,
+    reserved_by TEXT NOT NULL DEFAULT '',
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (domain, alias)
+);
+
+CREATE INDEX IF NOT EXISTS idx_alias_reservations_expires_at ON alias_reservations(expires_at);
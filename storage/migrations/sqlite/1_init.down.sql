@@ -0,0 +1 @@
+DROP TABLE IF EXISTS url;
\ No newline at end of file
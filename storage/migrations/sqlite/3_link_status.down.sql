@@ -0,0 +1,4 @@
+ALTER TABLE url DROP COLUMN dead;
+ALTER TABLE url DROP COLUMN checked_at;
+ALTER TABLE url DROP COLUMN last_status;
+ALTER TABLE url DROP COLUMN resolved_url;
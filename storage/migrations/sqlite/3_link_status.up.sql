@@ -0,0 +1,4 @@
+ALTER TABLE url ADD COLUMN resolved_url TEXT;
+ALTER TABLE url ADD COLUMN last_status INTEGER;
+ALTER TABLE url ADD COLUMN checked_at DATETIME;
+ALTER TABLE url ADD COLUMN dead BOOLEAN NOT NULL DEFAULT FALSE;
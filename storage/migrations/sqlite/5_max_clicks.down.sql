@@ -0,0 +1,2 @@
+ALTER TABLE url DROP COLUMN clicks_left;
+ALTER TABLE url DROP COLUMN max_clicks;
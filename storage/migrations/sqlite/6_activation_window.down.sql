@@ -0,0 +1,2 @@
+ALTER TABLE url DROP COLUMN not_after;
+ALTER TABLE url DROP COLUMN not_before;
@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN not_before DATETIME;
+ALTER TABLE url ADD COLUMN not_after DATETIME;
@@ -0,0 +1,11 @@
+ALTER TABLE url ADD COLUMN sticky_targets BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS link_targets (
+    id INTEGER PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    variant TEXT NOT NULL,
+    url TEXT NOT NULL,
+    weight INTEGER NOT NULL,
+    hits INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (url_id, variant)
+);
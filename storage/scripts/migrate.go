@@ -1,41 +1,174 @@
 package scripts
 
 import (
+	"RestApi/storage/migrations"
 	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-func RunMigrations(dsn string) error {
+// Migrator applies and inspects schema migrations. Migrations are read
+// from the files embedded into the binary unless a directory is given;
+// the directory must contain a subdirectory per dialect, like
+// storage/migrations does.
+type Migrator struct {
+	m   *migrate.Migrate
+	src source.Driver
+}
+
+// Status describes the migration state of a database.
+type Status struct {
+	Version uint
+	Dirty   bool
+	Pending []string
+}
 
-	migrationsPath := filepath.Join("storage", "migrations")
-	absPath, err := filepath.Abs(migrationsPath)
+// NewMigrator picks the dialect from the dsn scheme: postgres:// or
+// sqlite3://.
+func NewMigrator(dsn string, migrationsPath string) (*Migrator, error) {
+	dialect, err := dialectOf(dsn)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, err
 	}
-	if _, err := os.Stat(absPath); os.IsNotExist(err) {
-		return fmt.Errorf("migrations directory does not exist at %s", absPath)
+
+	var fsys fs.FS
+	if migrationsPath != "" {
+		dir := filepath.Join(migrationsPath, dialect)
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("migrations directory is not available: %w", err)
+		}
+		fsys = os.DirFS(dir)
+	} else if fsys, err = migrations.FS(dialect); err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
 	}
 
-	m, err := migrate.New(
-		fmt.Sprintf("file://%s", absPath),
-		dsn,
-	)
+	src, err := iofs.New(fsys, ".")
 	if err != nil {
-		return fmt.Errorf("failed to initialize migrator: %w", err)
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
 	}
-	defer m.Close()
 
-	if err := m.Up(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			log.Println("No new migrations to apply")
-			return nil
+	m, err := migrate.NewWithSourceInstance("iofs", src, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize migrator: %w", err)
+	}
+
+	return &Migrator{m: m, src: src}, nil
+}
+
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+
+	return errors.Join(srcErr, dbErr)
+}
+
+// Up applies n pending migrations, or all of them when n <= 0.
+func (m *Migrator) Up(n int) error {
+	if n <= 0 {
+		return noChange(m.m.Up())
+	}
+
+	return noChange(m.m.Steps(n))
+}
+
+// Down rolls back n applied migrations, or all of them when n <= 0.
+func (m *Migrator) Down(n int) error {
+	if n <= 0 {
+		return noChange(m.m.Down())
+	}
+
+	return noChange(m.m.Steps(-n))
+}
+
+// Goto migrates up or down to the given version.
+func (m *Migrator) Goto(version uint) error {
+	return noChange(m.m.Migrate(version))
+}
+
+// Force sets the version without running migrations and clears the dirty
+// flag. It is meant for recovering from a failed migration by hand.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+func (m *Migrator) Status() (Status, error) {
+	var st Status
+
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return st, fmt.Errorf("failed to read version: %w", err)
+	}
+	st.Version, st.Dirty = version, dirty
+
+	v, err := m.src.First()
+	for err == nil {
+		if v > st.Version {
+			name, readErr := m.upName(v)
+			if readErr != nil {
+				return st, readErr
+			}
+			st.Pending = append(st.Pending, name)
 		}
+		v, err = m.src.Next(v)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return st, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	return st, nil
+}
+
+func (m *Migrator) upName(version uint) (string, error) {
+	r, identifier, err := m.src.ReadUp(version)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migration %d: %w", version, err)
+	}
+	_ = r.Close()
+
+	return fmt.Sprintf("%d_%s.up.sql", version, identifier), nil
+}
+
+func noChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+
+	return err
+}
+
+func dialectOf(dsn string) (string, error) {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return migrations.DialectPostgres, nil
+	case strings.HasPrefix(dsn, "sqlite3://"):
+		return migrations.DialectSQLite, nil
+	default:
+		return "", errors.New("unsupported database url scheme")
+	}
+}
+
+// SQLiteDSN returns the migration dsn for a sqlite database file.
+func SQLiteDSN(storagePath string) string {
+	return "sqlite3://" + storagePath
+}
+
+// RunMigrations applies every pending migration.
+func RunMigrations(dsn string, migrationsPath string) error {
+	m, err := NewMigrator(dsn, migrationsPath)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(0); err != nil {
 		return fmt.Errorf("failed to apply migrations: %w", err)
 	}
 
@@ -0,0 +1,182 @@
+package scripts_test
+
+import (
+	"RestApi/storage/migrations"
+	"RestApi/storage/scripts"
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// Set to a disposable database to compare the postgres schema as well.
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
+
+func TestMigrationsInSync(t *testing.T) {
+	pg := migrationFiles(t, migrations.DialectPostgres)
+	lite := migrationFiles(t, migrations.DialectSQLite)
+
+	require.NotEmpty(t, pg)
+	require.Equal(t, pg, lite, "every migration must exist for both dialects")
+}
+
+func TestSQLiteMigrator(t *testing.T) {
+	dsn := scripts.SQLiteDSN(filepath.Join(t.TempDir(), "storage.db"))
+	latest := uint(len(migrationFiles(t, migrations.DialectSQLite)) / 2)
+
+	m, err := scripts.NewMigrator(dsn, "")
+	require.NoError(t, err)
+	defer m.Close()
+
+	st, err := m.Status()
+	require.NoError(t, err)
+	require.Equal(t, uint(0), st.Version)
+	require.Len(t, st.Pending, int(latest))
+
+	require.NoError(t, m.Up(0))
+	require.NoError(t, m.Up(0), "re-running up must be a no-op")
+
+	st, err = m.Status()
+	require.NoError(t, err)
+	require.Equal(t, scripts.Status{Version: latest}, st)
+
+	require.NoError(t, m.Down(1))
+	st, err = m.Status()
+	require.NoError(t, err)
+	require.Equal(t, latest-1, st.Version)
+	require.Len(t, st.Pending, 1)
+
+	require.NoError(t, m.Goto(latest))
+	require.NoError(t, m.Force(int(latest)))
+
+	st, err = m.Status()
+	require.NoError(t, err)
+	require.False(t, st.Dirty)
+	require.Empty(t, st.Pending)
+}
+
+func TestSchemasEquivalent(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+	require.NoError(t, scripts.RunMigrations(scripts.SQLiteDSN(path), ""))
+
+	liteDB, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer liteDB.Close()
+
+	require.NoError(t, scripts.RunMigrations(dsn, ""))
+	t.Cleanup(func() {
+		m, err := scripts.NewMigrator(dsn, "")
+		require.NoError(t, err)
+		defer m.Close()
+		require.NoError(t, m.Down(0))
+	})
+
+	pgDB, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer pgDB.Close()
+
+	require.Equal(t, postgresSchema(t, pgDB), sqliteSchema(t, liteDB))
+}
+
+func migrationFiles(t *testing.T, dialect string) []string {
+	t.Helper()
+
+	fsys, err := migrations.FS(dialect)
+	require.NoError(t, err)
+
+	names, err := fs.Glob(fsys, "*.sql")
+	require.NoError(t, err)
+
+	return names
+}
+
+// schema maps every application table to its sorted column names.
+type schema map[string][]string
+
+func sqliteSchema(t *testing.T, db *sql.DB) schema {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT m.name, p.name
+		FROM sqlite_master m JOIN pragma_table_info(m.name) p
+		WHERE m.type = 'table'
+		  AND m.name NOT LIKE 'sqlite_%'
+		  AND m.name <> 'schema_migrations'`)
+	require.NoError(t, err)
+
+	return collectSchema(t, rows)
+}
+
+func postgresSchema(t *testing.T, db *sql.DB) schema {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		  AND table_name <> 'schema_migrations'`)
+	require.NoError(t, err)
+
+	return collectSchema(t, rows)
+}
+
+func collectSchema(t *testing.T, rows *sql.Rows) schema {
+	t.Helper()
+	defer rows.Close()
+
+	s := schema{}
+	for rows.Next() {
+		var table, column string
+		require.NoError(t, rows.Scan(&table, &column))
+		s[table] = append(s[table], column)
+	}
+	require.NoError(t, rows.Err())
+
+	for _, columns := range s {
+		sort.Strings(columns)
+	}
+
+	return s
+}
+
+func TestSQLiteLinkDomainMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	m, err := scripts.NewMigrator(scripts.SQLiteDSN(path), "")
+	require.NoError(t, err)
+	defer m.Close()
+	require.NoError(t, m.Goto(11))
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO url(id, alias, url, max_clicks, clicks_left) VALUES (1, 'abc', 'https://example.com', 3, 2)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO link_targets(url_id, variant, url, weight) VALUES (1, 'a', 'https://a.example', 1)")
+	require.NoError(t, err)
+
+	require.NoError(t, m.Up(0))
+
+	var domain string
+	var left int
+	require.NoError(t, db.QueryRow("SELECT domain, clicks_left FROM url WHERE alias = 'abc'").Scan(&domain, &left))
+	require.Empty(t, domain)
+	require.Equal(t, 2, left)
+
+	var targets int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM link_targets WHERE url_id = 1").Scan(&targets))
+	require.Equal(t, 1, targets)
+
+	// Aliases are unique per domain only.
+	_, err = db.Exec("INSERT INTO url(domain, alias, url) VALUES ('brand.example', 'abc', 'https://b.example')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO url(domain, alias, url) VALUES ('brand.example', 'abc', 'https://c.example')")
+	require.Error(t, err)
+}